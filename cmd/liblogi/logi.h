@@ -0,0 +1,63 @@
+/*
+ * liblogi - C API of logi, built with:
+ *
+ *     go build -buildmode=c-shared -o liblogi.so ./cmd/liblogi
+ *
+ * Memory ownership:
+ *   - Strings passed to functions are borrowed, they are copied before the function returns, and they are still owned
+ *     by the caller.
+ *   - Strings returned by functions, including error messages, are owned by the caller, they must be freed with
+ *     logi_free, and not with free of another allocator.
+ *   - Virtual machines are referenced by handles, they must be freed with logi_vm_free. Handles are not valid after
+ *     they are freed, and 0 is never a valid handle.
+ *
+ * Errors:
+ *   - Functions which can fail take an `error` out parameter. On failure, if it is not NULL, it is set to the error
+ *     message, which must be freed with logi_free. On success it is not changed.
+ *
+ * All functions can be called from multiple threads, also with the same virtual machine.
+ */
+
+#ifndef LOGI_H
+#define LOGI_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef uintptr_t logi_vm;
+
+/* logi_vm_new creates a virtual machine, loading a macro or definition again replaces the previous one */
+logi_vm logi_vm_new(void);
+
+/* logi_vm_free frees the virtual machine, freeing 0 or a freed handle does nothing */
+void logi_vm_free(logi_vm vm);
+
+/* logi_load_macro loads macros of the content, it returns 0 on success and -1 on failure */
+int logi_load_macro(logi_vm vm, const char *content, char **error);
+
+/* logi_load_logi loads definitions of the content with loaded macros, it returns 0 on success and -1 on failure */
+int logi_load_logi(logi_vm vm, const char *content, char **error);
+
+/*
+ * logi_compile returns loaded definitions as json, the same json as `logi compile` with the kind.
+ * Kind is "normal" or "values", NULL is "normal". It returns NULL on failure.
+ */
+char *logi_compile(logi_vm vm, const char *kind, char **error);
+
+/*
+ * logi_evaluate evaluates the expression, and returns its value as json. Vars is a json object of variables, it can be
+ * NULL. It returns NULL on failure.
+ */
+char *logi_evaluate(logi_vm vm, const char *expression, const char *vars, char **error);
+
+/* logi_free frees a string returned by liblogi, freeing NULL does nothing */
+void logi_free(char *str);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
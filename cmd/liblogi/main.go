@@ -0,0 +1,135 @@
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"github.com/tislib/logi/pkg/capi"
+	"sync"
+	"unsafe"
+)
+
+// liblogi is a c shared library, see logi.h for its api and memory ownership rules
+func main() {
+}
+
+var vms = make(map[C.uintptr_t]*capi.Vm)
+var lastHandle C.uintptr_t
+var vmsMu sync.RWMutex
+
+//export logi_vm_new
+func logi_vm_new() C.uintptr_t {
+	vmsMu.Lock()
+	defer vmsMu.Unlock()
+
+	lastHandle++
+	vms[lastHandle] = capi.New()
+
+	return lastHandle
+}
+
+//export logi_vm_free
+func logi_vm_free(handle C.uintptr_t) {
+	vmsMu.Lock()
+	defer vmsMu.Unlock()
+
+	delete(vms, handle)
+}
+
+//export logi_load_macro
+func logi_load_macro(handle C.uintptr_t, content *C.char, errOut **C.char) C.int {
+	return status(withVm(handle, func(v *capi.Vm) error {
+		return v.LoadMacro(goString(content))
+	}), errOut)
+}
+
+//export logi_load_logi
+func logi_load_logi(handle C.uintptr_t, content *C.char, errOut **C.char) C.int {
+	return status(withVm(handle, func(v *capi.Vm) error {
+		return v.LoadLogi(goString(content))
+	}), errOut)
+}
+
+//export logi_compile
+func logi_compile(handle C.uintptr_t, kind *C.char, errOut **C.char) *C.char {
+	var result string
+
+	var err = withVm(handle, func(v *capi.Vm) (err error) {
+		result, err = v.Compile(goString(kind))
+
+		return err
+	})
+
+	return cString(result, err, errOut)
+}
+
+//export logi_evaluate
+func logi_evaluate(handle C.uintptr_t, expression *C.char, vars *C.char, errOut **C.char) *C.char {
+	var result string
+
+	var err = withVm(handle, func(v *capi.Vm) (err error) {
+		result, err = v.Evaluate(goString(expression), goString(vars))
+
+		return err
+	})
+
+	return cString(result, err, errOut)
+}
+
+//export logi_free
+func logi_free(str *C.char) {
+	C.free(unsafe.Pointer(str))
+}
+
+func withVm(handle C.uintptr_t, fn func(v *capi.Vm) error) error {
+	vmsMu.RLock()
+	v, found := vms[handle]
+	vmsMu.RUnlock()
+
+	if !found {
+		return fmt.Errorf("invalid vm handle: %d", handle)
+	}
+
+	return fn(v)
+}
+
+// goString copies the string, NULL is an empty string
+func goString(str *C.char) string {
+	if str == nil {
+		return ""
+	}
+
+	return C.GoString(str)
+}
+
+// status returns 0 on success, and -1 on failure, setting the error
+func status(err error, errOut **C.char) C.int {
+	if err != nil {
+		setError(err, errOut)
+
+		return -1
+	}
+
+	return 0
+}
+
+// cString returns the result as a string allocated with malloc, or NULL on failure, setting the error
+func cString(result string, err error, errOut **C.char) *C.char {
+	if err != nil {
+		setError(err, errOut)
+
+		return nil
+	}
+
+	return C.CString(result)
+}
+
+func setError(err error, errOut **C.char) {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/sourcegraph/jsonrpc2"
 	wsjsonrpc2 "github.com/sourcegraph/jsonrpc2/websocket"
+	"github.com/tislib/logi/pkg/transport"
 )
 
 func (self *Server) newStreamConnection(stream io.ReadWriteCloser) *jsonrpc2.Conn {
@@ -16,7 +17,7 @@ func (self *Server) newStreamConnection(stream io.ReadWriteCloser) *jsonrpc2.Con
 	context, cancel := contextpkg.WithTimeout(contextpkg.Background(), self.StreamTimeout)
 	defer cancel()
 
-	return jsonrpc2.NewConn(context, jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{}), handler, connectionOptions...)
+	return jsonrpc2.NewConn(context, transport.NewObjectStream(stream), handler, connectionOptions...)
 }
 
 func (self *Server) newWebSocketConnection(socket *websocket.Conn) *jsonrpc2.Conn {
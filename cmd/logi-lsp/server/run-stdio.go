@@ -1,28 +1,10 @@
 package server
 
 import (
-	"errors"
-	"os"
+	"github.com/tislib/logi/pkg/transport"
 )
 
 func (self *Server) RunStdio() error {
-	self.ServeStream(Stdio{})
+	self.ServeStream(transport.Stdio{})
 	return nil
 }
-
-type Stdio struct{}
-
-// ([io.Reader] interface)
-func (Stdio) Read(p []byte) (int, error) {
-	return os.Stdin.Read(p)
-}
-
-// ([io.Writer] interface)
-func (Stdio) Write(p []byte) (int, error) {
-	return os.Stdout.Write(p)
-}
-
-// ([io.Closer] interface)
-func (Stdio) Close() error {
-	return errors.Join(os.Stdin.Close(), os.Stdout.Close())
-}
@@ -1,27 +1,9 @@
 package server
 
 import (
-	log "github.com/sirupsen/logrus"
+	"github.com/tislib/logi/pkg/transport"
 )
 
 func (self *Server) RunTCP(address string) error {
-	listener, err := self.newNetworkListener("tcp", address)
-	if err != nil {
-		return err
-	}
-
-	log.Info("listening for TCP connections")
-
-	var connectionCount uint64
-
-	for {
-		connection, err := (*listener).Accept()
-		if err != nil {
-			return err
-		}
-
-		connectionCount++
-
-		go self.ServeStream(connection)
-	}
+	return transport.ServeTCP(address, self.ServeStream)
 }
@@ -0,0 +1,12 @@
+//go:build js && wasm
+
+package main
+
+import "github.com/tislib/logi/pkg/wasm"
+
+// logi-wasm exposes the parser and the virtual machine to javascript, see wasm.Register
+func main() {
+	wasm.Register()
+
+	select {}
+}
@@ -1,120 +1,100 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	astMacro "github.com/tislib/logi/pkg/ast/macro"
-	"github.com/tislib/logi/pkg/parser/logi"
-	"github.com/tislib/logi/pkg/parser/macro"
+	_ "github.com/tislib/logi/pkg/compiled"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/encoder"
 	"os"
-	"path"
+	"runtime"
 	"strings"
 )
 
 var compileCmd = &cobra.Command{
-	Use:   "compile",
+	Use:   "compile [inputs...]",
 	Short: "compile - compile logi file",
-	Long:  `compile logi file and generate definitions`,
+	Long: `compile logi files and generate definitions.
+Inputs can be files, directories (all *.lg files are compiled recursively), glob patterns or - for stdin.
+If output directory is set, outputs are written mirroring input directory structure, unchanged files are skipped.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		initCommand(cmd)
 
-		if strings.HasSuffix(*compileCmdMacroDir, "/") == false {
-			*compileCmdMacroDir = *compileCmdMacroDir + "/"
+		var patterns = append(*compileCmdInput, args...)
+
+		if len(patterns) == 0 {
+			return fmt.Errorf("no input is given")
 		}
 
-		// read logi file
-		logiContent, err := os.ReadFile(*compileCmdInput)
+		inputs, err := compiler.ResolveInputs(patterns, os.Stdin)
 
 		if err != nil {
-			return fmt.Errorf("error reading logi file: %v", err)
+			return err
 		}
 
-		var output interface{}
-
-		if *compileCmdKind == "plain" {
-			plainAst, err := logi.ParsePlainContent(string(logiContent), true)
-
-			if err != nil {
-				return fmt.Errorf("error compiling logi file: %v", err)
-			}
-
-			output = plainAst.Definitions
+		var macroSet = compiler.NewMacroSet()
 
-		} else {
-
-			// list all files in macro dir
-			macroDir, err := os.ReadDir(*compileCmdMacroDir)
+		if *compileCmdKind != string(compiler.KindPlain) {
+			macroSet, err = compiler.LoadMacroDir(*compileCmdMacroDir)
 
 			if err != nil {
-				return fmt.Errorf("error reading macro dir: %v", err)
+				return err
 			}
+		}
 
-			var macros []astMacro.Macro
-
-			// for each file in macro dir
-			for _, file := range macroDir {
-				// check extension
-				if strings.HasSuffix(file.Name(), ".lgm") == false {
-					continue
-				}
-
-				fileContent, err := os.ReadFile(*compileCmdMacroDir + file.Name())
+		format, err := encoder.Get(*compileCmdFormat)
 
-				if err != nil {
-					return fmt.Errorf("error reading macro file: %v", err)
-				}
+		if err != nil {
+			return err
+		}
 
-				macroAst, err := macro.ParseMacroContent(string(fileContent), true)
+		c, err := compiler.NewCompiler(macroSet, compiler.Options{
+			Kind:        compiler.Kind(*compileCmdKind),
+			Format:      format,
+			OutDir:      *compileCmdOutDir,
+			Bundle:      *compileCmdBundle,
+			Parallelism: *compileCmdParallel,
+			Force:       *compileCmdForce,
+		})
 
-				if err != nil {
-					return fmt.Errorf("failed to load macro file: %w", err)
-				}
+		if err != nil {
+			return err
+		}
 
-				macros = append(macros, macroAst.Macros...)
-			}
+		results, err := c.Compile(inputs)
 
-			// compile logi file
-			definitions, err := logi.Parse(string(logiContent), macros, true)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				return fmt.Errorf("error compiling logi file: %v", err)
-			}
+		var failed = 0
 
-			switch *compileCmdKind {
-			case "normal":
-				var result []interface{}
-
-				for _, definition := range definitions.Definitions {
-					definition.PlainStatements = nil
-					result = append(result, definition)
-				}
-				output = result
-			default:
-				return fmt.Errorf("unknown kind: %s", *compileCmdKind)
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+				_, _ = fmt.Fprintf(os.Stderr, "%v\n", result.Err)
+			} else if result.Skipped {
+				log.Debugf("%s is not changed, skipped", result.Input.Path)
 			}
 		}
 
-		result, err := json.MarshalIndent(output, "", "  ")
-
-		if err != nil {
-			return fmt.Errorf("error marshalling definitions: %v", err)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d file(s) failed to compile", failed, len(results))
 		}
 
-		// write definitions to output directory
-		if compileCmdOutDir == nil || *compileCmdOutDir == "" {
-			fmt.Println(string(result))
-		} else {
-			var fileDir = path.Dir(*compileCmdInput)
+		// without output directory and bundle, combined output is written to stdout
+		if *compileCmdOutDir == "" && *compileCmdBundle == "" {
+			result, err := c.Encode(c.Combine(results))
 
-			var fileName = strings.TrimPrefix(*compileCmdInput, fileDir)
-			fileName = strings.TrimSuffix(fileName, ".lg") + ".json"
+			if err != nil {
+				return err
+			}
 
-			var outputFile = *compileCmdOutDir + fileName
-			err = os.WriteFile(outputFile, result, 0644)
+			os.Stdout.Write(result)
 
-			if err != nil {
-				return fmt.Errorf("error writing definitions: %v", err)
+			if !format.Binary && len(result) > 0 && result[len(result)-1] != '\n' {
+				fmt.Println()
 			}
 		}
 
@@ -123,15 +103,23 @@ var compileCmd = &cobra.Command{
 }
 
 var compileCmdMacroDir = new(string)
-var compileCmdInput = new(string)
+var compileCmdInput = new([]string)
 var compileCmdOutDir = new(string)
 var compileCmdKind = new(string)
+var compileCmdFormat = new(string)
+var compileCmdBundle = new(string)
+var compileCmdParallel = new(int)
+var compileCmdForce = new(bool)
 
 func init() {
 	rootCmd.AddCommand(compileCmd)
 
 	compileCmd.PersistentFlags().StringVarP(compileCmdMacroDir, "macro-dir", "m", ".", "directory with macro files")
-	compileCmd.PersistentFlags().StringVarP(compileCmdInput, "input", "i", "", "directory with macro files")
+	compileCmd.PersistentFlags().StringArrayVarP(compileCmdInput, "input", "i", nil, "input file, directory, glob pattern or - for stdin, can be repeated")
 	compileCmd.PersistentFlags().StringVarP(compileCmdOutDir, "out", "o", "", "output directory")
-	compileCmd.PersistentFlags().StringVarP(compileCmdKind, "kind", "k", "normal", "kind of file to compile [`plain` for plain logi data, `normal` for logi data, default is `normal`]")
+	compileCmd.PersistentFlags().StringVarP(compileCmdKind, "kind", "k", "normal", "kind of file to compile [`plain` for plain logi data, `normal` for logi data, `values` for simplified values, default is `normal`]")
+	compileCmd.PersistentFlags().StringVarP(compileCmdFormat, "format", "f", "json", fmt.Sprintf("output format [%s]", strings.Join(encoder.Names(), ", ")))
+	compileCmd.PersistentFlags().StringVar(compileCmdBundle, "bundle", "", "write all definitions combined into a single file")
+	compileCmd.PersistentFlags().IntVarP(compileCmdParallel, "parallel", "p", runtime.NumCPU(), "number of files compiled in parallel")
+	compileCmd.PersistentFlags().BoolVar(compileCmdForce, "force", false, "recompile files even if they are not changed")
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tislib/logi/pkg/dap"
+	"github.com/tislib/logi/pkg/transport"
+	"io"
+)
+
+var dapCmd = &cobra.Command{
+	Use:   "dap",
+	Short: "dap - Debug Adapter Protocol server for logi",
+	Long: `dap serves the Debug Adapter Protocol over stdio, or over TCP if an address is given.
+
+Launch arguments:
+  program       logi file
+  macros        macro files or directories, default is the directory of the program
+  definition    name of the definition to execute, default is the first one
+  implementer   implementer which executes statements, default is "print"
+  stopOnEntry   pause before the first statement`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCommand(cmd)
+
+		var serve = func(stream io.ReadWriteCloser) {
+			_ = dap.NewSession(transport.NewObjectStream(stream), dap.Options{}).Serve()
+			_ = stream.Close()
+		}
+
+		if *dapCmdAddress != "" {
+			return transport.ServeTCP(*dapCmdAddress, serve)
+		}
+
+		serve(transport.Stdio{})
+
+		return nil
+	},
+}
+
+var dapCmdAddress = new(string)
+
+func init() {
+	rootCmd.AddCommand(dapCmd)
+
+	dapCmd.PersistentFlags().StringVarP(dapCmdAddress, "address", "a", "", "TCP address to listen on, e.g. :4711")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/tislib/logi/pkg/decompiler"
+	"os"
+	"path"
+	"strings"
+)
+
+var decompileCmd = &cobra.Command{
+	Use:   "decompile [input]",
+	Short: "decompile - convert json/yaml definitions back to logi file",
+	Long:  `decompile json/yaml definitions in the normal compile output shape back to logi source, using the macro syntax`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCommand(cmd)
+
+		var input = args[0]
+
+		data, err := os.ReadFile(input)
+
+		if err != nil {
+			return fmt.Errorf("error reading input file: %v", err)
+		}
+
+		var format = decompiler.Format(*decompileCmdFormat)
+
+		if format == "" {
+			switch strings.ToLower(path.Ext(input)) {
+			case ".yaml", ".yml":
+				format = decompiler.FormatYaml
+			default:
+				format = decompiler.FormatJson
+			}
+		}
+
+		macros, err := loadMacroDir(*decompileCmdMacroDir)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := decompiler.Decompile(data, format, macros, *decompileCmdMacro)
+
+		if err != nil {
+			return fmt.Errorf("error decompiling: %v", err)
+		}
+
+		for _, issue := range result.Issues {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: %s\n", issue)
+		}
+
+		if *decompileCmdOutput == "" {
+			fmt.Print(result.Source)
+		} else {
+			err = os.WriteFile(*decompileCmdOutput, []byte(result.Source), 0644)
+
+			if err != nil {
+				return fmt.Errorf("error writing logi file: %v", err)
+			}
+		}
+
+		if *decompileCmdStrict && len(result.Issues) > 0 {
+			return fmt.Errorf("%d field(s) can not be expressed by macro syntax", len(result.Issues))
+		}
+
+		return nil
+	},
+}
+
+var decompileCmdMacroDir = new(string)
+var decompileCmdMacro = new(string)
+var decompileCmdFormat = new(string)
+var decompileCmdOutput = new(string)
+var decompileCmdStrict = new(bool)
+
+func init() {
+	rootCmd.AddCommand(decompileCmd)
+
+	decompileCmd.PersistentFlags().StringVarP(decompileCmdMacroDir, "macro-dir", "m", ".", "directory with macro files")
+	decompileCmd.PersistentFlags().StringVar(decompileCmdMacro, "macro", "", "macro name of the definitions, overrides macroName of the input")
+	decompileCmd.PersistentFlags().StringVarP(decompileCmdFormat, "format", "f", "", "input format [`json` or `yaml`, default is detected from file extension]")
+	decompileCmd.PersistentFlags().StringVarP(decompileCmdOutput, "out", "o", "", "output logi file")
+	decompileCmd.PersistentFlags().BoolVar(decompileCmdStrict, "strict", false, "fail if some fields can not be expressed by macro syntax")
+}
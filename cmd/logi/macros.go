@@ -0,0 +1,17 @@
+package main
+
+import (
+	astMacro "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/compiler"
+)
+
+// loadMacroDir parses all macro files (*.lgm) in the given directory
+func loadMacroDir(dir string) ([]astMacro.Macro, error) {
+	macroSet, err := compiler.LoadMacroDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return macroSet.Macros, nil
+}
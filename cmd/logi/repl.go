@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/tislib/logi/pkg/repl"
+	"os"
+	"path/filepath"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl [macro files...]",
+	Short: "repl - interactive shell for macros, definitions and expressions",
+	Long: `repl loads macro files, then evaluates typed macros, logi definitions and expressions.
+Definitions are printed as json, expressions are evaluated with variables set in the session. Type :help for commands.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCommand(cmd)
+
+		var macroFiles = args
+
+		if *replCmdMacroDir != "" {
+			matches, err := filepath.Glob(filepath.Join(*replCmdMacroDir, "*.lgm"))
+
+			if err != nil {
+				return err
+			}
+
+			macroFiles = append(matches, macroFiles...)
+		}
+
+		var historyFile = *replCmdHistory
+
+		if historyFile == "" && !*replCmdNoHistory {
+			if home, err := os.UserHomeDir(); err == nil {
+				historyFile = filepath.Join(home, ".logi_history")
+			}
+		}
+
+		r, err := repl.New(repl.Options{
+			MacroFiles:  macroFiles,
+			HistoryFile: historyFile,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(os.Stderr, "logi repl, type :help for commands")
+
+		return r.Run(os.Stdin, os.Stdout)
+	},
+}
+
+var replCmdMacroDir = new(string)
+var replCmdHistory = new(string)
+var replCmdNoHistory = new(bool)
+
+func init() {
+	rootCmd.AddCommand(replCmd)
+
+	replCmd.PersistentFlags().StringVarP(replCmdMacroDir, "macro-dir", "m", "", "directory with macro files")
+	replCmd.PersistentFlags().StringVar(replCmdHistory, "history", "", "history file, default is ~/.logi_history")
+	replCmd.PersistentFlags().BoolVar(replCmdNoHistory, "no-history", false, "do not save history")
+}
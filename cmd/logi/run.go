@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/implementer"
+	"github.com/tislib/logi/pkg/vm"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [inputs...]",
+	Short: "run - execute a logi definition",
+	Long: `run executes a definition of logi files with an implementer.
+Implementers are registered in Go with implementer.Register, builds of logi which import them can use them by name.
+With --process, statements are sent to an external process instead, as json lines on its stdin, and the process
+replies to each of them with a json line on its stdout:
+
+  request:  {"id": 1, "scope": "", "command": "on", "parameters": {"component": "led1"}, "attributes": {}}
+  response: {"id": 1, "error": "", "vars": {}, "output": "", "skip": false}
+
+Vars are declared for next statements, output is printed, and skip skips sub statements of the statement.
+
+With --rpc, statements are sent to a json-rpc 2.0 implementer over tcp, websocket or stdio of a command, as
+"execute" requests with the same fields and results. While executing a statement, the implementer can send
+"evaluate" requests with the execution of the statement and an expression, e.g. {"execution": 1, "expression": "x * 2"}.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCommand(cmd)
+
+		var patterns = append(*runCmdInput, args...)
+
+		if len(patterns) == 0 {
+			return fmt.Errorf("no input is given")
+		}
+
+		var v = vm.New()
+
+		macroFiles, err := filepath.Glob(filepath.Join(*runCmdMacroDir, "*.lgm"))
+
+		if err != nil {
+			return err
+		}
+
+		if err := v.LoadMacroFile(macroFiles...); err != nil {
+			return err
+		}
+
+		inputs, err := compiler.ResolveInputs(patterns, os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		for _, input := range inputs {
+			if input.Path == compiler.StdinInput {
+				_, err = v.LoadLogiContent(string(input.Content))
+			} else {
+				_, err = v.LoadLogiFile(input.Path)
+			}
+
+			if err != nil {
+				return fmt.Errorf("%s: %v", input.Path, err)
+			}
+		}
+
+		definition, err := runDefinition(v)
+
+		if err != nil {
+			return err
+		}
+
+		var impl vm.Implementer
+
+		if *runCmdRpc != "" {
+			remote, err := dialRemote(v, *runCmdRpc)
+
+			if err != nil {
+				return err
+			}
+
+			defer remote.Close()
+
+			impl = remote
+		} else if *runCmdProcess != "" {
+			command, err := splitCommand(*runCmdProcess)
+
+			if err != nil {
+				return err
+			}
+
+			process, err := implementer.NewProcess(os.Stdout, command[0], command[1:]...)
+
+			if err != nil {
+				return err
+			}
+
+			defer process.Close()
+
+			impl = process
+		} else {
+			factory, err := implementer.Get(*runCmdImplementer)
+
+			if err != nil {
+				return err
+			}
+
+			impl = factory(os.Stdout)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		if *runCmdTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, *runCmdTimeout)
+			defer cancel()
+		}
+
+		return v.(vm.Executor).ExecuteContext(ctx, definition, impl, vm.Limits{})
+	},
+}
+
+// runDefinition returns the definition given with --def, it can be omitted if only one definition is loaded
+func runDefinition(v vm.VirtualMachine) (*logiAst.Definition, error) {
+	if *runCmdDefinition != "" {
+		return v.GetDefinitionByName(*runCmdDefinition)
+	}
+
+	var definitions = v.GetDefinitions()
+
+	if len(definitions) != 1 {
+		return nil, fmt.Errorf("%d definitions are loaded, definition to run must be given with --def", len(definitions))
+	}
+
+	return &definitions[0], nil
+}
+
+// dialRemote connects to the remote implementer of the address, addresses other than tcp and websocket urls are commands
+func dialRemote(v vm.VirtualMachine, address string) (*implementer.Remote, error) {
+	var options = implementer.RemoteOptions{Timeout: *runCmdStatementTimeout, Output: os.Stdout}
+
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		return implementer.DialRemote(v, "tcp", strings.TrimPrefix(address, "tcp://"), options)
+	case strings.HasPrefix(address, "ws://") || strings.HasPrefix(address, "wss://"):
+		return implementer.DialRemoteWebsocket(v, address, options)
+	default:
+		command, err := splitCommand(address)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return implementer.StartRemote(v, options, command[0], command[1:]...)
+	}
+}
+
+// splitCommand splits the command into its name and arguments
+func splitCommand(command string) ([]string, error) {
+	var fields = strings.Fields(command)
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+
+	return fields, nil
+}
+
+var runCmdMacroDir = new(string)
+var runCmdInput = new([]string)
+var runCmdDefinition = new(string)
+var runCmdImplementer = new(string)
+var runCmdProcess = new(string)
+var runCmdTimeout = new(time.Duration)
+var runCmdRpc = new(string)
+var runCmdStatementTimeout = new(time.Duration)
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.PersistentFlags().StringVarP(runCmdMacroDir, "macro-dir", "m", ".", "directory with macro files")
+	runCmd.PersistentFlags().StringArrayVarP(runCmdInput, "input", "i", nil, "input file, directory or glob pattern, can be repeated")
+	runCmd.PersistentFlags().StringVarP(runCmdDefinition, "def", "d", "", "name of the definition to run")
+	runCmd.PersistentFlags().StringVar(runCmdImplementer, "implementer", "print", fmt.Sprintf("implementer which executes statements [%s]", strings.Join(implementer.Names(), ", ")))
+	runCmd.PersistentFlags().StringVar(runCmdProcess, "process", "", "command of an implementer process, e.g. \"python3 lamp.py\"")
+	runCmd.PersistentFlags().DurationVar(runCmdTimeout, "timeout", 0, "maximum duration of the execution")
+	runCmd.PersistentFlags().StringVar(runCmdRpc, "rpc", "", "json-rpc implementer, tcp://host:port, ws://host:port/path or a command which is used over stdio")
+	runCmd.PersistentFlags().DurationVar(runCmdStatementTimeout, "statement-timeout", 0, "maximum duration of a statement of json-rpc implementers")
+}
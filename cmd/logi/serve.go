@@ -1,26 +1,48 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
-	"github.com/tislib/logi/pkg/vm"
-	"io"
+	"github.com/tislib/logi/pkg/server"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "serve - serve logi file",
-	Long:  `serve logi file and generate definitions`,
+	Short: "serve - serve REST API for logi",
+	Long: `serve REST API to validate and compile logi content, and manage macros and definitions at runtime.
+
+Macros and definitions are kept in isolated namespaces. Endpoints below use the default namespace,
+all of them are also available under /namespaces/{namespace} prefix.
+
+Endpoints:
+  GET    /namespaces                       list namespaces
+  POST   /namespaces                       create namespace, body is {"name": "..."}
+  GET    /namespaces/{namespace}           get namespace
+  DELETE /namespaces/{namespace}           delete namespace with its macros and definitions
+  POST   /validate[?type=macro]            validate logi or macro content without storing it
+  POST   /compile[?kind=..&format=..]      compile logi content
+  GET    /macros                           list macros
+  POST   /macros                           create macros
+  GET    /macros/{name}                    get macro
+  DELETE /macros/{name}                    delete macro
+  GET    /definitions[?macro=..&name=..]   list and query definitions
+  POST   /definitions                      load definitions
+  GET    /definitions/{name}               get definition
+  DELETE /definitions/{name}               delete definition
+  POST   /                                 load definitions, duplicate names are appended (kept for compatibility)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		vm := vm.New()
+		initCommand(cmd)
 
-		if strings.HasSuffix(*serveCmdMacroDir, "/") == false {
-			*serveCmdMacroDir = *serveCmdMacroDir + "/"
-		}
+		s := server.New(server.Options{
+			MaxBodySize:    *serveCmdMaxBodySize,
+			MaxNamespaces:  *serveCmdMaxNamespaces,
+			MaxMacros:      *serveCmdMaxMacros,
+			MaxDefinitions: *serveCmdMaxDefinitions,
+		})
 
 		// list all files in macro dir
 		macroDir, err := os.ReadDir(*serveCmdMacroDir)
@@ -36,7 +58,13 @@ var serveCmd = &cobra.Command{
 				continue
 			}
 
-			err = vm.LoadMacroFile(*serveCmdMacroDir + file.Name())
+			content, err := os.ReadFile(filepath.Join(*serveCmdMacroDir, file.Name()))
+
+			if err != nil {
+				return fmt.Errorf("error reading macro file: %v", err)
+			}
+
+			err = s.LoadMacroContent(string(content))
 
 			if err != nil {
 				return fmt.Errorf("failed to load macro file: %w", err)
@@ -44,40 +72,11 @@ var serveCmd = &cobra.Command{
 		}
 
 		srv := http.Server{
-			Addr: ":7051",
-			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				body, err := io.ReadAll(r.Body)
-
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write([]byte(fmt.Sprintf("error reading request body: %v", err)))
-					return
-				}
-
-				// serve logi file
-				definitions, err := vm.LoadLogiContent(string(body))
-
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write([]byte(fmt.Sprintf("error reading request body: %v", err)))
-					return
-				}
-
-				result, err := json.MarshalIndent(definitions, "", "  ")
-
-				if err != nil {
-					w.WriteHeader(http.StatusBadRequest)
-					w.Write([]byte(fmt.Sprintf("error reading request body: %v", err)))
-					return
-				}
-
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				w.Write(result)
-			}),
+			Addr:    *serveCmdListen,
+			Handler: s,
 		}
 
-		fmt.Println("Listening to :7051")
+		fmt.Printf("Listening to %s\n", *serveCmdListen)
 		err = srv.ListenAndServe()
 
 		if err != nil {
@@ -89,9 +88,19 @@ var serveCmd = &cobra.Command{
 }
 
 var serveCmdMacroDir = new(string)
+var serveCmdListen = new(string)
+var serveCmdMaxBodySize = new(int64)
+var serveCmdMaxNamespaces = new(int)
+var serveCmdMaxMacros = new(int)
+var serveCmdMaxDefinitions = new(int)
 
 func init() {
 	rootCmd.AddCommand(serveCmd)
 
 	serveCmd.PersistentFlags().StringVarP(serveCmdMacroDir, "macro-dir", "m", ".", "directory with macro files")
+	serveCmd.PersistentFlags().StringVarP(serveCmdListen, "listen", "l", ":7051", "address to listen")
+	serveCmd.PersistentFlags().Int64Var(serveCmdMaxBodySize, "max-body-size", 10<<20, "maximum size of request content in bytes")
+	serveCmd.PersistentFlags().IntVar(serveCmdMaxNamespaces, "max-namespaces", 100, "maximum number of namespaces, 0 means no limit")
+	serveCmd.PersistentFlags().IntVar(serveCmdMaxMacros, "max-macros", 1000, "maximum number of macros per namespace, 0 means no limit")
+	serveCmd.PersistentFlags().IntVar(serveCmdMaxDefinitions, "max-definitions", 10000, "maximum number of definitions per namespace, 0 means no limit")
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/encoder"
+	"github.com/tislib/logi/pkg/watcher"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [inputs...]",
+	Short: "watch - recompile logi files on change",
+	Long: `watch logi and macro files and recompile them incrementally.
+When a macro changes, only logi files using that macro are recompiled. Diagnostics are printed continuously.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		initCommand(cmd)
+
+		var patterns = append(*watchCmdInput, args...)
+
+		if len(patterns) == 0 {
+			return fmt.Errorf("no input is given")
+		}
+
+		format, err := encoder.Get(*watchCmdFormat)
+
+		if err != nil {
+			return err
+		}
+
+		w, err := compiler.NewWatch(*watchCmdMacroDir, patterns, compiler.Options{
+			Kind:   compiler.Kind(*watchCmdKind),
+			Format: format,
+			OutDir: *watchCmdOutDir,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		results, err := w.Start()
+
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			printWatchResult(result)
+		}
+
+		var fileWatcher watcher.FileWatcher
+
+		if *watchCmdPoll {
+			fileWatcher, err = watcher.NewPollWatcher(w.Dirs(), *watchCmdInterval)
+		} else {
+			fileWatcher, err = watcher.NewFileWatcher(w.Dirs(), *watchCmdInterval)
+		}
+
+		if err != nil {
+			return fmt.Errorf("error watching files: %v", err)
+		}
+
+		defer fileWatcher.Close()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		_, _ = fmt.Fprintf(os.Stderr, "watching %s\n", strings.Join(w.Dirs(), ", "))
+
+		return w.Run(ctx, fileWatcher, printWatchResult)
+	},
+}
+
+func printWatchResult(result compiler.Result) {
+	var now = time.Now().Format("15:04:05")
+
+	if result.Err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[%s] error: %v\n", now, result.Err)
+	} else if result.Removed && result.Output != "" {
+		_, _ = fmt.Fprintf(os.Stderr, "[%s] removed %s, deleted %s\n", now, result.Input.Path, result.Output)
+	} else if result.Removed {
+		_, _ = fmt.Fprintf(os.Stderr, "[%s] removed %s\n", now, result.Input.Path)
+	} else if result.Skipped {
+		log.Debugf("%s is not changed, skipped", result.Input.Path)
+	} else if result.Output != "" {
+		_, _ = fmt.Fprintf(os.Stderr, "[%s] compiled %s -> %s\n", now, result.Input.Path, result.Output)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "[%s] ok %s\n", now, result.Input.Path)
+	}
+}
+
+var watchCmdMacroDir = new(string)
+var watchCmdInput = new([]string)
+var watchCmdOutDir = new(string)
+var watchCmdKind = new(string)
+var watchCmdFormat = new(string)
+var watchCmdPoll = new(bool)
+var watchCmdInterval = new(time.Duration)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.PersistentFlags().StringVarP(watchCmdMacroDir, "macro-dir", "m", ".", "directory with macro files")
+	watchCmd.PersistentFlags().StringArrayVarP(watchCmdInput, "input", "i", nil, "input file, directory or glob pattern, can be repeated")
+	watchCmd.PersistentFlags().StringVarP(watchCmdOutDir, "out", "o", "", "output directory, if it is not set files are only checked")
+	watchCmd.PersistentFlags().StringVarP(watchCmdKind, "kind", "k", "normal", "kind of file to compile [`plain`, `normal` or `values`]")
+	watchCmd.PersistentFlags().StringVarP(watchCmdFormat, "format", "f", "json", fmt.Sprintf("output format [%s]", strings.Join(encoder.Names(), ", ")))
+	watchCmd.PersistentFlags().BoolVar(watchCmdPoll, "poll", false, "use polling instead of inotify")
+	watchCmd.PersistentFlags().DurationVar(watchCmdInterval, "interval", time.Second, "polling interval")
+}
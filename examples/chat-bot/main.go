@@ -30,16 +30,16 @@ func main() {
 		log.Fatal(err)
 	}
 
-	implementer := &chatBotImplementer{
+	chatBot := &chatBot{
 		intents: make(map[string]intent),
 	}
 
-	// Execute the definition
-	if err := v.Execute(definition, implementer); err != nil {
+	// Execute the definition, sub statements of intents are executed after the intent handler
+	if err := v.Execute(definition, chatBot.router()); err != nil {
 		log.Fatal(err)
 	}
 
-	log.Println(implementer.intents)
+	log.Println(chatBot.intents)
 	// map[Farewell:{Goodbye See you later!} Greeting:{Hello Hi there!}]
 }
 
@@ -48,38 +48,32 @@ type intent struct {
 	response string
 }
 
-type chatBotImplementer struct {
+type chatBot struct {
 	intents map[string]intent
 
 	currentIntent string
 }
 
-func (c *chatBotImplementer) Call(vm vm.VirtualMachine, statement logiAst.Statement) error {
-	if statement.Scope == "" {
-		switch statement.Command {
-		case "intent":
+func (c *chatBot) router() *vm.Router {
+	return vm.NewRouter().
+		Handle("", "intent", func(vm vm.VirtualMachine, statement logiAst.Statement) error {
 			c.currentIntent = statement.GetParameter("name").AsString()
+			c.intents[c.currentIntent] = intent{}
 
-			for _, subStatement := range statement.SubStatements[0] {
-				if err := c.Call(vm, subStatement); err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	if statement.Scope == "conversation" {
-		switch statement.Command {
-		case "pattern":
+			return nil
+		}).
+		Handle("conversation", "pattern", func(vm vm.VirtualMachine, statement logiAst.Statement) error {
 			i := c.intents[c.currentIntent]
 			i.pattern = statement.GetParameter("pattern").AsString()
 			c.intents[c.currentIntent] = i
-		case "response":
+
+			return nil
+		}).
+		Handle("conversation", "response", func(vm vm.VirtualMachine, statement logiAst.Statement) error {
 			i := c.intents[c.currentIntent]
 			i.response = statement.GetParameter("response").AsString()
 			c.intents[c.currentIntent] = i
-		}
-	}
 
-	return nil
+			return nil
+		})
 }
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -195,3 +197,90 @@ func MapValue(m map[string]Value) Value {
 		Map:  m,
 	}
 }
+
+// ValueOf converts a plain value, e.g. decoded from json, to a Value. Json numbers are expected as json.Number, so
+// integers and floats keep their kind, integers out of int64 range are converted to floats.
+func ValueOf(value interface{}) (Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return NullValue(), nil
+	case Value:
+		return v, nil
+	case string:
+		return StringValue(v), nil
+	case bool:
+		return BooleanValue(v), nil
+	case int:
+		return IntegerValue(int64(v)), nil
+	case int64:
+		return IntegerValue(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return FloatValue(float64(v)), nil
+		}
+
+		return IntegerValue(int64(v)), nil
+	case float64:
+		return FloatValue(v), nil
+	case json.Number:
+		// numbers with a fraction or exponent, and integers out of range, are not parsed as integers
+		if i, err := v.Int64(); err == nil {
+			return IntegerValue(i), nil
+		}
+
+		f, err := v.Float64()
+
+		if err != nil {
+			return Value{}, err
+		}
+
+		return FloatValue(f), nil
+	case []interface{}:
+		var result []Value
+
+		for _, item := range v {
+			itemValue, err := ValueOf(item)
+
+			if err != nil {
+				return Value{}, err
+			}
+
+			result = append(result, itemValue)
+		}
+
+		return ArrayValue(result...), nil
+	case map[string]interface{}:
+		var result = make(map[string]Value)
+
+		for key, item := range v {
+			itemValue, err := ValueOf(item)
+
+			if err != nil {
+				return Value{}, err
+			}
+
+			result[key] = itemValue
+		}
+
+		return MapValue(result), nil
+	default:
+		return Value{}, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// ValuesOf converts plain values to Values, see ValueOf
+func ValuesOf(values map[string]interface{}) (map[string]Value, error) {
+	var result = make(map[string]Value)
+
+	for name, item := range values {
+		value, err := ValueOf(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %v", name, err)
+		}
+
+		result[name] = value
+	}
+
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestValueOf(t *testing.T) {
+	tests := map[string]struct {
+		input         interface{}
+		expected      Value
+		expectedError string
+	}{
+		"null":             {input: nil, expected: NullValue()},
+		"string":           {input: "a", expected: StringValue("a")},
+		"boolean":          {input: true, expected: BooleanValue(true)},
+		"int":              {input: 2, expected: IntegerValue(2)},
+		"float":            {input: 2.5, expected: FloatValue(2.5)},
+		"whole float":      {input: 2.0, expected: FloatValue(2)},
+		"uint64":           {input: uint64(2), expected: IntegerValue(2)},
+		"uint64 overflow":  {input: uint64(math.MaxUint64), expected: FloatValue(math.MaxUint64)},
+		"json integer":     {input: json.Number("2"), expected: IntegerValue(2)},
+		"json whole float": {input: json.Number("2.0"), expected: FloatValue(2)},
+		"json exponent":    {input: json.Number("1e3"), expected: FloatValue(1000)},
+		"json overflow":    {input: json.Number("18446744073709551616"), expected: FloatValue(18446744073709551616)},
+		"array": {
+			input:    []interface{}{"a", json.Number("1")},
+			expected: ArrayValue(StringValue("a"), IntegerValue(1)),
+		},
+		"map": {
+			input:    map[string]interface{}{"a": json.Number("1.5")},
+			expected: MapValue(map[string]Value{"a": FloatValue(1.5)}),
+		},
+		"unsupported": {input: struct{}{}, expectedError: "unsupported value type struct {}"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			value, err := ValueOf(tt.input)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, value)
+			}
+		})
+	}
+}
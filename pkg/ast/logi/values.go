@@ -0,0 +1,124 @@
+package logi
+
+// Values returns simplified representation of the definition.
+// Statements are keyed by their command, or by the value of their first parameter if they have no command.
+// If there are multiple statements with the same key, their values are collected into a list.
+func (d Definition) Values() map[string]interface{} {
+	return statementValues(d.Statements)
+}
+
+// Values returns parameters and attributes of the statement as plain values.
+// Array items are put under their parameter name and scope statements are put under `statements` key.
+func (s Statement) Values() map[string]interface{} {
+	var result = make(map[string]interface{})
+
+	for _, parameter := range s.Parameters {
+		result[parameter.Name] = parameter.Value.AsInterface()
+	}
+
+	for _, attribute := range s.Attributes {
+		if attribute.Value != nil {
+			result[attribute.Name] = attribute.Value.AsInterface()
+		} else {
+			result[attribute.Name] = true
+		}
+	}
+
+	if len(s.Arguments) > 0 {
+		var arguments = make(map[string]interface{})
+
+		for _, argument := range s.Arguments {
+			arguments[argument.Name] = argument.Type.ToDisplayName()
+		}
+
+		result["arguments"] = arguments
+	}
+
+	var bodies []interface{}
+
+	for _, subStatements := range s.SubStatements {
+		if len(subStatements) > 0 && IsArrayItems(subStatements) {
+			key, items := arrayItemValues(subStatements)
+			result[key] = items
+			continue
+		}
+
+		bodies = append(bodies, statementValues(subStatements))
+	}
+
+	if len(bodies) == 1 {
+		result["statements"] = bodies[0]
+	} else if len(bodies) > 1 {
+		result["statements"] = bodies
+	}
+
+	return result
+}
+
+func statementValues(statements []Statement) map[string]interface{} {
+	var result = make(map[string]interface{})
+	var lists = make(map[string]bool)
+
+	for _, statement := range statements {
+		var key = statementKey(statement)
+		var value = statement.Values()
+
+		existing, found := result[key]
+
+		if !found {
+			result[key] = value
+		} else if lists[key] {
+			result[key] = append(existing.([]interface{}), value)
+		} else {
+			result[key] = []interface{}{existing, value}
+			lists[key] = true
+		}
+	}
+
+	return result
+}
+
+func statementKey(statement Statement) string {
+	if statement.Command != "" {
+		return statement.Command
+	}
+
+	if len(statement.Parameters) > 0 {
+		return statement.Parameters[0].Value.ToDisplayName()
+	}
+
+	return ""
+}
+
+// arrayItemValues converts array items, items with a single parameter are converted to their value.
+func arrayItemValues(items []Statement) (string, []interface{}) {
+	var key = ""
+	var result []interface{}
+
+	for _, item := range items {
+		if len(item.Parameters) == 1 && len(item.SubStatements) == 0 {
+			key = item.Parameters[0].Name
+			result = append(result, item.Parameters[0].Value.AsInterface())
+		} else {
+			result = append(result, item.Values())
+		}
+	}
+
+	if key == "" {
+		key = "items"
+	}
+
+	return key, result
+}
+
+// IsArrayItems checks if the sub statements are items of an array parameter instead of scope statements,
+// array items have neither scope nor command.
+func IsArrayItems(statements []Statement) bool {
+	for _, statement := range statements {
+		if statement.Scope != "" || statement.Command != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,71 @@
+package logi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	"testing"
+)
+
+func TestDefinitionValues(t *testing.T) {
+	definition := Definition{
+		Statements: []Statement{
+			{
+				Command: "creditScore",
+				Parameters: []Parameter{
+					{Name: "min", Value: common.IntegerValue(500)},
+					{Name: "max", Value: common.IntegerValue(600)},
+				},
+			},
+			{
+				Command: "roles",
+				SubStatements: [][]Statement{
+					{
+						{Parameters: []Parameter{{Name: "roles", Value: common.StringValue("Role1")}}},
+						{Parameters: []Parameter{{Name: "roles", Value: common.StringValue("Role2")}}},
+					},
+				},
+			},
+			{
+				Parameters: []Parameter{
+					{Name: "propertyName", Value: common.StringValue("id")},
+				},
+				Attributes: []Attribute{
+					{Name: "primary"},
+					{Name: "default", Value: common.PointerValue(common.IntegerValue(1))},
+				},
+			},
+			{
+				Command: "permissions",
+				SubStatements: [][]Statement{
+					{
+						{Scope: "permissions", Command: "WRITE", Parameters: []Parameter{{Name: "object", Value: common.StringValue("object1")}}},
+						{Scope: "permissions", Command: "WRITE", Parameters: []Parameter{{Name: "object", Value: common.StringValue("object2")}}},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"creditScore": map[string]interface{}{
+			"min": int64(500),
+			"max": int64(600),
+		},
+		"roles": map[string]interface{}{
+			"roles": []interface{}{"Role1", "Role2"},
+		},
+		"id": map[string]interface{}{
+			"propertyName": "id",
+			"primary":      true,
+			"default":      int64(1),
+		},
+		"permissions": map[string]interface{}{
+			"statements": map[string]interface{}{
+				"WRITE": []interface{}{
+					map[string]interface{}{"object": "object1"},
+					map[string]interface{}{"object": "object2"},
+				},
+			},
+		},
+	}, definition.Values())
+}
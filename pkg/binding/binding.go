@@ -0,0 +1,77 @@
+package binding
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/encoder"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/vm"
+	"strings"
+)
+
+// Compile returns the definitions as json, with the same output as `logi compile` with the kind.
+// Kind is normal or values, default is normal, plain kind is not supported as definitions are already matched.
+func Compile(definitions []logiAst.Definition, kind string) (string, error) {
+	switch compiler.Kind(kind) {
+	case "":
+		kind = string(compiler.KindNormal)
+	case compiler.KindPlain:
+		return "", fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	value, err := compiler.CompileDefinitions(definitions, compiler.Kind(kind))
+
+	if err != nil {
+		return "", err
+	}
+
+	return Encode(value)
+}
+
+// Evaluate evaluates the expression with the variables given as a json object, and returns its value as json
+func Evaluate(v vm.VirtualMachine, expression string, varsJson string) (string, error) {
+	parsed, err := logi.ParseExpression(expression)
+
+	if err != nil {
+		return "", err
+	}
+
+	var plainVars map[string]interface{}
+
+	if varsJson != "" {
+		decoder := json.NewDecoder(strings.NewReader(varsJson))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&plainVars); err != nil {
+			return "", fmt.Errorf("invalid variables: %v", err)
+		}
+	}
+
+	vars, err := common.ValuesOf(plainVars)
+
+	if err != nil {
+		return "", err
+	}
+
+	value, err := v.Evaluate(parsed, vars, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return Encode(value.AsInterface())
+}
+
+// Encode encodes the value as json
+func Encode(value interface{}) (string, error) {
+	data, err := encoder.Encode("json", value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,113 @@
+package binding
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/vm"
+	"testing"
+)
+
+const greeterLgm = `macro greeter {
+    kind Syntax
+
+    syntax {
+        greet <name string>
+    }
+}`
+
+func TestCompile(t *testing.T) {
+	tests := map[string]struct {
+		content        string
+		kind           string
+		expectedOutput string
+		expectedError  string
+	}{
+		"default kind": {
+			content:        "greeter hello {\n    greet \"world\"\n}",
+			expectedOutput: `"name": "hello"`,
+		},
+		"values": {
+			content:        "greeter hello {\n    greet \"world\"\n}",
+			kind:           "values",
+			expectedOutput: `"name": "world"`,
+		},
+		"no definitions": {
+			kind:           "normal",
+			expectedOutput: "[]",
+		},
+		"plain": {
+			kind:          "plain",
+			expectedError: "unsupported kind: plain",
+		},
+		"unknown kind": {
+			kind:          "unknown",
+			expectedError: "unknown kind: unknown",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var v = vm.New()
+
+			assert.NoError(t, v.LoadMacroContent(greeterLgm))
+
+			definitions, err := v.LoadLogiContent(tt.content)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			output, err := Compile(definitions, tt.kind)
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Contains(t, output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := map[string]struct {
+		expression     string
+		vars           string
+		expectedOutput string
+		expectedError  string
+	}{
+		"without variables": {
+			expression:     "1 + 2",
+			expectedOutput: "3",
+		},
+		"integer variable": {
+			expression:     "x * 2",
+			vars:           `{"x": 21}`,
+			expectedOutput: "42",
+		},
+		"float variable": {
+			expression:     "x * 2",
+			vars:           `{"x": 1.25}`,
+			expectedOutput: "2.5",
+		},
+		"invalid variables": {
+			expression:    "x",
+			vars:          `[]`,
+			expectedError: "invalid variables",
+		},
+		"unknown variable": {
+			expression:    "x + 1",
+			expectedError: "variable x not found",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			output, err := Evaluate(vm.New(), tt.expression, tt.vars)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedOutput, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package capi
+
+import (
+	"github.com/tislib/logi/pkg/binding"
+	"github.com/tislib/logi/pkg/vm"
+)
+
+// Vm is a virtual machine used by the c api, macros and definitions are kept between calls
+type Vm struct {
+	vm vm.VirtualMachine
+}
+
+func New() *Vm {
+	var v = vm.New()
+
+	// contents can be loaded again, e.g. after they are edited
+	v.(vm.Registry).SetDuplicatePolicy(vm.DuplicateOverride)
+
+	return &Vm{vm: v}
+}
+
+func (v *Vm) LoadMacro(content string) error {
+	return v.vm.LoadMacroContent(content)
+}
+
+func (v *Vm) LoadLogi(content string) error {
+	_, err := v.vm.LoadLogiContent(content)
+
+	return err
+}
+
+// Compile returns loaded definitions as json, see binding.Compile
+func (v *Vm) Compile(kind string) (string, error) {
+	return binding.Compile(v.vm.GetDefinitions(), kind)
+}
+
+// Evaluate evaluates the expression with the variables of the json object, see binding.Evaluate
+func (v *Vm) Evaluate(expression string, varsJson string) (string, error) {
+	return binding.Evaluate(v.vm, expression, varsJson)
+}
@@ -0,0 +1,91 @@
+package capi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const lampLgm = `macro lamp {
+    kind Syntax
+
+    syntax {
+        power <watts int>
+    }
+}`
+
+func TestVm(t *testing.T) {
+	tests := map[string]struct {
+		macros        string
+		logi          string
+		fn            func(v *Vm) (string, error)
+		expected      string
+		expectedError string
+	}{
+		"compile": {
+			macros: lampLgm,
+			logi:   "lamp l1 {\n    power 60\n}",
+			fn: func(v *Vm) (string, error) {
+				return v.Compile("values")
+			},
+			expected: "{\n  \"l1\": {\n    \"power\": {\n      \"watts\": 60\n    }\n  }\n}",
+		},
+		"compile without definitions": {
+			fn: func(v *Vm) (string, error) {
+				return v.Compile("")
+			},
+			expected: "[]",
+		},
+		"compile plain": {
+			fn: func(v *Vm) (string, error) {
+				return v.Compile("plain")
+			},
+			expectedError: "unsupported kind: plain",
+		},
+		"load again": {
+			macros: lampLgm,
+			logi:   "lamp l1 {\n    power 60\n}",
+			fn: func(v *Vm) (string, error) {
+				if err := v.LoadLogi("lamp l1 {\n    power 40\n}"); err != nil {
+					return "", err
+				}
+
+				return v.Compile("values")
+			},
+			expected: "{\n  \"l1\": {\n    \"power\": {\n      \"watts\": 40\n    }\n  }\n}",
+		},
+		"evaluate": {
+			fn: func(v *Vm) (string, error) {
+				return v.Evaluate("x * 2", `{"x": 21}`)
+			},
+			expected: "42",
+		},
+		"evaluate invalid variables": {
+			fn: func(v *Vm) (string, error) {
+				return v.Evaluate("x * 2", `[]`)
+			},
+			expectedError: "invalid variables",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var v = New()
+
+			if tt.macros != "" {
+				assert.NoError(t, v.LoadMacro(tt.macros))
+			}
+
+			if tt.logi != "" {
+				assert.NoError(t, v.LoadLogi(tt.logi))
+			}
+
+			result, err := tt.fn(v)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
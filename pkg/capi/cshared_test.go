@@ -0,0 +1,61 @@
+package capi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestSharedLibrary builds cmd/liblogi as a c shared library, and runs the c harness with it
+func TestSharedLibrary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("c shared library build is skipped in short mode")
+	}
+
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("c harness is only built on linux and darwin")
+	}
+
+	gcc, err := exec.LookPath("gcc")
+
+	if err != nil {
+		t.Skip("gcc is not installed")
+	}
+
+	var dir = t.TempDir()
+	var include = filepath.Join("..", "..", "cmd", "liblogi")
+
+	var build = exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, "liblogi.so"), "github.com/tislib/logi/cmd/liblogi")
+	build.Env = append(os.Environ(), "CGO_ENABLED=1")
+
+	if output, err := build.CombinedOutput(); !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	var harness = filepath.Join(dir, "harness")
+
+	var compile = exec.Command(gcc, "-Wall", "-Werror", "-o", harness, filepath.Join("test_data", "harness.c"), "-I", include, "-L", dir, "-llogi", "-Wl,-rpath,"+dir)
+
+	if output, err := compile.CombinedOutput(); !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	output, err := exec.Command(harness).CombinedOutput()
+
+	if assert.NoError(t, err, string(output)) {
+		assert.Equal(t, `compile: {
+  "l1": {
+    "power": {
+      "watts": 60
+    }
+  }
+}
+evaluate: 42
+error: error parsing logi content: failed to locate macro definition: macro definition not found: unknown
+error: invalid vm handle: 1
+`, string(output))
+	}
+}
@@ -0,0 +1,66 @@
+// harness.c checks the c api of liblogi, it prints each result and exits with 1 on the first failed check
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "logi.h"
+
+#define CHECK(condition, message)                  \
+    if (!(condition)) {                            \
+        fprintf(stderr, "FAIL: %s\n", message);    \
+        return 1;                                  \
+    }
+
+static const char *lamp_lgm =
+    "macro lamp {\n"
+    "    kind Syntax\n"
+    "\n"
+    "    syntax {\n"
+    "        power <watts int>\n"
+    "    }\n"
+    "}\n";
+
+static const char *lamp_lg =
+    "lamp l1 {\n"
+    "    power 60\n"
+    "}\n";
+
+int main(void) {
+    char *error = NULL;
+    char *result = NULL;
+
+    logi_vm vm = logi_vm_new();
+    CHECK(vm != 0, "vm is created");
+
+    CHECK(logi_load_macro(vm, lamp_lgm, &error) == 0, "macro is loaded");
+    CHECK(logi_load_logi(vm, lamp_lg, &error) == 0, "definition is loaded");
+
+    result = logi_compile(vm, "values", &error);
+    CHECK(result != NULL, "definitions are compiled");
+    printf("compile: %s\n", result);
+    logi_free(result);
+
+    result = logi_evaluate(vm, "x * 2", "{\"x\": 21}", &error);
+    CHECK(result != NULL, "expression is evaluated");
+    printf("evaluate: %s\n", result);
+    logi_free(result);
+
+    CHECK(logi_load_logi(vm, "unknown u1 {\n    power 60\n}\n", &error) == -1, "definition without macro fails");
+    CHECK(error != NULL, "error is set");
+    printf("error: %s\n", error);
+    logi_free(error);
+    error = NULL;
+
+    // errors are optional
+    CHECK(logi_evaluate(vm, "y + 1", NULL, NULL) == NULL, "unknown variable fails");
+
+    logi_vm_free(vm);
+
+    CHECK(logi_compile(vm, NULL, &error) == NULL, "freed vm can not be used");
+    printf("error: %s\n", error);
+    logi_free(error);
+
+    logi_free(NULL);
+
+    return 0;
+}
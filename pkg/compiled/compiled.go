@@ -0,0 +1,151 @@
+package compiled
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/encoder"
+	"hash/crc32"
+	"io"
+)
+
+// FormatVersion is the version of the binary format, it must be increased when ast structures are changed,
+// so artifacts compiled by older versions are rejected instead of being decoded incorrectly.
+const FormatVersion uint16 = 1
+
+// FormatName is the name of the binary format in encoder registry
+const FormatName = "bin"
+
+var magic = []byte("LOGI")
+
+var ErrNotCompiled = errors.New("not a compiled logi artifact")
+var ErrVersionMismatch = errors.New("compiled artifact version mismatch")
+var ErrCorrupted = errors.New("compiled artifact is corrupted")
+
+// Artifact is the content of a compiled file.
+//
+// Binary layout: magic "LOGI", format version (uint16, big endian), payload length (uint64, big endian),
+// gob encoded payload and crc32 checksum of the payload (uint32, big endian).
+type Artifact struct {
+	Macros      []macroAst.Macro
+	Definitions []logiAst.Definition
+}
+
+// IsCompiled checks if data starts with the header of a compiled artifact
+func IsCompiled(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+func Write(w io.Writer, artifact Artifact) error {
+	var payload bytes.Buffer
+
+	if err := gob.NewEncoder(&payload).Encode(artifact); err != nil {
+		return fmt.Errorf("error encoding artifact: %w", err)
+	}
+
+	var header = make([]byte, 0, len(magic)+2+8)
+	header = append(header, magic...)
+	header = binary.BigEndian.AppendUint16(header, FormatVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(payload.Len()))
+
+	var checksum = binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(payload.Bytes()))
+
+	for _, part := range [][]byte{header, payload.Bytes(), checksum} {
+		if _, err := w.Write(part); err != nil {
+			return fmt.Errorf("error writing artifact: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func Read(r io.Reader) (*Artifact, error) {
+	var reader = bufio.NewReader(r)
+	var header = make([]byte, len(magic)+2+8)
+
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotCompiled
+		}
+
+		return nil, fmt.Errorf("error reading artifact: %w", err)
+	}
+
+	if !IsCompiled(header) {
+		return nil, ErrNotCompiled
+	}
+
+	var version = binary.BigEndian.Uint16(header[len(magic):])
+
+	if version != FormatVersion {
+		return nil, fmt.Errorf("%w: artifact version is %d, supported version is %d, recompile it", ErrVersionMismatch, version, FormatVersion)
+	}
+
+	var length = binary.BigEndian.Uint64(header[len(magic)+2:])
+	var payload bytes.Buffer
+
+	if n, err := io.CopyN(&payload, reader, int64(length)); err != nil || uint64(n) != length {
+		return nil, fmt.Errorf("%w: unexpected end of payload", ErrCorrupted)
+	}
+
+	var checksum = make([]byte, 4)
+
+	if _, err := io.ReadFull(reader, checksum); err != nil {
+		return nil, fmt.Errorf("%w: missing checksum", ErrCorrupted)
+	}
+
+	if binary.BigEndian.Uint32(checksum) != crc32.ChecksumIEEE(payload.Bytes()) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+
+	var artifact Artifact
+
+	if err := gob.NewDecoder(&payload).Decode(&artifact); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	return &artifact, nil
+}
+
+// encodeArtifact encodes compile output as an artifact, it accepts an artifact or a list of definitions
+func encodeArtifact(value interface{}) ([]byte, error) {
+	var artifact Artifact
+
+	switch v := value.(type) {
+	case Artifact:
+		artifact = v
+	case *Artifact:
+		artifact = *v
+	case []logiAst.Definition:
+		artifact.Definitions = v
+	case []interface{}:
+		for _, item := range v {
+			definition, ok := item.(logiAst.Definition)
+
+			if !ok {
+				return nil, fmt.Errorf("%s format supports only definitions, use normal kind", FormatName)
+			}
+
+			artifact.Definitions = append(artifact.Definitions, definition)
+		}
+	default:
+		return nil, fmt.Errorf("%s format supports only definitions, use normal kind", FormatName)
+	}
+
+	var buf bytes.Buffer
+
+	if err := Write(&buf, artifact); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func init() {
+	encoder.Register(encoder.Format{Name: FormatName, Extension: ".bin", ContentType: "application/octet-stream", Binary: true, Encode: encodeArtifact})
+}
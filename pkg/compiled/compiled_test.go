@@ -0,0 +1,229 @@
+package compiled
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/encoder"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"strings"
+	"testing"
+)
+
+const circuitMacro = `
+macro circuit {
+    kind Syntax
+
+    syntax {
+        components { components }
+        actions { command }
+    }
+
+    scopes {
+        components {
+            Led <component Name> <pin int>
+        }
+        command {
+            on(<component Name>)
+            off(<component Name>)
+            if (<condition bool>) { command }
+            if (<condition bool>) { command } else { command }
+        }
+    }
+}
+`
+
+const circuitLogi = `
+circuit simple1 {
+    components {
+        Led yellowLed 5
+        Led redLed 6
+    }
+
+    actions {
+        on(yellowLed)
+
+        if (status(redLed) == 'on' & 1 < 2) {
+            off(redLed)
+        } else {
+            on(redLed)
+        }
+    }
+}
+`
+
+func parseArtifact(t testing.TB) Artifact {
+	mAst, err := macro.ParseMacroContent(circuitMacro, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lAst, err := logi.Parse(circuitLogi, mAst.Macros, true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Artifact{Macros: mAst.Macros, Definitions: lAst.Definitions}
+}
+
+func writeArtifact(t testing.TB, artifact Artifact) []byte {
+	var buf bytes.Buffer
+
+	if err := Write(&buf, artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	artifact := parseArtifact(t)
+	data := writeArtifact(t, artifact)
+
+	assert.True(t, IsCompiled(data))
+
+	loaded, err := Read(bytes.NewReader(data))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected, _ := json.Marshal(artifact)
+	actual, _ := json.Marshal(loaded)
+
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestReadErrors(t *testing.T) {
+	valid := writeArtifact(t, parseArtifact(t))
+
+	tests := map[string]struct {
+		data          func() []byte
+		expectedError error
+	}{
+		"empty": {
+			data:          func() []byte { return nil },
+			expectedError: ErrNotCompiled,
+		},
+		"json": {
+			data:          func() []byte { return []byte(`{"definitions": []}`) },
+			expectedError: ErrNotCompiled,
+		},
+		"other version": {
+			data: func() []byte {
+				data := append([]byte{}, valid...)
+				binary.BigEndian.PutUint16(data[len(magic):], FormatVersion+1)
+				return data
+			},
+			expectedError: ErrVersionMismatch,
+		},
+		"truncated": {
+			data:          func() []byte { return valid[:len(valid)/2] },
+			expectedError: ErrCorrupted,
+		},
+		"modified payload": {
+			data: func() []byte {
+				data := append([]byte{}, valid...)
+				data[len(data)/2] ^= 0xff
+				return data
+			},
+			expectedError: ErrCorrupted,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.data()))
+
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestEncoderFormat(t *testing.T) {
+	artifact := parseArtifact(t)
+
+	var definitions []interface{}
+
+	for _, definition := range artifact.Definitions {
+		definitions = append(definitions, definition)
+	}
+
+	data, err := encoder.Encode(FormatName, definitions)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	loaded, err := Read(bytes.NewReader(data))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, loaded.Definitions, 1)
+	assert.Empty(t, loaded.Macros)
+
+	_, err = encoder.Encode(FormatName, map[string]interface{}{"simple1": map[string]interface{}{}})
+
+	assert.Error(t, err)
+}
+
+// largeLogi returns a rule set with many definitions, decoding has a fixed cost of type information, so binary format
+// is faster than parsing on large rule sets
+func largeLogi() string {
+	var sb strings.Builder
+
+	for i := 0; i < 100; i++ {
+		sb.WriteString(strings.Replace(circuitLogi, "simple1", fmt.Sprintf("circuit%d", i), 1))
+	}
+
+	return sb.String()
+}
+
+func BenchmarkParse(b *testing.B) {
+	mAst, err := macro.ParseMacroContent(circuitMacro, false)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var content = largeLogi()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := logi.Parse(content, mAst.Macros, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	mAst, err := macro.ParseMacroContent(circuitMacro, false)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	lAst, err := logi.Parse(largeLogi(), mAst.Macros, false)
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	data := writeArtifact(b, Artifact{Macros: mAst.Macros, Definitions: lAst.Definitions})
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Read(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const cacheFileName = ".logi-cache.json"
+
+// cache keeps hashes of compiled outputs, it is stored in output directory
+type cache struct {
+	path    string
+	entries map[string]cacheEntry
+	mu      sync.Mutex
+}
+
+// cacheEntry describes the input an output is compiled from, together with the macros the input depends on,
+// so unchanged inputs can be skipped without parsing them
+type cacheEntry struct {
+	Hash      string   `json:"hash"`
+	Macros    []string `json:"macros,omitempty"`
+	MacroHash string   `json:"macroHash"`
+}
+
+func loadCache(path string) *cache {
+	var c = &cache{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return c
+	}
+
+	// broken cache is ignored, all files are recompiled
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+
+	return c
+}
+
+func (c *cache) get(output string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[output]
+
+	return entry, found
+}
+
+func (c *cache) set(output string, entry cacheEntry) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[output] = entry
+}
+
+func (c *cache) remove(output string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, output)
+}
+
+func (c *cache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing compile cache: %v", err)
+	}
+
+	return nil
+}
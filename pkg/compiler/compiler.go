@@ -0,0 +1,419 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/ast/plain"
+	"github.com/tislib/logi/pkg/encoder"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Kind string
+
+const (
+	// KindPlain compiles logi files to plain definitions, without macros
+	KindPlain Kind = "plain"
+	// KindNormal compiles logi files to definitions using macros
+	KindNormal Kind = "normal"
+	// KindValues compiles logi files to simplified values, keyed by definition name
+	KindValues Kind = "values"
+)
+
+type Options struct {
+	Kind   Kind
+	Format encoder.Format
+
+	// OutDir is the directory outputs are written to, mirroring input directory structure.
+	// If it is empty, outputs are not written.
+	OutDir string
+
+	// Bundle is the file all outputs are combined into, it is optional
+	Bundle string
+
+	// Parallelism is the number of files compiled at the same time, default is 1
+	Parallelism int
+
+	// Force disables skipping of unchanged files
+	Force bool
+}
+
+// Result is the compilation result of a single input
+type Result struct {
+	Input Input
+
+	// Output is the path output is written to, it is empty if output is not written
+	Output string
+
+	// Value is the compiled value, it is nil if the input is skipped without parsing
+	Value interface{}
+
+	// Macros are names of the macros which input depends on
+	Macros []string
+
+	// Skipped is true if the input is not changed since the last compilation
+	Skipped bool
+
+	// Removed is true if the input is removed, its output is deleted
+	Removed bool
+
+	Err error
+}
+
+type Compiler struct {
+	macros  *MacroSet
+	options Options
+	cache   *cache
+}
+
+func NewCompiler(macros *MacroSet, options Options) (*Compiler, error) {
+	if macros == nil {
+		macros = NewMacroSet()
+	}
+
+	if options.Kind == "" {
+		options.Kind = KindNormal
+	}
+
+	switch options.Kind {
+	case KindPlain, KindNormal, KindValues:
+	default:
+		return nil, fmt.Errorf("unknown kind: %s", options.Kind)
+	}
+
+	if options.Format.Encode == nil {
+		format, err := encoder.Get("json")
+
+		if err != nil {
+			return nil, err
+		}
+
+		options.Format = format
+	}
+
+	if options.Parallelism < 1 {
+		options.Parallelism = 1
+	}
+
+	c := &Compiler{
+		macros:  macros,
+		options: options,
+	}
+
+	if options.OutDir != "" {
+		c.cache = loadCache(filepath.Join(options.OutDir, cacheFileName))
+	}
+
+	return c, nil
+}
+
+// Compile compiles inputs in parallel, results are returned in the same order as inputs.
+// Errors of individual inputs are reported in their results, returned error is only about writing outputs.
+func (c *Compiler) Compile(inputs []Input) ([]Result, error) {
+	var results = make([]Result, len(inputs))
+	var jobs = make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < c.options.Parallelism; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				results[i] = c.CompileInput(inputs[i])
+			}
+		}()
+	}
+
+	for i := range inputs {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if c.options.Bundle != "" {
+		if err := c.writeBundle(results); err != nil {
+			return results, err
+		}
+	}
+
+	if c.cache != nil {
+		if err := c.cache.save(); err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// CompileInput compiles a single input and writes its output, it is skipped if neither input nor its macros are changed
+func (c *Compiler) CompileInput(input Input) Result {
+	var result = Result{Input: input}
+
+	content, err := input.read()
+
+	if err != nil {
+		result.Err = fmt.Errorf("error reading logi file: %v", err)
+		return result
+	}
+
+	if c.options.OutDir != "" {
+		result.Output = c.OutputPath(input)
+	}
+
+	// unchanged inputs are skipped before parsing, macros they depend on are known from the cache
+	var hash = c.hash(content)
+
+	if entry, ok := c.canSkip(result.Output, hash); ok {
+		result.Skipped = true
+		result.Macros = entry.Macros
+
+		if c.options.Bundle == "" {
+			return result
+		}
+	}
+
+	plainAst, err := logi.ParsePlainContent(string(content), true)
+
+	if err != nil {
+		result.Err = fmt.Errorf("error compiling logi file %s: %v", input.Path, err)
+		return result
+	}
+
+	if c.options.Kind != KindPlain {
+		result.Macros = usedMacros(plainAst.Definitions)
+	}
+
+	result.Value, err = compilePlainAst(*plainAst, c.options.Kind, c.macros.Macros)
+
+	if err != nil {
+		result.Err = fmt.Errorf("error compiling logi file %s: %v", input.Path, err)
+		return result
+	}
+
+	if result.Output != "" && !result.Skipped {
+		if err := c.write(result.Output, result.Value); err != nil {
+			result.Err = err
+			return result
+		}
+
+		c.cache.set(result.Output, cacheEntry{
+			Hash:      hash,
+			Macros:    result.Macros,
+			MacroHash: c.macros.Hash(result.Macros...),
+		})
+	}
+
+	return result
+}
+
+// Remove deletes the output of a removed input together with its cache entry
+func (c *Compiler) Remove(input Input) Result {
+	var result = Result{Input: input, Removed: true}
+
+	if c.options.OutDir == "" {
+		return result
+	}
+
+	result.Output = c.OutputPath(input)
+
+	if err := os.Remove(result.Output); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		result.Err = fmt.Errorf("error removing output of %s: %v", input.Path, err)
+		return result
+	}
+
+	if c.cache != nil {
+		c.cache.remove(result.Output)
+
+		if err := c.cache.save(); err != nil {
+			result.Err = err
+		}
+	}
+
+	return result
+}
+
+// OutputPath returns path of the output for the input, mirroring its path relative to the input base
+func (c *Compiler) OutputPath(input Input) string {
+	var rel = strings.TrimSuffix(input.RelativePath(), ".lg") + c.options.Format.Extension
+
+	return filepath.Join(c.options.OutDir, rel)
+}
+
+// Combine merges values of the results, lists are concatenated and values maps are merged
+func (c *Compiler) Combine(results []Result) interface{} {
+	if c.options.Kind == KindValues {
+		var combined = make(map[string]interface{})
+
+		for _, result := range results {
+			if values, ok := result.Value.(map[string]interface{}); ok {
+				for key, value := range values {
+					combined[key] = value
+				}
+			}
+		}
+
+		return combined
+	}
+
+	var combined = make([]interface{}, 0)
+
+	for _, result := range results {
+		if values, ok := result.Value.([]interface{}); ok {
+			combined = append(combined, values...)
+		}
+	}
+
+	return combined
+}
+
+// Encode encodes the value using the output format
+func (c *Compiler) Encode(value interface{}) ([]byte, error) {
+	data, err := c.options.Format.Encode(value)
+
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling definitions: %v", err)
+	}
+
+	return data, nil
+}
+
+// CompileContent compiles logi content into the value of the given kind,
+// plain and normal kinds are compiled into a list of definitions, values kind is compiled into a map keyed by definition name.
+func CompileContent(content string, kind Kind, macros []macroAst.Macro) (interface{}, error) {
+	plainAst, err := logi.ParsePlainContent(content, true)
+
+	if err != nil {
+		if kind == KindPlain {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("failed to parse logi: %w", err)
+	}
+
+	return compilePlainAst(*plainAst, kind, macros)
+}
+
+// compilePlainAst compiles already parsed content, so inputs are parsed once
+func compilePlainAst(plainAst plain.Ast, kind Kind, macros []macroAst.Macro) (interface{}, error) {
+	if kind == KindPlain {
+		var result = make([]interface{}, 0)
+
+		for _, definition := range plainAst.Definitions {
+			result = append(result, definition)
+		}
+
+		return result, nil
+	}
+
+	definitions, err := logi.ParsePlainAst(plainAst, macros)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return CompileDefinitions(definitions.Definitions, kind)
+}
+
+// CompileDefinitions compiles definitions, which are already matched with macros, into the value of normal or values kind
+func CompileDefinitions(definitions []logiAst.Definition, kind Kind) (interface{}, error) {
+	switch kind {
+	case KindNormal:
+		var result = make([]interface{}, 0)
+
+		for _, definition := range definitions {
+			definition.PlainStatements = nil
+			result = append(result, definition)
+		}
+
+		return result, nil
+	case KindValues:
+		var result = make(map[string]interface{})
+
+		for _, definition := range definitions {
+			result[definition.Name] = definition.Values()
+		}
+
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unknown kind: %s", kind)
+	}
+}
+
+// canSkip checks if the output is compiled from the same content and none of the macros it depends on are changed since then
+func (c *Compiler) canSkip(output string, hash string) (cacheEntry, bool) {
+	if c.options.Force || output == "" {
+		return cacheEntry{}, false
+	}
+
+	entry, found := c.cache.get(output)
+
+	if !found || entry.Hash != hash || entry.MacroHash != c.macros.Hash(entry.Macros...) {
+		return cacheEntry{}, false
+	}
+
+	_, err := os.Stat(output)
+
+	return entry, err == nil
+}
+
+func (c *Compiler) hash(content []byte) string {
+	return hashBytes([]byte(strings.Join([]string{
+		hashBytes(content),
+		string(c.options.Kind),
+		c.options.Format.Name,
+	}, ":")))
+}
+
+func (c *Compiler) write(path string, value interface{}) error {
+	data, err := c.Encode(value)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing definitions: %v", err)
+	}
+
+	return nil
+}
+
+func (c *Compiler) writeBundle(results []Result) error {
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("bundle is not written, %s has errors", result.Input.Path)
+		}
+	}
+
+	return c.write(c.options.Bundle, c.Combine(results))
+}
+
+func usedMacros(definitions []plain.Definition) []string {
+	var seen = make(map[string]bool)
+	var result []string
+
+	for _, definition := range definitions {
+		if !seen[definition.MacroName] {
+			seen[definition.MacroName] = true
+			result = append(result, definition.MacroName)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,253 @@
+package compiler
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/encoder"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const creditRuleMacro = `
+macro creditRule {
+    kind Syntax
+
+    syntax {
+        creditScore <min int> <max int>
+        age <min int> <max int>
+    }
+}
+`
+
+const creditRuleMacroV2 = `
+macro creditRule {
+    kind Syntax
+
+    syntax {
+        creditScore <min int> <max int>
+        age <min int> <max int>
+        income <min int> <max int>
+    }
+}
+`
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		var path = filepath.Join(dir, name)
+
+		if !assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755)) {
+			t.FailNow()
+		}
+
+		if !assert.NoError(t, os.WriteFile(path, []byte(content), 0644)) {
+			t.FailNow()
+		}
+	}
+}
+
+func compileDir(t *testing.T, dir string, options Options, patterns ...string) []Result {
+	macroSet, err := LoadMacroDir(filepath.Join(dir, "macros"))
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	c, err := NewCompiler(macroSet, options)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for i := range patterns {
+		patterns[i] = filepath.Join(dir, patterns[i])
+	}
+
+	inputs, err := ResolveInputs(patterns, strings.NewReader(""))
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	results, err := c.Compile(inputs)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+
+	return results
+}
+
+func skipped(results []Result) map[string]bool {
+	var result = make(map[string]bool)
+
+	for _, item := range results {
+		result[item.Input.RelativePath()] = item.Skipped
+	}
+
+	return result
+}
+
+func TestCompile(t *testing.T) {
+	var dir = t.TempDir()
+	var outDir = filepath.Join(dir, "out")
+
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm":     creditRuleMacro,
+		"src/rule1.lg":               "creditRule Rule1 {\n    age 18 65\n}\n",
+		"src/nested/rule2.lg":        "creditRule Rule2 {\n    creditScore 500 600\n}\n",
+		"src/nested/deeper/rule3.lg": "creditRule Rule3 {\n    age 21 30\n}\n",
+		"src/readme.md":              "not a logi file",
+	})
+
+	format, _ := encoder.Get("json")
+	var options = Options{Kind: KindNormal, Format: format, OutDir: outDir, Parallelism: 2}
+
+	// mirrored output
+	results := compileDir(t, dir, options, "src")
+
+	assert.Len(t, results, 3)
+	assert.Equal(t, map[string]bool{"rule1.lg": false, "nested/rule2.lg": false, "nested/deeper/rule3.lg": false}, skipped(results))
+
+	for _, name := range []string{"rule1.json", "nested/rule2.json", "nested/deeper/rule3.json"} {
+		assert.FileExists(t, filepath.Join(outDir, name))
+	}
+
+	assert.Equal(t, []string{"creditRule"}, results[0].Macros)
+
+	// unchanged files are skipped
+	results = compileDir(t, dir, options, "src")
+
+	assert.Equal(t, map[string]bool{"rule1.lg": true, "nested/rule2.lg": true, "nested/deeper/rule3.lg": true}, skipped(results))
+	assert.Nil(t, results[0].Value)
+	assert.Equal(t, []string{"creditRule"}, results[0].Macros)
+
+	// changed file is recompiled
+	writeFiles(t, dir, map[string]string{
+		"src/rule1.lg": "creditRule Rule1 {\n    age 18 70\n}\n",
+	})
+
+	results = compileDir(t, dir, options, "src")
+
+	assert.Equal(t, map[string]bool{"rule1.lg": false, "nested/rule2.lg": true, "nested/deeper/rule3.lg": true}, skipped(results))
+
+	// changed macro recompiles dependent files
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm": creditRuleMacroV2,
+	})
+
+	results = compileDir(t, dir, options, "src")
+
+	assert.Equal(t, map[string]bool{"rule1.lg": false, "nested/rule2.lg": false, "nested/deeper/rule3.lg": false}, skipped(results))
+
+	// force recompiles unchanged files
+	options.Force = true
+	results = compileDir(t, dir, options, "src")
+
+	assert.Equal(t, map[string]bool{"rule1.lg": false, "nested/rule2.lg": false, "nested/deeper/rule3.lg": false}, skipped(results))
+}
+
+func TestCompileGlobAndBundle(t *testing.T) {
+	var dir = t.TempDir()
+	var outDir = filepath.Join(dir, "out")
+	var bundle = filepath.Join(dir, "bundle.json")
+
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm": creditRuleMacro,
+		"src/a/rule1.lg":         "creditRule Rule1 {\n    age 18 65\n}\n",
+		"src/b/rule2.lg":         "creditRule Rule2 {\n    creditScore 500 600\n}\n",
+		"src/b/other.txt":        "not a logi file",
+	})
+
+	format, _ := encoder.Get("json")
+
+	for i := 0; i < 2; i++ {
+		results := compileDir(t, dir, Options{Kind: KindValues, Format: format, OutDir: outDir, Bundle: bundle}, "src/*/*.lg")
+
+		assert.Len(t, results, 2)
+		assert.FileExists(t, filepath.Join(outDir, "a/rule1.json"))
+		assert.FileExists(t, filepath.Join(outDir, "b/rule2.json"))
+
+		data, err := os.ReadFile(bundle)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var values map[string]interface{}
+
+		assert.NoError(t, json.Unmarshal(data, &values))
+		assert.Contains(t, values, "Rule1")
+		assert.Contains(t, values, "Rule2")
+	}
+}
+
+func TestResolveInputs(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"a.lg":       "",
+		"sub/b.lg":   "",
+		"sub/c.lgm":  "",
+		"sub/x/d.lg": "",
+	})
+
+	tests := map[string]struct {
+		patterns []string
+		expected []string
+	}{
+		"file": {
+			patterns: []string{"a.lg"},
+			expected: []string{"a.lg"},
+		},
+		"directory": {
+			patterns: []string{"sub"},
+			expected: []string{"b.lg", "x/d.lg"},
+		},
+		"glob": {
+			patterns: []string{"*/*.lg"},
+			expected: []string{"sub/b.lg"},
+		},
+		"duplicates": {
+			patterns: []string{"a.lg", "*.lg"},
+			expected: []string{"a.lg"},
+		},
+		"stdin": {
+			patterns: []string{"-"},
+			expected: []string{"stdin.lg"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var patterns []string
+
+			for _, pattern := range tt.patterns {
+				if pattern == StdinInput {
+					patterns = append(patterns, pattern)
+				} else {
+					patterns = append(patterns, filepath.Join(dir, pattern))
+				}
+			}
+
+			inputs, err := ResolveInputs(patterns, strings.NewReader("creditRule Rule1 {}"))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var relativePaths []string
+
+			for _, input := range inputs {
+				relativePaths = append(relativePaths, input.RelativePath())
+			}
+
+			assert.Equal(t, tt.expected, relativePaths)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StdinInput is the input name which reads logi content from stdin
+const StdinInput = "-"
+
+// Input is a logi file to compile
+type Input struct {
+	// Path of the file, or StdinInput
+	Path string
+
+	// Base is the directory which output paths are mirrored relative to
+	Base string
+
+	// Content of the input, it is only set for stdin
+	Content []byte
+}
+
+// RelativePath returns path of the input relative to its base, it is used to mirror directory structure in output
+func (i Input) RelativePath() string {
+	if i.Path == StdinInput {
+		return "stdin.lg"
+	}
+
+	rel, err := filepath.Rel(i.Base, i.Path)
+
+	if err != nil {
+		return filepath.Base(i.Path)
+	}
+
+	return rel
+}
+
+func (i Input) read() ([]byte, error) {
+	if i.Path == StdinInput {
+		return i.Content, nil
+	}
+
+	return os.ReadFile(i.Path)
+}
+
+// ResolveInputs expands files, directories and glob patterns to logi files.
+// Directories are walked recursively for *.lg files. StdinInput reads content from the given reader.
+func ResolveInputs(patterns []string, stdin io.Reader) ([]Input, error) {
+	var result []Input
+	var seen = make(map[string]bool)
+
+	var add = func(input Input) {
+		if seen[input.Path] {
+			return
+		}
+
+		seen[input.Path] = true
+		result = append(result, input)
+	}
+
+	for _, pattern := range patterns {
+		if pattern == StdinInput {
+			content, err := io.ReadAll(stdin)
+
+			if err != nil {
+				return nil, fmt.Errorf("error reading stdin: %v", err)
+			}
+
+			add(Input{Path: StdinInput, Content: content})
+			continue
+		}
+
+		var base = filepath.Dir(pattern)
+		var matches = []string{pattern}
+
+		if isGlob(pattern) {
+			var err error
+
+			base = globBase(pattern)
+			matches, err = filepath.Glob(pattern)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %v", pattern, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match pattern %s", pattern)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+
+			if err != nil {
+				return nil, fmt.Errorf("error reading input: %v", err)
+			}
+
+			if !info.IsDir() {
+				add(Input{Path: match, Base: base})
+				continue
+			}
+
+			var dirBase = match
+
+			if isGlob(pattern) {
+				dirBase = base
+			}
+
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if !d.IsDir() && strings.HasSuffix(path, ".lg") {
+					add(Input{Path: path, Base: dirBase})
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return nil, fmt.Errorf("error reading input directory: %v", err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globBase returns the longest directory prefix of the pattern without glob characters
+func globBase(pattern string) string {
+	var dir = filepath.Dir(pattern)
+
+	for isGlob(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	return dir
+}
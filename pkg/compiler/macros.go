@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MacroSet contains macros loaded from macro files, together with hashes of the files they are defined in,
+// so compiled outputs can be invalidated when a macro they depend on changes.
+type MacroSet struct {
+	Macros []macroAst.Macro
+
+	// Files maps macro names to the macro files they are defined in
+	Files map[string]string
+
+	hashes map[string]string
+}
+
+func NewMacroSet() *MacroSet {
+	return &MacroSet{
+		Files:  make(map[string]string),
+		hashes: make(map[string]string),
+	}
+}
+
+// LoadMacroDir parses all macro files (*.lgm) in the given directory
+func LoadMacroDir(dir string) (*MacroSet, error) {
+	var result = NewMacroSet()
+
+	// list all files in macro dir
+	macroDir, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading macro dir: %v", err)
+	}
+
+	// for each file in macro dir
+	for _, file := range macroDir {
+		// check extension
+		if strings.HasSuffix(file.Name(), ".lgm") == false {
+			continue
+		}
+
+		var filePath = filepath.Join(dir, file.Name())
+
+		fileContent, err := os.ReadFile(filePath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading macro file: %v", err)
+		}
+
+		if err := result.Add(filePath, fileContent); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Add parses the macro content and adds its macros to the set
+func (s *MacroSet) Add(path string, content []byte) error {
+	ast, err := macro.ParseMacroContent(string(content), true)
+
+	if err != nil {
+		return fmt.Errorf("failed to load macro file %s: %w", path, err)
+	}
+
+	for _, item := range ast.Macros {
+		// macros are hashed without source map, so edits in other macros of the same file do not change their hashes
+		var hashed = item
+		hashed.SourceMap = nil
+
+		data, err := json.Marshal(hashed)
+
+		if err != nil {
+			return fmt.Errorf("failed to hash macro %s: %w", item.Name, err)
+		}
+
+		s.Macros = append(s.Macros, item)
+		s.Files[item.Name] = path
+		s.hashes[item.Name] = hashBytes(data)
+	}
+
+	return nil
+}
+
+// Changed returns names of the macros which are added, removed or changed in the other set
+func (s *MacroSet) Changed(other *MacroSet) []string {
+	var result []string
+
+	for name, hash := range s.hashes {
+		if other.hashes[name] != hash {
+			result = append(result, name)
+		}
+	}
+
+	for name := range other.hashes {
+		if _, found := s.hashes[name]; !found {
+			result = append(result, name)
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// Hash returns combined hash of the given macros, unknown macros are part of the hash as well
+func (s *MacroSet) Hash(names ...string) string {
+	var sorted = append([]string{}, names...)
+	sort.Strings(sorted)
+
+	var h = sha256.New()
+
+	for _, name := range sorted {
+		h.Write([]byte(name + ":" + s.hashes[name] + "\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashBytes(data []byte) string {
+	var sum = sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
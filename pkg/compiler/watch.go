@@ -0,0 +1,259 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"github.com/tislib/logi/pkg/watcher"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// debounceInterval is the time to wait for more changes before recompiling, editors usually write files in multiple steps
+const debounceInterval = 100 * time.Millisecond
+
+// Watch keeps compiled outputs of the inputs up to date.
+// When a macro file changes, only inputs using changed macros are recompiled, when a logi file changes only that file is recompiled.
+type Watch struct {
+	macroDir string
+	patterns []string
+	compiler *Compiler
+	macros   *MacroSet
+	inputs   map[string]Input
+	deps     map[string][]string
+}
+
+func NewWatch(macroDir string, patterns []string, options Options) (*Watch, error) {
+	for _, pattern := range patterns {
+		if pattern == StdinInput {
+			return nil, fmt.Errorf("stdin can not be watched")
+		}
+	}
+
+	// bundle needs all results, it is not supported in incremental compilation
+	options.Bundle = ""
+
+	var macros = NewMacroSet()
+
+	if options.Kind != KindPlain {
+		var err error
+		macros, err = LoadMacroDir(macroDir)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := NewCompiler(macros, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watch{
+		macroDir: macroDir,
+		patterns: patterns,
+		compiler: c,
+		macros:   macros,
+		inputs:   make(map[string]Input),
+		deps:     make(map[string][]string),
+	}, nil
+}
+
+// Dirs returns directories which need to be watched for the inputs and macros
+func (w *Watch) Dirs() []string {
+	var seen = make(map[string]bool)
+	var result []string
+
+	var add = func(dir string) {
+		dir = filepath.Clean(dir)
+
+		if !seen[dir] {
+			seen[dir] = true
+			result = append(result, dir)
+		}
+	}
+
+	if w.compiler.options.Kind != KindPlain {
+		add(w.macroDir)
+	}
+
+	for _, pattern := range w.patterns {
+		if isGlob(pattern) {
+			add(globBase(pattern))
+		} else if strings.HasSuffix(pattern, ".lg") {
+			add(filepath.Dir(pattern))
+		} else {
+			add(pattern)
+		}
+	}
+
+	return result
+}
+
+// Start compiles all inputs
+func (w *Watch) Start() ([]Result, error) {
+	inputs, err := ResolveInputs(w.patterns, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, input := range inputs {
+		w.inputs[filepath.Clean(input.Path)] = input
+	}
+
+	return w.compile(inputs)
+}
+
+// Run recompiles changed inputs until context is done, results are reported to the handler.
+// Watcher errors are reported to the handler as well, watching continues with the next events until the watcher stops.
+func (w *Watch) Run(ctx context.Context, fileWatcher watcher.FileWatcher, handler func(Result)) error {
+	var lastErr error
+	var changed = make(map[string]bool)
+	var timer = time.NewTimer(debounceInterval)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-fileWatcher.Errors():
+			lastErr = err
+			handler(Result{Err: fmt.Errorf("error watching files: %v", err)})
+		case path, ok := <-fileWatcher.Events():
+			if !ok && lastErr != nil {
+				return fmt.Errorf("file watcher stopped: %v", lastErr)
+			} else if !ok {
+				return fmt.Errorf("file watcher stopped")
+			}
+
+			if strings.HasSuffix(path, ".lg") || strings.HasSuffix(path, ".lgm") {
+				changed[filepath.Clean(path)] = true
+				timer.Reset(debounceInterval)
+			}
+		case <-timer.C:
+			var paths []string
+
+			for path := range changed {
+				paths = append(paths, path)
+			}
+
+			changed = make(map[string]bool)
+
+			for _, result := range w.Apply(paths) {
+				handler(result)
+			}
+		}
+	}
+}
+
+// Apply recompiles inputs affected by changes of the given paths
+func (w *Watch) Apply(paths []string) []Result {
+	var affected = make(map[string]bool)
+	var results []Result
+	var macrosChanged = false
+
+	for _, path := range paths {
+		path = filepath.Clean(path)
+
+		if strings.HasSuffix(path, ".lgm") {
+			macrosChanged = true
+		} else {
+			affected[path] = true
+		}
+	}
+
+	if macrosChanged && w.compiler.options.Kind != KindPlain {
+		macros, err := LoadMacroDir(w.macroDir)
+
+		if err != nil {
+			// last valid macros are kept until macro files are fixed
+			results = append(results, Result{Input: Input{Path: w.macroDir}, Err: err})
+		} else {
+			var changedMacros = make(map[string]bool)
+
+			for _, name := range w.macros.Changed(macros) {
+				changedMacros[name] = true
+			}
+
+			w.macros = macros
+			w.compiler.macros = macros
+
+			for path, deps := range w.deps {
+				for _, name := range deps {
+					if changedMacros[name] {
+						affected[path] = true
+					}
+				}
+			}
+		}
+	}
+
+	if len(affected) == 0 {
+		return results
+	}
+
+	// inputs are resolved again, to pick up created and removed files
+	inputs, err := ResolveInputs(w.patterns, nil)
+
+	if err != nil {
+		return append(results, Result{Err: err})
+	}
+
+	var current = make(map[string]Input)
+
+	for _, input := range inputs {
+		current[filepath.Clean(input.Path)] = input
+	}
+
+	var removed []string
+
+	for path := range w.inputs {
+		if _, found := current[path]; !found {
+			removed = append(removed, path)
+		}
+	}
+
+	sort.Strings(removed)
+
+	for _, path := range removed {
+		results = append(results, w.compiler.Remove(w.inputs[path]))
+		delete(w.deps, path)
+	}
+
+	w.inputs = current
+
+	var toCompile []Input
+
+	for path := range affected {
+		if input, found := current[path]; found {
+			toCompile = append(toCompile, input)
+		}
+	}
+
+	sort.Slice(toCompile, func(i, j int) bool {
+		return toCompile[i].Path < toCompile[j].Path
+	})
+
+	compiled, err := w.compile(toCompile)
+
+	if err != nil {
+		results = append(results, Result{Err: err})
+	}
+
+	return append(results, compiled...)
+}
+
+func (w *Watch) compile(inputs []Input) ([]Result, error) {
+	results, err := w.compiler.Compile(inputs)
+
+	for _, result := range results {
+		if result.Macros != nil || result.Err == nil {
+			w.deps[filepath.Clean(result.Input.Path)] = result.Macros
+		}
+	}
+
+	return results, err
+}
@@ -0,0 +1,305 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/watcher"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const roleMacro = `
+macro role {
+    kind Syntax
+
+    syntax {
+        description <description string>
+    }
+}
+`
+
+const roleMacroV2 = `
+macro role {
+    kind Syntax
+
+    syntax {
+        description <description string>
+        level <level int>
+    }
+}
+`
+
+func compiledPaths(results []Result) []string {
+	var paths []string
+
+	for _, result := range results {
+		paths = append(paths, filepath.Base(result.Input.Path))
+	}
+
+	return paths
+}
+
+func TestWatchApply(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm": creditRuleMacro,
+		"macros/role.lgm":        roleMacro,
+		"src/rule.lg":            "creditRule Rule1 {\n    age 18 65\n}\n",
+		"src/role.lg":            "role Admin {\n    description \"admin\"\n}\n",
+	})
+
+	w, err := NewWatch(filepath.Join(dir, "macros"), []string{filepath.Join(dir, "src")}, Options{OutDir: filepath.Join(dir, "out")})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	results, err := w.Start()
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.ElementsMatch(t, []string{"rule.lg", "role.lg"}, compiledPaths(results))
+	assert.ElementsMatch(t, []string{filepath.Join(dir, "macros"), filepath.Join(dir, "src")}, w.Dirs())
+
+	// only files using changed macro are recompiled
+	writeFiles(t, dir, map[string]string{
+		"macros/role.lgm": roleMacroV2,
+		"src/role.lg":     "role Admin {\n    description \"admin\"\n    level 3\n}\n",
+	})
+
+	results = w.Apply([]string{filepath.Join(dir, "macros/role.lgm")})
+
+	assert.Equal(t, []string{"role.lg"}, compiledPaths(results))
+	assert.NoError(t, results[0].Err)
+
+	// only changed logi file is recompiled
+	writeFiles(t, dir, map[string]string{
+		"src/rule.lg": "creditRule Rule1 {\n    age 18 unknown\n}\n",
+	})
+
+	results = w.Apply([]string{filepath.Join(dir, "src/rule.lg")})
+
+	assert.Equal(t, []string{"rule.lg"}, compiledPaths(results))
+	assert.Error(t, results[0].Err)
+
+	// broken macro files keep last valid macros
+	writeFiles(t, dir, map[string]string{
+		"macros/role.lgm": "macro role {",
+	})
+
+	results = w.Apply([]string{filepath.Join(dir, "macros/role.lgm")})
+
+	assert.Len(t, results, 1)
+	assert.Error(t, results[0].Err)
+
+	// created files are picked up
+	writeFiles(t, dir, map[string]string{
+		"src/nested/role2.lg": "role User {\n    level 1\n}\n",
+	})
+
+	results = w.Apply([]string{filepath.Join(dir, "src/nested/role2.lg")})
+
+	assert.Equal(t, []string{"role2.lg"}, compiledPaths(results))
+	assert.NoError(t, results[0].Err)
+
+	// removed files have their outputs and cache entries deleted
+	var output = filepath.Join(dir, "out/role.json")
+
+	assert.FileExists(t, output)
+	assert.NoError(t, os.Remove(filepath.Join(dir, "src/role.lg")))
+
+	results = w.Apply([]string{filepath.Join(dir, "src/role.lg")})
+
+	if assert.Len(t, results, 1) {
+		assert.True(t, results[0].Removed)
+		assert.Equal(t, output, results[0].Output)
+		assert.NoError(t, results[0].Err)
+	}
+
+	assert.NoFileExists(t, output)
+
+	cacheData, err := os.ReadFile(filepath.Join(dir, "out", cacheFileName))
+
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(cacheData), output)
+	}
+}
+
+type testWatcher struct {
+	events chan string
+	errors chan error
+}
+
+func (w *testWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *testWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *testWatcher) Close() error {
+	return nil
+}
+
+func TestWatchRunErrors(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm": creditRuleMacro,
+		"src/rule.lg":            "creditRule Rule1 {\n    age 18 65\n}\n",
+	})
+
+	w, err := NewWatch(filepath.Join(dir, "macros"), []string{filepath.Join(dir, "src")}, Options{})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = w.Start()
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var watcher = &testWatcher{events: make(chan string), errors: make(chan error)}
+	var results = make(chan Result, 10)
+	var done = make(chan error)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		done <- w.Run(ctx, watcher, func(result Result) {
+			results <- result
+		})
+	}()
+
+	// errors are reported and watching continues
+	watcher.errors <- fmt.Errorf("scan failed")
+
+	result := <-results
+	assert.EqualError(t, result.Err, "error watching files: scan failed")
+
+	watcher.events <- filepath.Join(dir, "src/rule.lg")
+
+	select {
+	case result = <-results:
+		assert.Equal(t, "rule.lg", filepath.Base(result.Input.Path))
+		assert.NoError(t, result.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change is not compiled after error")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestFileWatcher(t *testing.T) {
+	tests := map[string]struct {
+		create func(dirs []string) (watcher.FileWatcher, error)
+	}{
+		"default": {
+			create: func(dirs []string) (watcher.FileWatcher, error) {
+				return watcher.NewFileWatcher(dirs, 10*time.Millisecond)
+			},
+		},
+		"poll": {
+			create: func(dirs []string) (watcher.FileWatcher, error) {
+				return watcher.NewPollWatcher(dirs, 10*time.Millisecond)
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var dir = t.TempDir()
+
+			writeFiles(t, dir, map[string]string{
+				"macros/credit-rule.lgm": creditRuleMacro,
+				"src/rule.lg":            "creditRule Rule1 {\n    age 18 65\n}\n",
+			})
+
+			w, err := NewWatch(filepath.Join(dir, "macros"), []string{filepath.Join(dir, "src")}, Options{})
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			_, err = w.Start()
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			watcher, err := tt.create(w.Dirs())
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			defer watcher.Close()
+
+			var compiled = make(chan Result, 10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go func() {
+				_ = w.Run(ctx, watcher, func(result Result) {
+					compiled <- result
+				})
+			}()
+
+			// poll watcher detects changes by modification time
+			time.Sleep(20 * time.Millisecond)
+
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "src/rule.lg"), []byte("creditRule Rule1 {\n    age 21 65\n}\n"), 0644))
+
+			select {
+			case result := <-compiled:
+				assert.Equal(t, "rule.lg", filepath.Base(result.Input.Path))
+				assert.NoError(t, result.Err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("change is not detected")
+			}
+		})
+	}
+}
+
+func TestWatchRunStopsWithWatcher(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"macros/credit-rule.lgm": creditRuleMacro,
+		"src/rule.lg":            "creditRule Rule1 {\n    age 18 65\n}\n",
+	})
+
+	w, err := NewWatch(filepath.Join(dir, "macros"), []string{filepath.Join(dir, "src")}, Options{})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var fileWatcher = &testWatcher{events: make(chan string), errors: make(chan error)}
+	var done = make(chan error)
+
+	go func() {
+		done <- w.Run(context.Background(), fileWatcher, func(result Result) {})
+	}()
+
+	// watcher stops after reading fails
+	fileWatcher.errors <- fmt.Errorf("read failed")
+	close(fileWatcher.events)
+
+	select {
+	case err := <-done:
+		assert.EqualError(t, err, "file watcher stopped: read failed")
+	case <-time.After(5 * time.Second):
+		t.Fatal("run is not stopped with the watcher")
+	}
+}
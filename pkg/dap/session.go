@@ -0,0 +1,728 @@
+package dap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/implementer"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DefaultImplementer prints statements with their parameters to the debug console, and executes their sub statements
+const DefaultImplementer = "print"
+
+// threadId is the id of the only thread, the execution of the launched definition
+const threadId = 1
+
+// Options of debug sessions
+type Options struct {
+	// Implementers which can be chosen by launch requests, by name, in addition to the registered implementers.
+	// They write to the debug console with output.
+	Implementers map[string]func(output io.Writer) vm.Implementer
+}
+
+// Session is a debug session of a client, e.g. VS Code. The client launches a definition of a logi file, which is
+// executed with an implementer once the client is done with configuration.
+type Session struct {
+	stream  jsonrpc2.ObjectStream
+	options Options
+
+	vm         vm.VirtualMachine
+	debugger   *vm.Debugger
+	launch     *LaunchArguments
+	definition *logiAst.Definition
+	lines      map[int]bool
+
+	// lines of breakpoints by source path, they are kept until the program is launched
+	breakpoints map[string][]int
+
+	configured bool
+	started    bool
+	entry      bool
+	stop       *vm.Stop
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+
+	seq     int
+	writeMu sync.Mutex
+}
+
+func NewSession(stream jsonrpc2.ObjectStream, options Options) *Session {
+	return &Session{
+		stream:      stream,
+		options:     options,
+		breakpoints: make(map[string][]int),
+	}
+}
+
+// Serve handles requests until the client disconnects or the stream is closed, the execution is stopped after it
+func (s *Session) Serve() error {
+	defer s.terminate()
+
+	for {
+		var request Request
+
+		if err := s.stream.ReadObject(&request); errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+
+		body, err := s.handle(request)
+
+		s.respond(request, body, err)
+
+		if err != nil {
+			continue
+		}
+
+		switch request.Command {
+		case "launch":
+			// breakpoints are set after launch, so they can be verified against the program
+			s.event("initialized", nil)
+			s.startIfReady()
+		case "configurationDone":
+			s.startIfReady()
+		// execution is resumed after the response, so the client receives the response before the next stopped event
+		case "continue":
+			s.resume((*vm.Debugger).Continue)
+		case "next":
+			s.resume((*vm.Debugger).StepOver)
+		case "stepIn":
+			s.resume((*vm.Debugger).StepIn)
+		case "stepOut":
+			s.resume((*vm.Debugger).StepOut)
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (s *Session) handle(request Request) (any, error) {
+	switch request.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true, SupportsTerminateRequest: true}, nil
+	case "launch":
+		var args LaunchArguments
+
+		if err := unmarshalArguments(request, &args); err != nil {
+			return nil, err
+		}
+
+		return nil, s.load(args)
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+
+		if err := unmarshalArguments(request, &args); err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"breakpoints": s.setBreakpoints(args)}, nil
+	case "configurationDone":
+		s.mu.Lock()
+		s.configured = true
+		s.mu.Unlock()
+
+		return nil, nil
+	case "threads":
+		return map[string]any{"threads": []Thread{{Id: threadId, Name: s.threadName()}}}, nil
+	case "stackTrace":
+		frames, err := s.stackTrace()
+
+		return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}, err
+	case "scopes":
+		var args ScopesArguments
+
+		if err := unmarshalArguments(request, &args); err != nil {
+			return nil, err
+		}
+
+		scopes, err := s.scopes(args.FrameId)
+
+		return map[string]any{"scopes": scopes}, err
+	case "variables":
+		var args VariablesArguments
+
+		if err := unmarshalArguments(request, &args); err != nil {
+			return nil, err
+		}
+
+		variables, err := s.variables(args.VariablesReference)
+
+		return map[string]any{"variables": variables}, err
+	case "continue":
+		_, err := s.paused()
+
+		return map[string]any{"allThreadsContinued": true}, err
+	case "next", "stepIn", "stepOut":
+		_, err := s.paused()
+
+		return nil, err
+	case "pause":
+		if s.debugger == nil {
+			return nil, fmt.Errorf("program is not launched")
+		}
+
+		s.debugger.Pause()
+
+		return nil, nil
+	case "terminate", "disconnect":
+		s.terminate()
+
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported command: %s", request.Command)
+	}
+}
+
+func unmarshalArguments(request Request, args any) error {
+	if len(request.Arguments) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(request.Arguments, args); err != nil {
+		return fmt.Errorf("invalid arguments of %s: %v", request.Command, err)
+	}
+
+	return nil
+}
+
+// load loads macros and the program into a new virtual machine
+func (s *Session) load(args LaunchArguments) error {
+	if args.Program == "" {
+		return fmt.Errorf("program is required")
+	}
+
+	var macros = args.Macros
+
+	if len(macros) == 0 {
+		macros = []string{filepath.Dir(args.Program)}
+	}
+
+	files, err := macroFiles(macros)
+
+	if err != nil {
+		return err
+	}
+
+	var implementerName = args.Implementer
+
+	if implementerName == "" {
+		implementerName = DefaultImplementer
+	}
+
+	if _, err := s.implementer(implementerName); err != nil {
+		return err
+	}
+
+	v := vm.New()
+	v.(vm.Inspector).EnableSourceMap(true)
+
+	if err := v.LoadMacroFile(files...); err != nil {
+		return err
+	}
+
+	definitions, err := v.LoadLogiFile(args.Program)
+
+	if err != nil {
+		return err
+	}
+
+	var definition *logiAst.Definition
+
+	for i := range definitions {
+		if args.Definition == "" || definitions[i].Name == args.Definition {
+			definition = &definitions[i]
+
+			break
+		}
+	}
+
+	if definition == nil {
+		return fmt.Errorf("definition %q not found in %s", args.Definition, args.Program)
+	}
+
+	lines, err := statementLines(v, definition)
+
+	if err != nil {
+		return err
+	}
+
+	args.Implementer = implementerName
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.launch != nil {
+		return fmt.Errorf("program is already launched")
+	}
+
+	s.vm = v
+	s.launch = &args
+	s.definition = definition
+	s.lines = lines
+	s.debugger = vm.NewDebugger(s.stopped)
+
+	if !args.NoDebug {
+		v.(vm.Inspector).SetTracer(s.debugger.Trace)
+	}
+
+	if args.StopOnEntry {
+		s.entry = true
+		s.debugger.Pause()
+	}
+
+	return nil
+}
+
+func (s *Session) implementer(name string) (func(output io.Writer) vm.Implementer, error) {
+	if implementer, found := s.options.Implementers[name]; found {
+		return implementer, nil
+	}
+
+	factory, err := implementer.Get(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return factory, nil
+}
+
+// macroFiles returns the macro files of the paths, directories are replaced with the macro files in them
+func macroFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading macros: %v", err)
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.lgm"))
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading macros: %v", err)
+		}
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// statementLines returns the lines which have a statement of the definition, breakpoints can only be set on them
+func statementLines(v vm.VirtualMachine, definition *logiAst.Definition) (map[int]bool, error) {
+	matches, err := v.(vm.Inspector).Query(fmt.Sprintf("%s[%s]/**/*", definition.MacroName, definition.Name))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lines = make(map[int]bool)
+
+	for _, match := range matches {
+		if match.Location != nil {
+			lines[match.Location.Line] = true
+		}
+	}
+
+	return lines, nil
+}
+
+func (s *Session) setBreakpoints(args SetBreakpointsArguments) []Breakpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []int
+
+	for _, breakpoint := range args.Breakpoints {
+		lines = append(lines, breakpoint.Line)
+	}
+
+	s.breakpoints[args.Source.Path] = lines
+
+	var result []Breakpoint
+	var loaded = s.launch != nil && samePath(args.Source.Path, s.launch.Program)
+
+	for _, line := range lines {
+		var breakpoint = Breakpoint{Line: line, Source: &args.Source}
+
+		if !loaded {
+			breakpoint.Message = "source is not the launched program"
+		} else if !s.lines[line] {
+			breakpoint.Message = "there is no statement on the line"
+		} else {
+			breakpoint.Verified = true
+		}
+
+		result = append(result, breakpoint)
+	}
+
+	s.updateBreakpoints()
+
+	return result
+}
+
+// updateBreakpoints sets breakpoints of the launched program to the debugger
+func (s *Session) updateBreakpoints() {
+	if s.launch == nil {
+		return
+	}
+
+	var breakpoints []vm.Breakpoint
+
+	for path, lines := range s.breakpoints {
+		if !samePath(path, s.launch.Program) {
+			continue
+		}
+
+		for _, line := range lines {
+			breakpoints = append(breakpoints, vm.Breakpoint{Definition: s.definition.Name, Line: line})
+		}
+	}
+
+	s.debugger.SetBreakpoints(breakpoints...)
+}
+
+func samePath(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+
+	return errA == nil && errB == nil && absA == absB
+}
+
+// startIfReady starts the execution, once the program is launched and configuration is done
+func (s *Session) startIfReady() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.launch == nil || !s.configured || s.started {
+		return
+	}
+
+	s.started = true
+	s.updateBreakpoints()
+
+	implementer, _ := s.implementer(s.launch.Implementer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	go s.run(ctx, implementer(outputWriter{session: s, category: "stdout"}))
+}
+
+func (s *Session) run(ctx context.Context, implementer vm.Implementer) {
+	var exitCode = 0
+
+	if err := s.vm.(vm.Executor).ExecuteContext(ctx, s.definition, implementer, vm.Limits{}); err != nil {
+		s.event("output", OutputEvent{Category: "stderr", Output: err.Error() + "\n"})
+		exitCode = 1
+	}
+
+	s.event("exited", ExitedEvent{ExitCode: exitCode})
+	s.event("terminated", nil)
+}
+
+// terminate stops the execution, if it is paused it is resumed to stop
+func (s *Session) terminate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	if s.debugger != nil {
+		s.debugger.SetBreakpoints()
+		_ = s.debugger.Continue()
+	}
+
+	s.stop = nil
+}
+
+// stopped is called by the debugger when the execution is paused
+func (s *Session) stopped(stop vm.Stop) {
+	s.mu.Lock()
+	s.stop = &stop
+
+	var reason = string(stop.Reason)
+
+	if s.entry {
+		reason = "entry"
+		s.entry = false
+	}
+
+	s.mu.Unlock()
+
+	s.event("stopped", StoppedEvent{Reason: reason, ThreadId: threadId, AllThreadsStopped: true})
+}
+
+func (s *Session) resume(fn func(debugger *vm.Debugger) error) {
+	s.mu.Lock()
+	var debugger = s.debugger
+	s.stop = nil
+	s.mu.Unlock()
+
+	if debugger != nil {
+		if err := fn(debugger); err != nil {
+			log.Warnf("failed to resume execution: %v", err)
+		}
+	}
+}
+
+func (s *Session) threadName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.definition == nil {
+		return "main"
+	}
+
+	return s.definition.Name
+}
+
+// paused returns the stop of the paused execution
+func (s *Session) paused() (*vm.Stop, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop == nil {
+		return nil, vm.ErrNotPaused
+	}
+
+	return s.stop, nil
+}
+
+// stackTrace returns a frame for the paused statement, and for each of its parents, ids of frames start from 1
+func (s *Session) stackTrace() ([]StackFrame, error) {
+	stop, err := s.paused()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []StackFrame
+
+	for id := 1; ; id++ {
+		path, found := framePath(stop.Event.Path, id)
+
+		if !found {
+			break
+		}
+
+		var frame = StackFrame{Id: id, Name: "?", Source: s.source()}
+
+		if statement := statementAt(stop.Event.Definition, path); statement != nil {
+			frame.Name = statement.Command
+		}
+
+		if location := (vm.TraceEvent{Definition: stop.Event.Definition, Path: path}).Location(); location != nil {
+			frame.Line = location.Line
+			frame.Column = location.Column
+		}
+
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+func (s *Session) source() *Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &Source{Name: filepath.Base(s.launch.Program), Path: s.launch.Program}
+}
+
+// framePath returns the path of the statement of the frame, frame 1 is the paused statement, next ones are its parents
+func framePath(path []int, id int) ([]int, bool) {
+	var length = len(path) - 2*(id-1)
+
+	if id < 1 || length < 1 {
+		return nil, false
+	}
+
+	return path[:length], true
+}
+
+// statementAt returns the statement of the definition at the path, see vm.StatementRef
+func statementAt(definition *logiAst.Definition, path []int) *logiAst.Statement {
+	if definition == nil || len(path) == 0 || path[0] < 0 || path[0] >= len(definition.Statements) {
+		return nil
+	}
+
+	var statement = &definition.Statements[path[0]]
+
+	for i := 1; i+1 < len(path); i += 2 {
+		if path[i] < 0 || path[i] >= len(statement.SubStatements) || path[i+1] < 0 || path[i+1] >= len(statement.SubStatements[path[i]]) {
+			return nil
+		}
+
+		statement = &statement.SubStatements[path[i]][path[i+1]]
+	}
+
+	return statement
+}
+
+// scopes of a frame, variables reference is 2*frameId for parameters and 2*frameId+1 for variables.
+// Variables are only known for the paused statement.
+func (s *Session) scopes(frameId int) ([]Scope, error) {
+	stop, err := s.paused()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, found := framePath(stop.Event.Path, frameId); !found {
+		return nil, fmt.Errorf("frame %d not found", frameId)
+	}
+
+	var scopes = []Scope{{Name: "Parameters", VariablesReference: 2 * frameId}}
+
+	if frameId == 1 {
+		scopes = append(scopes, Scope{Name: "Variables", VariablesReference: 2*frameId + 1})
+	}
+
+	return scopes, nil
+}
+
+func (s *Session) variables(reference int) ([]Variable, error) {
+	stop, err := s.paused()
+
+	if err != nil {
+		return nil, err
+	}
+
+	path, found := framePath(stop.Event.Path, reference/2)
+
+	if !found || (reference%2 == 1 && reference/2 != 1) {
+		return nil, fmt.Errorf("variables %d not found", reference)
+	}
+
+	var vars = stop.Event.Environment.Vars()
+	var result = make([]Variable, 0)
+
+	if reference%2 == 1 {
+		var names []string
+
+		for name := range vars {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			result = append(result, variable(name, vars[name]))
+		}
+
+		return result, nil
+	}
+
+	var statement = statementAt(stop.Event.Definition, path)
+
+	if statement == nil {
+		return result, nil
+	}
+
+	for _, parameter := range statement.Parameters {
+		if parameter.Expression == nil {
+			result = append(result, variable(parameter.Name, parameter.Value))
+
+			continue
+		}
+
+		// expressions are shown with their value in the environment of the paused statement, names are also
+		// parsed as variable expressions, they are shown as they are if there is no such variable
+		value, err := s.vm.Evaluate(*parameter.Expression, vars, nil)
+
+		if err != nil {
+			value = parameter.Value
+		}
+
+		result = append(result, variable(parameter.Name, value))
+	}
+
+	return result, nil
+}
+
+func variable(name string, value common.Value) Variable {
+	return Variable{Name: name, Value: formatValue(value), Type: string(value.Kind)}
+}
+
+func formatValue(value common.Value) string {
+	data, err := json.Marshal(value.AsInterface())
+
+	if err != nil {
+		return value.ToDisplayName()
+	}
+
+	return string(data)
+}
+
+// outputWriter writes to the debug console of the client
+type outputWriter struct {
+	session  *Session
+	category string
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.session.event("output", OutputEvent{Category: w.category, Output: string(p)})
+
+	return len(p), nil
+}
+
+func (s *Session) respond(request Request, body any, err error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+
+	var response = Response{
+		Seq:        s.seq,
+		Type:       "response",
+		RequestSeq: request.Seq,
+		Success:    err == nil,
+		Command:    request.Command,
+		Body:       body,
+	}
+
+	if err != nil {
+		response.Message = err.Error()
+		response.Body = nil
+	}
+
+	s.write(response)
+}
+
+func (s *Session) event(event string, body any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.seq++
+
+	s.write(Event{Seq: s.seq, Type: "event", Event: event, Body: body})
+}
+
+func (s *Session) write(message any) {
+	if err := s.stream.WriteObject(message); err != nil {
+		log.Warnf("failed to write debug adapter message: %v", err)
+	}
+}
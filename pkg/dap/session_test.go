@@ -0,0 +1,311 @@
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const lampLgm = `macro lamp {
+    kind Syntax
+
+    syntax {
+        actions { command }
+    }
+
+    scopes {
+        command {
+            on(<component Name>)
+            off(<component Name>)
+            group(<name string>) { command }
+        }
+    }
+}`
+
+const lampLg = `lamp l1 {
+    actions {
+        on(led1)
+        group("pair") {
+            on(led2)
+            off(led1)
+        }
+        off(led2)
+    }
+}`
+
+// message is a response or an event received by the client
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Command    string          `json:"command"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type client struct {
+	t        *testing.T
+	stream   jsonrpc2.ObjectStream
+	messages chan message
+	seq      int
+	output   []string
+}
+
+func newClient(t *testing.T, options Options) *client {
+	serverConn, clientConn := net.Pipe()
+
+	var session = NewSession(jsonrpc2.NewBufferedStream(serverConn, jsonrpc2.VSCodeObjectCodec{}), options)
+
+	go func() {
+		_ = session.Serve()
+		_ = serverConn.Close()
+	}()
+
+	var c = &client{
+		t:        t,
+		stream:   jsonrpc2.NewBufferedStream(clientConn, jsonrpc2.VSCodeObjectCodec{}),
+		messages: make(chan message, 100),
+	}
+
+	go func() {
+		defer close(c.messages)
+
+		for {
+			var m message
+
+			if err := c.stream.ReadObject(&m); err != nil {
+				return
+			}
+
+			c.messages <- m
+		}
+	}()
+
+	t.Cleanup(func() {
+		_ = c.stream.Close()
+	})
+
+	return c
+}
+
+// request sends the request and returns its response, output events received before it are collected
+func (c *client) request(command string, args any, body any) message {
+	c.seq++
+
+	var request = map[string]any{"seq": c.seq, "type": "request", "command": command}
+
+	if args != nil {
+		request["arguments"] = args
+	}
+
+	if err := c.stream.WriteObject(request); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var response = c.next(func(m message) bool { return m.Type == "response" && m.RequestSeq == c.seq })
+
+	if body != nil {
+		assert.NoError(c.t, json.Unmarshal(response.Body, body))
+	}
+
+	return response
+}
+
+// event waits for the event
+func (c *client) event(event string, body any) message {
+	var m = c.next(func(m message) bool { return m.Type == "event" && m.Event == event })
+
+	if body != nil {
+		assert.NoError(c.t, json.Unmarshal(m.Body, body))
+	}
+
+	return m
+}
+
+func (c *client) next(match func(m message) bool) message {
+	for {
+		select {
+		case m, ok := <-c.messages:
+			if !ok {
+				c.t.Fatal("session is closed")
+			}
+
+			if m.Type == "event" && m.Event == "output" {
+				var output OutputEvent
+				_ = json.Unmarshal(m.Body, &output)
+				c.output = append(c.output, strings.TrimSpace(output.Output))
+			}
+
+			if match(m) {
+				return m
+			}
+		case <-time.After(5 * time.Second):
+			c.t.Fatal("timeout waiting for message")
+		}
+	}
+}
+
+func writeProgram(t *testing.T) string {
+	var dir = t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lamp.lgm"), []byte(lampLgm), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lamp.lg"), []byte(lampLg), 0644))
+
+	return filepath.Join(dir, "lamp.lg")
+}
+
+func TestSessionDebug(t *testing.T) {
+	var program = writeProgram(t)
+	var c = newClient(t, Options{})
+
+	var capabilities Capabilities
+	assert.True(t, c.request("initialize", map[string]any{"adapterID": "logi"}, &capabilities).Success)
+	assert.True(t, capabilities.SupportsConfigurationDoneRequest)
+
+	assert.True(t, c.request("launch", LaunchArguments{Program: program}, nil).Success)
+	c.event("initialized", nil)
+
+	// line 5 is on(led2), line 7 has no statement
+	var breakpoints struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+
+	c.request("setBreakpoints", SetBreakpointsArguments{
+		Source:      Source{Path: program},
+		Breakpoints: []SourceBreakpoint{{Line: 5}, {Line: 7}},
+	}, &breakpoints)
+
+	if assert.Len(t, breakpoints.Breakpoints, 2) {
+		assert.True(t, breakpoints.Breakpoints[0].Verified)
+		assert.False(t, breakpoints.Breakpoints[1].Verified)
+	}
+
+	assert.True(t, c.request("configurationDone", nil, nil).Success)
+
+	var stopped StoppedEvent
+	c.event("stopped", &stopped)
+	assert.Equal(t, "breakpoint", stopped.Reason)
+
+	var stackTrace struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+
+	c.request("stackTrace", StackTraceArguments{ThreadId: threadId}, &stackTrace)
+
+	var frames []string
+
+	for _, frame := range stackTrace.StackFrames {
+		frames = append(frames, fmt.Sprintf("%s %s:%d", frame.Name, filepath.Base(frame.Source.Path), frame.Line))
+	}
+
+	assert.Equal(t, []string{"on lamp.lg:5", "group lamp.lg:4", "actions lamp.lg:2"}, frames)
+
+	var scopes struct {
+		Scopes []Scope `json:"scopes"`
+	}
+
+	c.request("scopes", ScopesArguments{FrameId: 2}, &scopes)
+	assert.Equal(t, []Scope{{Name: "Parameters", VariablesReference: 4}}, scopes.Scopes)
+
+	var variables struct {
+		Variables []Variable `json:"variables"`
+	}
+
+	c.request("variables", VariablesArguments{VariablesReference: 4}, &variables)
+	assert.Equal(t, []Variable{{Name: "name", Value: `"pair"`, Type: "String"}}, variables.Variables)
+
+	c.request("variables", VariablesArguments{VariablesReference: 2}, &variables)
+	assert.Equal(t, []Variable{{Name: "component", Value: `"led2"`, Type: "String"}}, variables.Variables)
+
+	// step over off(led1), then step out of the group
+	assert.True(t, c.request("next", nil, nil).Success)
+	c.event("stopped", &stopped)
+	assert.Equal(t, "step", stopped.Reason)
+
+	assert.True(t, c.request("stepOut", nil, nil).Success)
+	c.event("stopped", &stopped)
+
+	c.request("stackTrace", StackTraceArguments{ThreadId: threadId}, &stackTrace)
+	assert.Equal(t, 8, stackTrace.StackFrames[0].Line)
+
+	assert.True(t, c.request("continue", nil, nil).Success)
+
+	var exited ExitedEvent
+	c.event("exited", &exited)
+	c.event("terminated", nil)
+
+	assert.Equal(t, 0, exited.ExitCode)
+	assert.Equal(t, []string{"actions", "on component=\"led1\"", "group name=\"pair\"", "on component=\"led2\"", "off component=\"led1\"", "off component=\"led2\""}, c.output)
+
+	assert.False(t, c.request("continue", nil, nil).Success)
+	assert.True(t, c.request("disconnect", nil, nil).Success)
+}
+
+func TestSessionStopOnEntry(t *testing.T) {
+	var program = writeProgram(t)
+	var c = newClient(t, Options{})
+
+	c.request("initialize", nil, nil)
+	assert.True(t, c.request("launch", LaunchArguments{Program: program, StopOnEntry: true}, nil).Success)
+	c.request("configurationDone", nil, nil)
+
+	var stopped StoppedEvent
+	c.event("stopped", &stopped)
+	assert.Equal(t, "entry", stopped.Reason)
+
+	// disconnect stops the paused execution
+	assert.True(t, c.request("disconnect", nil, nil).Success)
+}
+
+func TestSessionLaunch(t *testing.T) {
+	var program = writeProgram(t)
+
+	tests := map[string]struct {
+		args            LaunchArguments
+		expectedMessage string
+	}{
+		"program is required": {
+			expectedMessage: "program is required",
+		},
+		"definition not found": {
+			args:            LaunchArguments{Program: program, Definition: "l2"},
+			expectedMessage: `definition "l2" not found`,
+		},
+		"implementer not found": {
+			args:            LaunchArguments{Program: program, Implementer: "unknown"},
+			expectedMessage: `implementer "unknown" not found`,
+		},
+		"macros not found": {
+			args:            LaunchArguments{Program: program, Macros: []string{filepath.Join(filepath.Dir(program), "unknown")}},
+			expectedMessage: "error reading macros",
+		},
+		"launched": {
+			args: LaunchArguments{Program: program, Definition: "l1"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var c = newClient(t, Options{})
+
+			c.request("initialize", nil, nil)
+
+			var response = c.request("launch", tt.args, nil)
+
+			if tt.expectedMessage != "" {
+				assert.False(t, response.Success)
+				assert.Contains(t, response.Message, tt.expectedMessage)
+			} else {
+				assert.True(t, response.Success)
+			}
+		})
+	}
+}
@@ -0,0 +1,680 @@
+package decompiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"gopkg.in/yaml.v3"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJson Format = "json"
+	FormatYaml Format = "yaml"
+)
+
+// Issue describes a part of the input which can not be expressed with the macro syntax, it is skipped from the result.
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+type Result struct {
+	Source      string               `json:"source"`
+	Definitions []logiAst.Definition `json:"definitions"`
+	Issues      []Issue              `json:"issues"`
+}
+
+// Decompile converts definitions in the `normal` compile output shape back to logi source.
+// The input can be a list of definitions, a single definition or an object with `definitions` key.
+// Values can be written in the compiled form ({"kind": "Integer", "integer": 1}) or as plain json/yaml values.
+// If macroName is not empty, it overrides the macro names of the definitions.
+func Decompile(data []byte, format Format, macros []macroAst.Macro, macroName string) (*Result, error) {
+	input, err := decodeInput(data, format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var d = &decompiler{}
+
+	definitions, err := d.convertInput(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result = new(Result)
+	var sources []string
+
+	for _, definition := range definitions {
+		if macroName != "" {
+			definition.MacroName = macroName
+		}
+
+		macroDefinition := locateMacro(macros, definition.MacroName)
+
+		if macroDefinition == nil {
+			return nil, fmt.Errorf("macro definition not found: %s", definition.MacroName)
+		}
+
+		if definition.Name == "" {
+			return nil, fmt.Errorf("definition name is required")
+		}
+
+		d.macroDefinition = macroDefinition
+		definition.Statements = d.sanitizeStatements(definition.Name, definition.Statements, false)
+
+		source, err := logi.Print(definition, *macroDefinition)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to print definition %s: %w", definition.Name, err)
+		}
+
+		sources = append(sources, source)
+		result.Definitions = append(result.Definitions, definition)
+	}
+
+	result.Source = strings.Join(sources, "\n")
+	result.Issues = d.issues
+
+	return result, nil
+}
+
+type decompiler struct {
+	macroDefinition *macroAst.Macro
+	issues          []Issue
+}
+
+func (d *decompiler) report(path string, format string, args ...interface{}) {
+	d.issues = append(d.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// sanitizeStatements removes parameters, attributes and statements which can not be expressed by the macro syntax.
+func (d *decompiler) sanitizeStatements(path string, statements []logiAst.Statement, nested bool) []logiAst.Statement {
+	var result []logiAst.Statement
+
+	for idx, statement := range statements {
+		var statementPath = fmt.Sprintf("%s/%s[%d]", path, statementName(statement), idx)
+
+		if statement.Scope == "" && nested {
+			statement.Scope = d.inferScope(statement)
+		}
+
+		syntaxStatements, found := d.scopeSyntaxStatements(statement.Scope)
+
+		if !found {
+			d.report(statementPath, "scope %s is not defined in macro %s", statement.Scope, d.macroDefinition.Name)
+			continue
+		}
+
+		var candidates = commandSyntaxStatements(syntaxStatements, statement.Command)
+
+		if len(candidates) == 0 {
+			d.report(statementPath, "no syntax statement for command %q in scope %q", statement.Command, statement.Scope)
+			continue
+		}
+
+		statement.Parameters = d.sanitizeParameters(statementPath, statement.Parameters, candidates)
+		statement.Attributes = d.sanitizeAttributes(statementPath, statement.Attributes, candidates)
+
+		var arraySlots = d.arraySlots(candidates)
+
+		for i, subStatements := range statement.SubStatements {
+			if name, ok := arraySlots[i]; ok && logiAst.IsArrayItems(subStatements) {
+				statement.SubStatements[i] = d.sanitizeArrayItems(statementPath, name, subStatements)
+				continue
+			}
+
+			statement.SubStatements[i] = d.sanitizeStatements(statementPath, subStatements, true)
+
+			if statement.SubStatements[i] == nil {
+				statement.SubStatements[i] = make([]logiAst.Statement, 0)
+			}
+		}
+
+		if _, err := logi.PrintStatement(statement, *d.macroDefinition); err != nil {
+			d.report(statementPath, "statement can not be expressed: %v", err)
+			continue
+		}
+
+		result = append(result, statement)
+	}
+
+	return result
+}
+
+// sanitizeArrayItems removes parts of array items which can not be expressed, an item can only contain the value of the array parameter.
+func (d *decompiler) sanitizeArrayItems(path string, name string, items []logiAst.Statement) []logiAst.Statement {
+	var result = make([]logiAst.Statement, 0)
+
+	for idx, item := range items {
+		var itemPath = fmt.Sprintf("%s/%s[%d]", path, name, idx)
+		var sanitized = logiAst.Statement{}
+
+		for _, parameter := range item.Parameters {
+			if parameter.Name != name || len(sanitized.Parameters) > 0 {
+				d.report(itemPath, "parameter %s can not be expressed", parameter.Name)
+				continue
+			}
+
+			sanitized.Parameters = append(sanitized.Parameters, parameter)
+		}
+
+		for _, attribute := range item.Attributes {
+			d.report(itemPath, "attribute %s can not be expressed", attribute.Name)
+		}
+
+		for _, argument := range item.Arguments {
+			d.report(itemPath, "argument %s can not be expressed", argument.Name)
+		}
+
+		if len(item.SubStatements) > 0 {
+			d.report(itemPath, "sub statements can not be expressed")
+		}
+
+		if len(sanitized.Parameters) == 0 {
+			d.report(itemPath, "array item has no value")
+			continue
+		}
+
+		result = append(result, sanitized)
+	}
+
+	return result
+}
+
+// arraySlots returns the indexes of sub statements which are items of array parameters, mapped to the parameter names.
+func (d *decompiler) arraySlots(candidates []macroAst.SyntaxStatement) map[int]string {
+	var slots = make(map[int]string)
+
+	for _, candidate := range candidates {
+		d.collectArraySlots(candidate.Elements, 0, slots)
+	}
+
+	return slots
+}
+
+// collectArraySlots walks the elements in the order they produce sub statements and returns the next sub statement index.
+func (d *decompiler) collectArraySlots(elements []macroAst.SyntaxStatementElement, index int, slots map[int]string) int {
+	for _, element := range elements {
+		switch element.Kind {
+		case macroAst.SyntaxStatementElementKindScope:
+			index++
+		case macroAst.SyntaxStatementElementKindVariableKeyword:
+			if element.VariableKeyword.Type.Name == "array" {
+				slots[index] = element.VariableKeyword.Name
+				index++
+			} else if typeStatement := d.lookupType(element.VariableKeyword.Type.Name); typeStatement != nil {
+				index = d.collectArraySlots(typeStatement.Elements, index, slots)
+			}
+		case macroAst.SyntaxStatementElementKindTypeReference:
+			if typeStatement := d.lookupType(element.TypeReference.Name); typeStatement != nil {
+				index = d.collectArraySlots(typeStatement.Elements, index, slots)
+			}
+		case macroAst.SyntaxStatementElementKindCombination:
+			var next = index
+
+			for _, item := range element.Combination.Elements {
+				next = max(next, d.collectArraySlots([]macroAst.SyntaxStatementElement{item}, index, slots))
+			}
+
+			index = next
+		}
+	}
+
+	return index
+}
+
+func (d *decompiler) sanitizeParameters(path string, parameters []logiAst.Parameter, candidates []macroAst.SyntaxStatement) []logiAst.Parameter {
+	var names = make(map[string]bool)
+	var dynamic bool
+
+	for _, candidate := range candidates {
+		d.collectParameterNames(candidate.Elements, names, &dynamic)
+	}
+
+	if dynamic {
+		return parameters
+	}
+
+	var result []logiAst.Parameter
+
+	for _, parameter := range parameters {
+		if !names[parameter.Name] {
+			d.report(path, "parameter %s can not be expressed", parameter.Name)
+			continue
+		}
+
+		result = append(result, parameter)
+	}
+
+	return result
+}
+
+func (d *decompiler) sanitizeAttributes(path string, attributes []logiAst.Attribute, candidates []macroAst.SyntaxStatement) []logiAst.Attribute {
+	var names = make(map[string]bool)
+
+	for _, candidate := range candidates {
+		for _, element := range candidate.Elements {
+			if element.Kind == macroAst.SyntaxStatementElementKindAttributeList {
+				for _, attribute := range element.AttributeList.Attributes {
+					names[attribute.Name] = true
+				}
+			}
+		}
+	}
+
+	var result []logiAst.Attribute
+
+	for _, attribute := range attributes {
+		if !names[attribute.Name] {
+			d.report(path, "attribute %s can not be expressed", attribute.Name)
+			continue
+		}
+
+		result = append(result, attribute)
+	}
+
+	return result
+}
+
+func (d *decompiler) collectParameterNames(elements []macroAst.SyntaxStatementElement, names map[string]bool, dynamic *bool) {
+	for _, element := range elements {
+		switch element.Kind {
+		case macroAst.SyntaxStatementElementKindVariableKeyword:
+			if typeStatement := d.lookupType(element.VariableKeyword.Type.Name); typeStatement != nil {
+				d.collectParameterNames(typeStatement.Elements, names, dynamic)
+			} else {
+				names[element.VariableKeyword.Name] = true
+			}
+		case macroAst.SyntaxStatementElementKindTypeReference:
+			if typeStatement := d.lookupType(element.TypeReference.Name); typeStatement != nil {
+				d.collectParameterNames(typeStatement.Elements, names, dynamic)
+			}
+		case macroAst.SyntaxStatementElementKindParameterList:
+			if element.ParameterList.Dynamic {
+				*dynamic = true
+			}
+
+			for _, parameter := range element.ParameterList.Parameters {
+				names[parameter.Name] = true
+			}
+		case macroAst.SyntaxStatementElementKindCombination:
+			d.collectParameterNames(element.Combination.Elements, names, dynamic)
+		}
+	}
+}
+
+// inferScope locates scope of a nested statement by its command, legacy inputs may not contain scopes.
+func (d *decompiler) inferScope(statement logiAst.Statement) string {
+	for _, scope := range d.macroDefinition.Scopes.Scopes {
+		if len(commandSyntaxStatements(scope.Statements, statement.Command)) > 0 {
+			return scope.Name
+		}
+	}
+
+	return ""
+}
+
+func (d *decompiler) scopeSyntaxStatements(scope string) ([]macroAst.SyntaxStatement, bool) {
+	if scope == "" {
+		return d.macroDefinition.Syntax.Statements, true
+	}
+
+	for _, item := range d.macroDefinition.Scopes.Scopes {
+		if item.Name == scope {
+			return item.Statements, true
+		}
+	}
+
+	return nil, false
+}
+
+func (d *decompiler) lookupType(name string) *macroAst.TypeStatement {
+	for _, typeStatement := range d.macroDefinition.Types.Types {
+		if typeStatement.Name == name {
+			return &typeStatement
+		}
+	}
+
+	return nil
+}
+
+// commandSyntaxStatements returns syntax statements which can produce the given command.
+// Command of a statement is its leading keyword, statements without command can only be produced by syntax statements without leading keyword.
+func commandSyntaxStatements(statements []macroAst.SyntaxStatement, command string) []macroAst.SyntaxStatement {
+	var result []macroAst.SyntaxStatement
+
+	for _, statement := range statements {
+		if len(statement.Elements) == 0 {
+			continue
+		}
+
+		var first = statement.Elements[0]
+
+		switch first.Kind {
+		case macroAst.SyntaxStatementElementKindKeyword:
+			if first.KeywordDef.Name == command {
+				result = append(result, statement)
+			}
+		case macroAst.SyntaxStatementElementKindCombination:
+			for _, item := range first.Combination.Elements {
+				if item.Kind == macroAst.SyntaxStatementElementKindKeyword && item.KeywordDef.Name == command {
+					result = append(result, statement)
+					break
+				} else if item.Kind != macroAst.SyntaxStatementElementKindKeyword && command == "" {
+					result = append(result, statement)
+					break
+				}
+			}
+		default:
+			if command == "" {
+				result = append(result, statement)
+			}
+		}
+	}
+
+	return result
+}
+
+func statementName(statement logiAst.Statement) string {
+	if statement.Command != "" {
+		return statement.Command
+	}
+
+	return "statement"
+}
+
+func locateMacro(macros []macroAst.Macro, name string) *macroAst.Macro {
+	for _, macroDefinition := range macros {
+		if macroDefinition.Name == name {
+			return &macroDefinition
+		}
+	}
+
+	return nil
+}
+
+func decodeInput(data []byte, format Format) (interface{}, error) {
+	var input interface{}
+
+	switch format {
+	case FormatJson:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&input); err != nil {
+			return nil, fmt.Errorf("failed to decode json: %w", err)
+		}
+	case FormatYaml:
+		if err := yaml.Unmarshal(data, &input); err != nil {
+			return nil, fmt.Errorf("failed to decode yaml: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	return input, nil
+}
+
+func (d *decompiler) convertInput(input interface{}) ([]logiAst.Definition, error) {
+	switch item := input.(type) {
+	case []interface{}:
+		var result []logiAst.Definition
+
+		for idx, definitionItem := range item {
+			definition, err := d.convertDefinition(definitionItem)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert definition #%d: %w", idx, err)
+			}
+
+			result = append(result, *definition)
+		}
+
+		return result, nil
+	case map[string]interface{}:
+		if definitions, ok := item["definitions"]; ok {
+			return d.convertInput(definitions)
+		}
+
+		definition, err := d.convertDefinition(item)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert definition: %w", err)
+		}
+
+		return []logiAst.Definition{*definition}, nil
+	default:
+		return nil, fmt.Errorf("unexpected input, expecting list of definitions or definition object")
+	}
+}
+
+func (d *decompiler) convertDefinition(input interface{}) (*logiAst.Definition, error) {
+	item, ok := input.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("definition must be an object")
+	}
+
+	var result = new(logiAst.Definition)
+
+	result.MacroName = asString(item["macroName"])
+	result.Name = asString(item["name"])
+
+	d.reportUnknownKeys(result.Name, item, "macroName", "name", "statements", "plainStatements")
+
+	// plain statements are kept so unchanged statements are printed in their original form
+	if item["plainStatements"] != nil {
+		if err := remarshal(item["plainStatements"], &result.PlainStatements); err != nil {
+			d.report(result.Name, "plain statements can not be used: %v", err)
+			result.PlainStatements = nil
+		}
+	}
+
+	statements, err := d.convertStatements(result.Name, item["statements"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Statements = statements
+
+	return result, nil
+}
+
+func (d *decompiler) convertStatements(path string, input interface{}) ([]logiAst.Statement, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	items, ok := input.([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("statements must be a list")
+	}
+
+	var result = make([]logiAst.Statement, 0)
+
+	for idx, item := range items {
+		statement, err := d.convertStatement(path, idx, item)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert statement #%d: %w", idx, err)
+		}
+
+		result = append(result, *statement)
+	}
+
+	return result, nil
+}
+
+func (d *decompiler) convertStatement(path string, idx int, input interface{}) (*logiAst.Statement, error) {
+	item, ok := input.(map[string]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("statement must be an object")
+	}
+
+	var result = new(logiAst.Statement)
+
+	result.Scope = asString(item["scope"])
+	result.Command = asString(item["command"])
+
+	var statementPath = fmt.Sprintf("%s/%s[%d]", path, statementName(*result), idx)
+
+	d.reportUnknownKeys(statementPath, item, "scope", "command", "parameters", "attributes", "arguments", "subStatements")
+
+	for _, parameterItem := range asList(item["parameters"]) {
+		parameterMap, ok := parameterItem.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("parameter must be an object")
+		}
+
+		var parameter = logiAst.Parameter{
+			Name: asString(parameterMap["name"]),
+		}
+
+		value, err := convertValue(parameterMap["value"])
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert value of parameter %s: %w", parameter.Name, err)
+		}
+
+		parameter.Value = value
+
+		d.reportUnknownKeys(statementPath+"/parameters/"+parameter.Name, parameterMap, "name", "value", "expression")
+
+		if parameterMap["expression"] != nil {
+			var expression = new(common.Expression)
+
+			if err := remarshal(parameterMap["expression"], expression); err != nil {
+				return nil, fmt.Errorf("failed to convert expression of parameter %s: %w", parameter.Name, err)
+			}
+
+			parameter.Expression = expression
+		}
+
+		result.Parameters = append(result.Parameters, parameter)
+	}
+
+	for _, attributeItem := range asList(item["attributes"]) {
+		attributeMap, ok := attributeItem.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("attribute must be an object")
+		}
+
+		var attribute = logiAst.Attribute{
+			Name: asString(attributeMap["name"]),
+		}
+
+		d.reportUnknownKeys(statementPath+"/attributes/"+attribute.Name, attributeMap, "name", "value")
+
+		if attributeMap["value"] != nil {
+			value, err := convertValue(attributeMap["value"])
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert value of attribute %s: %w", attribute.Name, err)
+			}
+
+			attribute.Value = common.PointerValue(value)
+		}
+
+		result.Attributes = append(result.Attributes, attribute)
+	}
+
+	for _, argumentItem := range asList(item["arguments"]) {
+		var argument logiAst.Argument
+
+		if err := remarshal(argumentItem, &argument); err != nil {
+			return nil, fmt.Errorf("failed to convert argument: %w", err)
+		}
+
+		result.Arguments = append(result.Arguments, argument)
+	}
+
+	for _, subStatementsItem := range asList(item["subStatements"]) {
+		subStatements, err := d.convertStatements(statementPath, subStatementsItem)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.SubStatements = append(result.SubStatements, subStatements)
+	}
+
+	return result, nil
+}
+
+// reportUnknownKeys reports keys of the input object which are not part of the definition shape, they are skipped from the result.
+func (d *decompiler) reportUnknownKeys(path string, item map[string]interface{}, known ...string) {
+	var unknown []string
+
+	for key := range item {
+		if !slices.Contains(known, key) {
+			unknown = append(unknown, key)
+		}
+	}
+
+	sort.Strings(unknown)
+
+	for _, key := range unknown {
+		d.report(path, "key %s can not be expressed", key)
+	}
+}
+
+// convertValue accepts both compiled values, objects with a kind, and plain values.
+func convertValue(input interface{}) (common.Value, error) {
+	var value common.Value
+
+	if item, ok := input.(map[string]interface{}); ok {
+		if _, ok := item["kind"].(string); ok {
+			err := remarshal(item, &value)
+
+			return value, err
+		}
+	}
+
+	return common.ValueOf(input)
+}
+
+func remarshal(input interface{}, output interface{}) error {
+	data, err := json.Marshal(input)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, output)
+}
+
+func asString(input interface{}) string {
+	if s, ok := input.(string); ok {
+		return s
+	}
+
+	return ""
+}
+
+func asList(input interface{}) []interface{} {
+	if l, ok := input.([]interface{}); ok {
+		return l
+	}
+
+	return nil
+}
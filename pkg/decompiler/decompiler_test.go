@@ -0,0 +1,221 @@
+package decompiler
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"testing"
+)
+
+const creditRuleMacro = `
+macro creditRule {
+    kind Syntax
+
+    syntax {
+        creditScore <min int> <max int>
+        income <min int> <max int>
+        age <min int> <max int>
+    }
+}
+`
+
+const roleMacro = `
+macro role {
+    kind Syntax
+
+    syntax {
+        description <description string>
+        permissions { permissions }
+    }
+
+    scopes {
+        permissions {
+            WRITE <object Name>
+            READ <object Name>
+            CRUD <object Name> [owner bool]
+        }
+    }
+}
+`
+
+const entityMacro = `
+macro entity {
+    kind Syntax
+
+    syntax {
+        tags <tags array<string>>
+        fields { fields }
+    }
+
+    scopes {
+        fields {
+            <name Name> <type Type>
+        }
+    }
+}
+`
+
+func TestDecompile(t *testing.T) {
+	tests := map[string]struct {
+		macro          string
+		macroName      string
+		format         Format
+		input          string
+		expectedSource string
+		expectedIssues []Issue
+	}{
+		"compiled json": {
+			macro:  creditRuleMacro,
+			format: FormatJson,
+			input: `[
+				{
+					"macroName": "creditRule",
+					"name": "Rule1",
+					"statements": [
+						{
+							"scope": "",
+							"command": "creditScore",
+							"parameters": [
+								{"name": "min", "value": {"kind": "Integer", "integer": 500}},
+								{"name": "max", "value": {"kind": "Integer", "integer": 600}}
+							]
+						}
+					]
+				}
+			]`,
+			expectedSource: "creditRule Rule1 {\n    creditScore 500 600\n}\n",
+		},
+		"plain yaml values with macro override": {
+			macro:     creditRuleMacro,
+			macroName: "creditRule",
+			format:    FormatYaml,
+			input: `
+name: Rule2
+statements:
+  - command: age
+    parameters:
+      - name: min
+        value: 18
+      - name: max
+        value: 65
+      - name: unit
+        value: years
+  - command: height
+    parameters:
+      - name: min
+        value: 150
+`,
+			expectedSource: "creditRule Rule2 {\n    age 18 65\n}\n",
+			expectedIssues: []Issue{
+				{Path: "Rule2/age[0]", Message: "parameter unit can not be expressed"},
+				{Path: "Rule2/height[1]", Message: "no syntax statement for command \"height\" in scope \"\""},
+			},
+		},
+		"nested statements without scope": {
+			macro:  roleMacro,
+			format: FormatJson,
+			input: `{
+				"definitions": [
+					{
+						"macroName": "role",
+						"name": "Role1",
+						"statements": [
+							{"command": "description", "parameters": [{"name": "description", "value": "Admin"}]},
+							{"command": "permissions", "subStatements": [[
+								{"command": "WRITE", "parameters": [{"name": "object", "value": "object1"}]},
+								{"command": "CRUD", "parameters": [{"name": "object", "value": "object2"}], "attributes": [{"name": "owner"}, {"name": "hidden"}]}
+							]]}
+						]
+					}
+				]
+			}`,
+			expectedSource: "role Role1 {\n    description \"Admin\"\n    permissions {\n        WRITE object1\n        CRUD object2 [owner]\n    }\n}\n",
+			expectedIssues: []Issue{
+				{Path: "Role1/permissions[1]/CRUD[1]", Message: "attribute hidden can not be expressed"},
+			},
+		},
+		"unknown keys": {
+			macro:  creditRuleMacro,
+			format: FormatYaml,
+			input: `
+macroName: creditRule
+name: Rule3
+owner: admin
+statements:
+  - command: income
+    comment: monthly
+    parameters:
+      - name: min
+        value: 1000
+        unit: usd
+      - name: max
+        value: 5000
+`,
+			expectedSource: "creditRule Rule3 {\n    income 1000 5000\n}\n",
+			expectedIssues: []Issue{
+				{Path: "Rule3", Message: "key owner can not be expressed"},
+				{Path: "Rule3/income[0]", Message: "key comment can not be expressed"},
+				{Path: "Rule3/income[0]/parameters/min", Message: "key unit can not be expressed"},
+			},
+		},
+		"scope statements without command and array items": {
+			macro:  entityMacro,
+			format: FormatJson,
+			input: `{
+				"macroName": "entity",
+				"name": "User",
+				"statements": [
+					{"command": "tags", "subStatements": [[
+						{"parameters": [{"name": "tags", "value": "a"}]},
+						{"parameters": [{"name": "tags", "value": "b"}, {"name": "extra", "value": 1}]}
+					]]},
+					{"command": "fields", "subStatements": [[
+						{"parameters": [{"name": "name", "value": "id"}, {"name": "type", "value": "int"}]},
+						{"parameters": [{"name": "name", "value": "email"}, {"name": "type", "value": "string"}, {"name": "unique", "value": true}]}
+					]]}
+				]
+			}`,
+			expectedSource: "entity User {\n    tags [\"a\", \"b\"]\n    fields {\n        id int\n        email string\n    }\n}\n",
+			expectedIssues: []Issue{
+				{Path: "User/tags[0]/tags[1]", Message: "parameter extra can not be expressed"},
+				{Path: "User/fields[1]/statement[1]", Message: "parameter unique can not be expressed"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mAst, err := macro.ParseMacroContent(tt.macro, false)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			result, err := Decompile([]byte(tt.input), tt.format, mAst.Macros, tt.macroName)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.expectedSource, result.Source)
+			assert.Equal(t, tt.expectedIssues, result.Issues)
+
+			// decompiled source must compile back to the same definitions
+			ast, err := logi.Parse(result.Source, mAst.Macros, false)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for i := range ast.Definitions {
+				ast.Definitions[i].PlainStatements = nil
+			}
+
+			expectedJson, _ := json.Marshal(result.Definitions)
+			gotJson, _ := json.Marshal(ast.Definitions)
+
+			assert.Equal(t, string(expectedJson), string(gotJson))
+		})
+	}
+}
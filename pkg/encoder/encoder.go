@@ -0,0 +1,132 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Format is an output encoder which can be selected by its name
+type Format struct {
+	// Name of the format, e.g. "json"
+	Name string
+
+	// Extension of the files written in this format, including the dot, e.g. ".json"
+	Extension string
+
+	// ContentType is the media type of the encoded data, e.g. "application/json"
+	ContentType string
+
+	// Binary formats are not text, they are written as is without trailing newline
+	Binary bool
+
+	// Encode encodes the value, value is any json serializable value (definitions, ast, values view, etc.)
+	Encode func(value interface{}) ([]byte, error)
+}
+
+var formats = make(map[string]Format)
+var formatsMu sync.RWMutex
+
+// Register registers a format, formats with the same name are replaced
+func Register(format Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats[format.Name] = format
+}
+
+// Get returns the format registered with the given name
+func Get(name string) (Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	format, ok := formats[name]
+
+	if !ok {
+		return Format{}, fmt.Errorf("unknown format: %s, available formats: %s", name, strings.Join(namesLocked(), ", "))
+	}
+
+	return format, nil
+}
+
+// Names returns names of all registered formats in alphabetical order
+func Names() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	return namesLocked()
+}
+
+// Encode encodes the value with the format registered with the given name
+func Encode(name string, value interface{}) ([]byte, error) {
+	format, err := Get(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Encode(value)
+}
+
+func namesLocked() []string {
+	var result []string
+
+	for name := range formats {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// Normalize converts the value to a tree of maps, lists and scalar values, using its json representation.
+// It is used by encoders which do not understand json tags, so all formats have the same field names.
+func Normalize(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var result interface{}
+
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return normalizeNumbers(result), nil
+}
+
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,97 @@
+package encoder
+
+import (
+	"github.com/pelletier/go-toml/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+var testDefinitions = []logiAst.Definition{
+	{
+		MacroName: "creditRule",
+		Name:      "Rule1",
+		Statements: []logiAst.Statement{
+			{
+				Command: "creditScore",
+				Parameters: []logiAst.Parameter{
+					{Name: "min", Value: common.IntegerValue(500)},
+					{Name: "ratio", Value: common.FloatValue(1.5)},
+				},
+			},
+		},
+	},
+}
+
+func TestFormats(t *testing.T) {
+	tests := map[string]struct {
+		decode func(data []byte) (interface{}, error)
+	}{
+		"yaml": {
+			decode: func(data []byte) (interface{}, error) {
+				var result interface{}
+				err := yaml.Unmarshal(data, &result)
+				return result, err
+			},
+		},
+		"toml": {
+			decode: func(data []byte) (interface{}, error) {
+				var result map[string]interface{}
+				err := toml.Unmarshal(data, &result)
+				return result["definitions"], err
+			},
+		},
+		"msgpack": {
+			decode: func(data []byte) (interface{}, error) {
+				var result interface{}
+				err := msgpack.Unmarshal(data, &result)
+				return result, err
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := Encode(name, testDefinitions)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			decoded, err := tt.decode(data)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			definition := decoded.([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "creditRule", definition["macroName"])
+			assert.Equal(t, "Rule1", definition["name"])
+
+			statement := definition["statements"].([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "creditScore", statement["command"])
+
+			parameters := statement["parameters"].([]interface{})
+			assert.EqualValues(t, 500, parameters[0].(map[string]interface{})["value"].(map[string]interface{})["integer"])
+			assert.EqualValues(t, 1.5, parameters[1].(map[string]interface{})["value"].(map[string]interface{})["float"])
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	_, err := Encode("unknown", testDefinitions)
+	assert.Error(t, err)
+
+	Register(Format{Name: "count", Extension: ".txt", Encode: func(value interface{}) ([]byte, error) {
+		return []byte{byte(len(value.([]logiAst.Definition)))}, nil
+	}})
+
+	data, err := Encode("count", testDefinitions)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, data)
+	assert.Contains(t, Names(), "count")
+}
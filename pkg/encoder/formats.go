@@ -0,0 +1,84 @@
+package encoder
+
+import (
+	"encoding/json"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func encodeJson(value interface{}) ([]byte, error) {
+	return json.MarshalIndent(value, "", "  ")
+}
+
+func encodeYaml(value interface{}) ([]byte, error) {
+	normalized, err := Normalize(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(normalized)
+}
+
+// encodeToml encodes the value as toml, as toml document must be a table, lists are put under `definitions` key.
+// Toml has no null value, so null values are omitted.
+func encodeToml(value interface{}) ([]byte, error) {
+	normalized, err := Normalize(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := normalized.(map[string]interface{}); !ok {
+		normalized = map[string]interface{}{
+			"definitions": normalized,
+		}
+	}
+
+	return toml.Marshal(removeNulls(normalized))
+}
+
+func encodeMsgpack(value interface{}) ([]byte, error) {
+	normalized, err := Normalize(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return msgpack.Marshal(normalized)
+}
+
+func removeNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		var result = make([]interface{}, 0, len(v))
+
+		for _, item := range v {
+			if item != nil {
+				result = append(result, removeNulls(item))
+			}
+		}
+
+		return result
+	case map[string]interface{}:
+		var result = make(map[string]interface{})
+
+		for key, item := range v {
+			if item != nil {
+				result[key] = removeNulls(item)
+			}
+		}
+
+		return result
+	default:
+		return value
+	}
+}
+
+func init() {
+	Register(Format{Name: "json", Extension: ".json", ContentType: "application/json", Encode: encodeJson})
+	Register(Format{Name: "yaml", Extension: ".yaml", ContentType: "application/yaml", Encode: encodeYaml})
+	Register(Format{Name: "toml", Extension: ".toml", ContentType: "application/toml", Encode: encodeToml})
+	Register(Format{Name: "msgpack", Extension: ".msgpack", ContentType: "application/msgpack", Binary: true, Encode: encodeMsgpack})
+}
@@ -0,0 +1,140 @@
+package implementer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates an implementer, implementers write their output to the given writer
+type Factory func(output io.Writer) vm.Implementer
+
+var factories = make(map[string]Factory)
+var factoriesMu sync.RWMutex
+
+// Register registers an implementer, implementers with the same name are replaced.
+// Packages can register their implementers in init, so they are available to commands which import them.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	factories[name] = factory
+}
+
+// Get returns the implementer registered with the given name
+func Get(name string) (Factory, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[name]
+
+	if !ok {
+		return nil, fmt.Errorf("implementer %q not found, available implementers: %s", name, strings.Join(namesLocked(), ", "))
+	}
+
+	return factory, nil
+}
+
+// Names returns names of all registered implementers in alphabetical order
+func Names() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	var result []string
+
+	for name := range factories {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// Parameters returns parameters of the statement as plain values, expressions are evaluated if the virtual machine is
+// an execution. Names, e.g. `on(led1)`, are parsed as variables, they are kept as is if there is no such variable.
+func Parameters(v vm.VirtualMachine, statement logiAst.Statement) (map[string]interface{}, error) {
+	var result = make(map[string]interface{})
+	e, isExecution := v.(*vm.Execution)
+
+	for _, parameter := range statement.Parameters {
+		var value = parameter.Value
+
+		if isExecution && parameter.Expression != nil {
+			if parameter.Expression.Kind == common.VariableKind {
+				if _, found := e.Environment().Get(parameter.Expression.Variable.Name); !found {
+					result[parameter.Name] = value.AsInterface()
+
+					continue
+				}
+			}
+
+			var err error
+
+			if value, err = e.Parameter(statement, parameter.Name); err != nil {
+				return nil, err
+			}
+		}
+
+		result[parameter.Name] = value.AsInterface()
+	}
+
+	return result, nil
+}
+
+// Attributes returns attributes of the statement as plain values, attributes without a value are true
+func Attributes(statement logiAst.Statement) map[string]interface{} {
+	var result = make(map[string]interface{})
+
+	for _, attribute := range statement.Attributes {
+		if attribute.Value != nil {
+			result[attribute.Name] = attribute.Value.AsInterface()
+		} else {
+			result[attribute.Name] = true
+		}
+	}
+
+	return result
+}
+
+// declare declares the variables in the environment of the execution, variables are ignored if the virtual machine is
+// not an execution
+func declare(v vm.VirtualMachine, vars map[string]interface{}) error {
+	e, ok := v.(*vm.Execution)
+
+	if !ok {
+		return nil
+	}
+
+	values, err := common.ValuesOf(vars)
+
+	if err != nil {
+		return err
+	}
+
+	for name, value := range values {
+		e.Environment().Declare(name, value)
+	}
+
+	return nil
+}
+
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,28 @@
+package implementer
+
+import (
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("print", Print)
+}
+
+// Print returns an implementer which prints statements with their parameters, and executes their sub statements
+func Print(output io.Writer) vm.Implementer {
+	return vm.NewRouter().Unknown(func(v vm.VirtualMachine, statement logiAst.Statement) error {
+		var parameters []string
+
+		for _, parameter := range statement.Parameters {
+			parameters = append(parameters, parameter.Name+"="+formatValue(parameter.Value.AsInterface()))
+		}
+
+		_, _ = fmt.Fprintln(output, strings.TrimSpace(statement.Command+" "+strings.Join(parameters, " ")))
+
+		return vm.DescendUnknown(v, statement)
+	})
+}
@@ -0,0 +1,223 @@
+package implementer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// closeTimeout is how long processes are waited to exit after they are closed
+var closeTimeout = 5 * time.Second
+
+// ProcessRequest is sent to the process for each statement, as a json line
+type ProcessRequest struct {
+	Id         int                    `json:"id"`
+	Scope      string                 `json:"scope"`
+	Command    string                 `json:"command"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// ProcessResponse is the reply of the process to a request, as a json line
+type ProcessResponse struct {
+	Id int `json:"id"`
+
+	// Error fails the execution of the statement
+	Error string `json:"error,omitempty"`
+
+	// Vars are declared in the block of the statement, so next statements can use them in expressions
+	Vars map[string]interface{} `json:"vars,omitempty"`
+
+	// Output is written to the output of the implementer
+	Output string `json:"output,omitempty"`
+
+	// Skip skips sub statements of the statement
+	Skip bool `json:"skip,omitempty"`
+}
+
+// Process is an implementer which sends statements to an external process over its stdin, and reads results from its
+// stdout. Sub statements are sent after their parent statement succeeds, stderr of the process is written to the output.
+// Statements are sent one at a time, so a process can be used by concurrent executions. The process is killed when the
+// context of an execution is done while its statement is running.
+type Process struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	decoder *json.Decoder
+	output  io.Writer
+	router  *vm.Router
+	seq     int
+	mu      sync.Mutex
+}
+
+// NewProcess starts the command
+func NewProcess(output io.Writer, name string, args ...string) (*Process, error) {
+	cmd, stdin, stdout, err := startCommand(output, name, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var p = &Process{cmd: cmd, stdin: stdin, output: output}
+
+	p.decoder = json.NewDecoder(bufio.NewReader(stdout))
+	p.decoder.UseNumber()
+	p.router = vm.NewRouter().Unknown(p.handle)
+
+	return p, nil
+}
+
+// startCommand starts the command, stderr of the command is written to the output
+func startCommand(output io.Writer, name string, args ...string) (*exec.Cmd, io.WriteCloser, io.ReadCloser, error) {
+	var cmd = exec.Command(name, args...)
+
+	cmd.Stderr = output
+
+	stdin, err := cmd.StdinPipe()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, fmt.Errorf("error starting implementer process: %v", err)
+	}
+
+	return cmd, stdin, stdout, nil
+}
+
+// waitCommand waits for the command to exit, the command is killed if it does not exit in closeTimeout
+func waitCommand(cmd *exec.Cmd) error {
+	var done = make(chan error, 1)
+
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(closeTimeout):
+		_ = cmd.Process.Kill()
+		<-done
+
+		return fmt.Errorf("implementer process did not exit in %v, it is killed", closeTimeout)
+	}
+}
+
+func (p *Process) Call(v vm.VirtualMachine, statement logiAst.Statement) error {
+	return p.router.Call(v, statement)
+}
+
+// Close closes stdin of the process, and waits for it to exit
+func (p *Process) Close() error {
+	_ = p.stdin.Close()
+
+	return waitCommand(p.cmd)
+}
+
+func (p *Process) handle(v vm.VirtualMachine, statement logiAst.Statement) error {
+	parameters, err := Parameters(v, statement)
+
+	if err != nil {
+		return err
+	}
+
+	var ctx = context.Background()
+
+	if e, ok := v.(*vm.Execution); ok {
+		ctx = e.Context()
+	}
+
+	response, err := p.send(ctx, ProcessRequest{
+		Scope:      statement.Scope,
+		Command:    statement.Command,
+		Parameters: parameters,
+		Attributes: Attributes(statement),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if response.Output != "" {
+		_, _ = io.WriteString(p.output, response.Output)
+	}
+
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+
+	if err := declare(v, response.Vars); err != nil {
+		return err
+	}
+
+	if response.Skip {
+		return vm.SkipSubStatements
+	}
+
+	return nil
+}
+
+// send sends the request and reads its response. Requests can not be cancelled over stdin, so the process is killed
+// if the context is done before it replies.
+func (p *Process) send(ctx context.Context, request ProcessRequest) (ProcessResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return ProcessResponse{}, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = p.cmd.Process.Kill()
+	})
+	defer stop()
+
+	p.seq++
+	request.Id = p.seq
+
+	data, err := json.Marshal(request)
+
+	if err != nil {
+		return ProcessResponse{}, err
+	}
+
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		return ProcessResponse{}, p.killedError(ctx, fmt.Errorf("error writing to implementer process: %v", err))
+	}
+
+	var response ProcessResponse
+
+	if err := p.decoder.Decode(&response); err != nil {
+		return ProcessResponse{}, p.killedError(ctx, fmt.Errorf("error reading from implementer process: %v", err))
+	}
+
+	if response.Id != request.Id {
+		return ProcessResponse{}, fmt.Errorf("implementer process replied to %d instead of %d", response.Id, request.Id)
+	}
+
+	return response, nil
+}
+
+// killedError returns the context error if the process is killed by it, otherwise err
+func (p *Process) killedError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("implementer process is killed: %w", ctx.Err())
+	}
+
+	return err
+}
@@ -0,0 +1,237 @@
+package implementer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const lampLgm = `macro lamp {
+    kind Syntax
+
+    syntax {
+        actions { command }
+    }
+
+    scopes {
+        command {
+            on(<component Name>)
+            measure(<name string>)
+            print(<value int>)
+            fail(<message string>)
+            compute(<name string>, <expression string>)
+            wait(<milliseconds int>)
+            group(<name string>) { command }
+        }
+    }
+}`
+
+// TestMain runs the test binary as the implementer process if LOGI_IMPLEMENTER_PROCESS is set
+func TestMain(m *testing.M) {
+	switch os.Getenv("LOGI_IMPLEMENTER_PROCESS") {
+	case "1":
+		runTestProcess()
+
+		return
+	case "rpc":
+		runTestRemoteProcess()
+
+		return
+	case "hang":
+		// ignores end of stdin
+		time.Sleep(time.Hour)
+
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// runTestProcess prints statements, declares measured values and fails fail statements
+func runTestProcess() {
+	var scanner = bufio.NewScanner(os.Stdin)
+
+	for scanner.Scan() {
+		var request ProcessRequest
+		var response ProcessResponse
+
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = err.Error()
+		}
+
+		response.Id = request.Id
+
+		switch request.Command {
+		case "measure":
+			response.Vars = map[string]interface{}{fmt.Sprint(request.Parameters["name"]): 20}
+		case "fail":
+			response.Error = fmt.Sprint(request.Parameters["message"])
+		case "wait":
+			time.Sleep(time.Duration(request.Parameters["milliseconds"].(float64)) * time.Millisecond)
+		case "group":
+			response.Skip = request.Parameters["name"] == "skipped"
+			fallthrough
+		default:
+			data, _ := json.Marshal(request.Parameters)
+			response.Output = request.Command + " " + string(data) + "\n"
+		}
+
+		data, _ := json.Marshal(response)
+		_, _ = os.Stdout.Write(append(data, '\n'))
+	}
+}
+
+func TestProcess(t *testing.T) {
+	tests := map[string]struct {
+		actions        string
+		expectedOutput string
+		expectedError  string
+	}{
+		"statements": {
+			actions:        "on(led1)\n        print(3)",
+			expectedOutput: "actions {}\non {\"component\":\"led1\"}\nprint {\"value\":3}\n",
+		},
+		"variables": {
+			actions:        "measure(\"temperature\")\n        print(temperature + 1)",
+			expectedOutput: "actions {}\nprint {\"value\":21}\n",
+		},
+		"sub statements": {
+			actions:        "group(\"pair\") {\n            on(led1)\n        }\n        group(\"skipped\") {\n            on(led2)\n        }",
+			expectedOutput: "actions {}\ngroup {\"name\":\"pair\"}\non {\"component\":\"led1\"}\ngroup {\"name\":\"skipped\"}\n",
+		},
+		"error": {
+			actions:        "fail(\"broken\")\n        on(led1)",
+			expectedOutput: "actions {}\n",
+			expectedError:  "broken",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var output strings.Builder
+
+			t.Setenv("LOGI_IMPLEMENTER_PROCESS", "1")
+
+			p, err := NewProcess(&output, os.Args[0])
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var v = vm.New()
+
+			assert.NoError(t, v.LoadMacroContent(lampLgm))
+
+			definitions, err := v.LoadLogiContent("lamp l1 {\n    actions {\n        " + tt.actions + "\n    }\n}")
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			err = v.Execute(&definitions[0], p)
+
+			assert.NoError(t, p.Close())
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedOutput, output.String())
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	factory, err := Get("print")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var output strings.Builder
+	var v = vm.New()
+
+	assert.NoError(t, v.LoadMacroContent(lampLgm))
+
+	definitions, err := v.LoadLogiContent("lamp l1 {\n    actions {\n        on(led1)\n    }\n}")
+
+	if assert.NoError(t, err) {
+		assert.NoError(t, v.Execute(&definitions[0], factory(&output)))
+		assert.Equal(t, "actions\non component=\"led1\"\n", output.String())
+	}
+
+	_, err = Get("unknown")
+	assert.ErrorContains(t, err, `implementer "unknown" not found, available implementers: print`)
+}
+
+func TestProcessContext(t *testing.T) {
+	t.Setenv("LOGI_IMPLEMENTER_PROCESS", "1")
+
+	p, err := NewProcess(io.Discard, os.Args[0])
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var v = vm.New()
+
+	assert.NoError(t, v.LoadMacroContent(lampLgm))
+
+	definitions, err := v.LoadLogiContent("lamp l1 {\n    actions {\n        wait(10000)\n    }\n}")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, v.(vm.Executor).ExecuteContext(ctx, &definitions[0], p, vm.Limits{}), context.DeadlineExceeded)
+
+	// process is killed when the context is done, so closing does not wait for the statement
+	var started = time.Now()
+
+	_ = p.Close()
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestProcessCloseTimeout(t *testing.T) {
+	tests := map[string]func() (io.Closer, error){
+		"process": func() (io.Closer, error) {
+			return NewProcess(io.Discard, os.Args[0])
+		},
+		"remote": func() (io.Closer, error) {
+			return StartRemote(vm.New(), RemoteOptions{}, os.Args[0])
+		},
+	}
+
+	closeTimeout = 100 * time.Millisecond
+	defer func() { closeTimeout = 5 * time.Second }()
+
+	for name, start := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("LOGI_IMPLEMENTER_PROCESS", "hang")
+
+			closer, err := start()
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var started = time.Now()
+
+			assert.EqualError(t, closer.Close(), "implementer process did not exit in 100ms, it is killed")
+			assert.Less(t, time.Since(started), 5*time.Second)
+		})
+	}
+}
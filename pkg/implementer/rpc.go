@@ -0,0 +1,322 @@
+package implementer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/sourcegraph/jsonrpc2"
+	wsjsonrpc2 "github.com/sourcegraph/jsonrpc2/websocket"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"net"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+var ErrTimeout = errors.New("remote implementer timed out")
+
+// ExecuteParams are the params of `execute` requests, which are sent to the remote implementer for each statement
+type ExecuteParams struct {
+	// Execution identifies the execution of the statement in `evaluate` requests, while the statement is executed
+	Execution  int64                  `json:"execution"`
+	Scope      string                 `json:"scope"`
+	Command    string                 `json:"command"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// ExecuteResult is the result of `execute` requests, errors are returned as json-rpc errors
+type ExecuteResult struct {
+	// Vars are declared in the block of the statement, so next statements can use them in expressions
+	Vars map[string]interface{} `json:"vars,omitempty"`
+
+	// Output is written to the output of the implementer
+	Output string `json:"output,omitempty"`
+
+	// Skip skips sub statements of the statement
+	Skip bool `json:"skip,omitempty"`
+}
+
+// EvaluateParams are the params of `evaluate` requests, which the remote implementer can send while executing a
+// statement, the result is the value of the expression
+type EvaluateParams struct {
+	// Execution of the statement, expressions see variables of the statement block. If it is zero, only global
+	// variables are visible.
+	Execution  int64                  `json:"execution"`
+	Expression string                 `json:"expression"`
+	Vars       map[string]interface{} `json:"vars"`
+}
+
+// UnmarshalJSON keeps numbers of variables as json.Number, so integers and floats keep their kind
+func (r *ExecuteResult) UnmarshalJSON(data []byte) error {
+	type plain ExecuteResult
+
+	return decodeNumbers(data, (*plain)(r))
+}
+
+// UnmarshalJSON keeps numbers of variables as json.Number, so integers and floats keep their kind
+func (p *EvaluateParams) UnmarshalJSON(data []byte) error {
+	type plain EvaluateParams
+
+	return decodeNumbers(data, (*plain)(p))
+}
+
+func decodeNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// RemoteOptions are options of remote implementers
+type RemoteOptions struct {
+	// Timeout is the maximum duration of a statement, zero is unlimited
+	Timeout time.Duration
+
+	// Output is where outputs of statements and stderr of processes are written, default is io.Discard
+	Output io.Writer
+}
+
+// Remote is an implementer which executes statements with a remote implementer over json-rpc 2.0.
+// Each statement is sent as an `execute` request, and sub statements are sent after their parent statement succeeds.
+// While executing a statement, the remote implementer can evaluate expressions with `evaluate` requests.
+// Statements of concurrent executions are sent concurrently.
+type Remote struct {
+	vm      vm.VirtualMachine
+	conn    *jsonrpc2.Conn
+	options RemoteOptions
+	router  *vm.Router
+	cmd     *exec.Cmd
+
+	executions map[int64]vm.VirtualMachine
+	seq        int64
+	mu         sync.Mutex
+}
+
+// NewRemote returns a remote implementer which uses the stream, expressions without an execution are evaluated
+// with the virtual machine
+func NewRemote(v vm.VirtualMachine, stream jsonrpc2.ObjectStream, options RemoteOptions) *Remote {
+	if options.Output == nil {
+		options.Output = io.Discard
+	}
+
+	var r = &Remote{
+		vm:         v,
+		options:    options,
+		executions: make(map[int64]vm.VirtualMachine),
+	}
+
+	r.router = vm.NewRouter().Unknown(r.handle)
+	r.conn = jsonrpc2.NewConn(context.Background(), stream, jsonrpc2.HandlerWithError(r.handleRequest))
+
+	return r
+}
+
+// DialRemote connects to a remote implementer, e.g. over tcp, messages are framed with Content-Length headers
+func DialRemote(v vm.VirtualMachine, network string, address string, options RemoteOptions) (*Remote, error) {
+	conn, err := net.Dial(network, address)
+
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to remote implementer: %v", err)
+	}
+
+	return NewRemote(v, jsonrpc2.NewBufferedStream(conn, jsonrpc2.VSCodeObjectCodec{}), options), nil
+}
+
+// DialRemoteWebsocket connects to a remote implementer over websocket, each message is a websocket message
+func DialRemoteWebsocket(v vm.VirtualMachine, url string, options RemoteOptions) (*Remote, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to remote implementer: %v", err)
+	}
+
+	return NewRemote(v, wsjsonrpc2.NewObjectStream(conn), options), nil
+}
+
+// StartRemote starts the command, and uses its stdin and stdout as the stream, messages are framed with
+// Content-Length headers
+func StartRemote(v vm.VirtualMachine, options RemoteOptions, name string, args ...string) (*Remote, error) {
+	if options.Output == nil {
+		options.Output = io.Discard
+	}
+
+	cmd, stdin, stdout, err := startCommand(options.Output, name, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var r = NewRemote(v, jsonrpc2.NewBufferedStream(processStream{stdout, stdin}, jsonrpc2.VSCodeObjectCodec{}), options)
+	r.cmd = cmd
+
+	return r, nil
+}
+
+func (r *Remote) Call(v vm.VirtualMachine, statement logiAst.Statement) error {
+	return r.router.Call(v, statement)
+}
+
+// Close closes the connection, and waits for the process to exit if the remote implementer is a process
+func (r *Remote) Close() error {
+	var err = r.conn.Close()
+
+	if r.cmd != nil {
+		return waitCommand(r.cmd)
+	}
+
+	if errors.Is(err, jsonrpc2.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *Remote) handle(v vm.VirtualMachine, statement logiAst.Statement) error {
+	parameters, err := Parameters(v, statement)
+
+	if err != nil {
+		return err
+	}
+
+	var ctx = context.Background()
+
+	if e, ok := v.(*vm.Execution); ok {
+		ctx = e.Context()
+	}
+
+	var callCtx = ctx
+
+	if r.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, r.options.Timeout)
+		defer cancel()
+	}
+
+	var execution = r.addExecution(v)
+	defer r.removeExecution(execution)
+
+	var result ExecuteResult
+
+	err = r.conn.Call(callCtx, "execute", ExecuteParams{
+		Execution:  execution,
+		Scope:      statement.Scope,
+		Command:    statement.Command,
+		Parameters: parameters,
+		Attributes: Attributes(statement),
+	}, &result)
+
+	var rpcErr *jsonrpc2.Error
+
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("%w: %s after %v", ErrTimeout, statement.Command, r.options.Timeout)
+	} else if errors.As(err, &rpcErr) {
+		return errors.New(rpcErr.Message)
+	} else if err != nil {
+		return fmt.Errorf("error calling remote implementer: %w", err)
+	}
+
+	if result.Output != "" {
+		_, _ = io.WriteString(r.options.Output, result.Output)
+	}
+
+	if err := declare(v, result.Vars); err != nil {
+		return err
+	}
+
+	if result.Skip {
+		return vm.SkipSubStatements
+	}
+
+	return nil
+}
+
+// handleRequest handles requests of the remote implementer
+func (r *Remote) handleRequest(_ context.Context, _ *jsonrpc2.Conn, request *jsonrpc2.Request) (interface{}, error) {
+	switch request.Method {
+	case "evaluate":
+		var params EvaluateParams
+
+		if request.Params == nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: "params are required"}
+		}
+
+		if err := json.Unmarshal(*request.Params, &params); err != nil {
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: err.Error()}
+		}
+
+		return r.evaluate(params)
+	default:
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", request.Method)}
+	}
+}
+
+func (r *Remote) evaluate(params EvaluateParams) (interface{}, error) {
+	var v = r.vm
+
+	if params.Execution != 0 {
+		r.mu.Lock()
+		execution, found := r.executions[params.Execution]
+		r.mu.Unlock()
+
+		if !found {
+			return nil, fmt.Errorf("execution %d is not running", params.Execution)
+		}
+
+		v = execution
+	}
+
+	expression, err := logi.ParseExpression(params.Expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := common.ValuesOf(params.Vars)
+
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := v.Evaluate(expression, vars, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.AsInterface(), nil
+}
+
+func (r *Remote) addExecution(v vm.VirtualMachine) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	r.executions[r.seq] = v
+
+	return r.seq
+}
+
+func (r *Remote) removeExecution(execution int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.executions, execution)
+}
+
+// processStream is the stream of a process, it reads from stdout and writes to stdin
+type processStream struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (s processStream) Close() error {
+	return errors.Join(s.WriteCloser.Close(), s.ReadCloser.Close())
+}
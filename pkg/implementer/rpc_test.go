@@ -0,0 +1,234 @@
+package implementer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/sourcegraph/jsonrpc2"
+	wsjsonrpc2 "github.com/sourcegraph/jsonrpc2/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	"github.com/tislib/logi/pkg/vm"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRemoteHandler is a remote implementer, it prints statements, computes expressions with evaluate requests,
+// fails fail statements and waits on wait statements
+func testRemoteHandler() jsonrpc2.Handler {
+	return jsonrpc2.AsyncHandler(jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) (interface{}, error) {
+		var params ExecuteParams
+
+		if err := json.Unmarshal(*request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		switch params.Command {
+		case "compute":
+			var value interface{}
+
+			if err := conn.Call(ctx, "evaluate", EvaluateParams{Execution: params.Execution, Expression: fmt.Sprint(params.Parameters["expression"])}, &value); err != nil {
+				return nil, err
+			}
+
+			return ExecuteResult{Vars: map[string]interface{}{fmt.Sprint(params.Parameters["name"]): value}}, nil
+		case "fail":
+			return nil, fmt.Errorf("%v", params.Parameters["message"])
+		case "wait":
+			time.Sleep(time.Duration(params.Parameters["milliseconds"].(float64)) * time.Millisecond)
+
+			return ExecuteResult{}, nil
+		default:
+			data, _ := json.Marshal(params.Parameters)
+
+			return ExecuteResult{Output: params.Command + " " + string(data) + "\n", Skip: params.Parameters["name"] == "skipped"}, nil
+		}
+	}))
+}
+
+// runTestRemoteProcess serves the remote implementer over stdio
+func runTestRemoteProcess() {
+	var conn = jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(processStream{os.Stdin, os.Stdout}, jsonrpc2.VSCodeObjectCodec{}), testRemoteHandler())
+
+	<-conn.DisconnectNotify()
+}
+
+func newTestRemote(t *testing.T, v vm.VirtualMachine, options RemoteOptions) *Remote {
+	serverConn, clientConn := net.Pipe()
+
+	var conn = jsonrpc2.NewConn(context.Background(), jsonrpc2.NewBufferedStream(serverConn, jsonrpc2.VSCodeObjectCodec{}), testRemoteHandler())
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return NewRemote(v, jsonrpc2.NewBufferedStream(clientConn, jsonrpc2.VSCodeObjectCodec{}), options)
+}
+
+func executeLamp(t *testing.T, v vm.VirtualMachine, implementer vm.Implementer, actions string) error {
+	definitions, err := v.LoadLogiContent("lamp l1 {\n    actions {\n        " + actions + "\n    }\n}")
+
+	if !assert.NoError(t, err) {
+		return err
+	}
+
+	return v.Execute(&definitions[0], implementer)
+}
+
+func TestRemote(t *testing.T) {
+	tests := map[string]struct {
+		actions        string
+		timeout        time.Duration
+		expectedOutput string
+		expectedError  string
+	}{
+		"statements": {
+			actions:        "on(led1)\n        print(3)",
+			expectedOutput: "actions {}\non {\"component\":\"led1\"}\nprint {\"value\":3}\n",
+		},
+		"evaluate": {
+			actions:        "compute(\"temperature\", \"10 * 2\")\n        compute(\"doubled\", \"temperature * 2\")\n        print(doubled + 1)",
+			expectedOutput: "actions {}\nprint {\"value\":41}\n",
+		},
+		"sub statements": {
+			actions:        "group(\"pair\") {\n            on(led1)\n        }\n        group(\"skipped\") {\n            on(led2)\n        }",
+			expectedOutput: "actions {}\ngroup {\"name\":\"pair\"}\non {\"component\":\"led1\"}\ngroup {\"name\":\"skipped\"}\n",
+		},
+		"error": {
+			actions:        "fail(\"broken\")\n        on(led1)",
+			expectedOutput: "actions {}\n",
+			expectedError:  "broken",
+		},
+		"evaluate error": {
+			actions:        "compute(\"x\", \"unknown + 1\")",
+			expectedOutput: "actions {}\n",
+			expectedError:  "variable unknown not found",
+		},
+		"timeout": {
+			actions:        "wait(500)\n        on(led1)",
+			timeout:        50 * time.Millisecond,
+			expectedOutput: "actions {}\n",
+			expectedError:  "remote implementer timed out: wait after 50ms",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var output strings.Builder
+			var v = vm.New()
+
+			assert.NoError(t, v.LoadMacroContent(lampLgm))
+
+			var remote = newTestRemote(t, v, RemoteOptions{Output: &output, Timeout: tt.timeout})
+
+			var err = executeLamp(t, v, remote, tt.actions)
+
+			assert.NoError(t, remote.Close())
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedOutput, output.String())
+		})
+	}
+}
+
+func TestRemoteTransports(t *testing.T) {
+	var upgrader websocket.Upgrader
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		socket, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		<-jsonrpc2.NewConn(r.Context(), wsjsonrpc2.NewObjectStream(socket), testRemoteHandler()).DisconnectNotify()
+	}))
+
+	defer server.Close()
+
+	tests := map[string]func(v vm.VirtualMachine, options RemoteOptions) (*Remote, error){
+		"websocket": func(v vm.VirtualMachine, options RemoteOptions) (*Remote, error) {
+			return DialRemoteWebsocket(v, "ws"+strings.TrimPrefix(server.URL, "http"), options)
+		},
+		"process": func(v vm.VirtualMachine, options RemoteOptions) (*Remote, error) {
+			t.Setenv("LOGI_IMPLEMENTER_PROCESS", "rpc")
+
+			return StartRemote(v, options, os.Args[0])
+		},
+	}
+
+	for name, dial := range tests {
+		t.Run(name, func(t *testing.T) {
+			var output strings.Builder
+			var v = vm.New()
+
+			assert.NoError(t, v.LoadMacroContent(lampLgm))
+
+			remote, err := dial(v, RemoteOptions{Output: &output})
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.NoError(t, executeLamp(t, v, remote, "compute(\"x\", \"20 + 1\")\n        print(x)"))
+			assert.NoError(t, remote.Close())
+			assert.Equal(t, "actions {}\nprint {\"value\":21}\n", output.String())
+		})
+	}
+}
+
+func TestRemoteVarKinds(t *testing.T) {
+	tests := map[string]struct {
+		data     string
+		decode   func(data []byte) (map[string]interface{}, error)
+		expected map[string]common.Value
+	}{
+		"execute result": {
+			data: `{"vars": {"i": 1, "f": 2.0}}`,
+			decode: func(data []byte) (map[string]interface{}, error) {
+				var result ExecuteResult
+				var err = json.Unmarshal(data, &result)
+
+				return result.Vars, err
+			},
+			expected: map[string]common.Value{"i": common.IntegerValue(1), "f": common.FloatValue(2)},
+		},
+		"evaluate params": {
+			data: `{"expression": "i", "vars": {"i": 1, "f": 2.0}}`,
+			decode: func(data []byte) (map[string]interface{}, error) {
+				var params EvaluateParams
+				var err = json.Unmarshal(data, &params)
+
+				return params.Vars, err
+			},
+			expected: map[string]common.Value{"i": common.IntegerValue(1), "f": common.FloatValue(2)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			vars, err := tt.decode([]byte(tt.data))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			values, err := common.ValuesOf(vars)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, values)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package logi
 
-import "fmt"
+import (
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+)
 
 type Error struct {
 	Line   int
@@ -21,3 +24,18 @@ func newError(line, column int, at, msg string) *Error {
 		Msg:    msg,
 	}
 }
+
+// SourceError is an error of a definition or statement which is syntactically valid but does not match its macro.
+// Location is only known if source map is enabled.
+type SourceError struct {
+	Location common.SourceLocation
+	Err      error
+}
+
+func (e *SourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,28 @@
+package logi
+
+import (
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	"github.com/tislib/logi/pkg/ast/plain"
+	"strings"
+)
+
+// ParseExpression parses an expression, e.g. `x * 2 + f(y)`, it is parsed as the parameter of a statement
+func ParseExpression(input string) (common.Expression, error) {
+	// parameter lists can not have new lines
+	var expression = strings.Join(strings.Fields(input), " ")
+
+	ast, err := ParsePlainContent("expression e {\n    eval("+expression+")\n}", false)
+
+	if err != nil {
+		return common.Expression{}, fmt.Errorf("invalid expression: %v", err)
+	}
+
+	var elements = ast.Definitions[0].Statements[0].Elements
+
+	if len(elements) != 2 || elements[1].Kind != plain.DefinitionStatementElementKindParameterList || len(elements[1].ParameterList.Parameters) != 1 {
+		return common.Expression{}, fmt.Errorf("invalid expression: %s", input)
+	}
+
+	return elements[1].ParameterList.Parameters[0], nil
+}
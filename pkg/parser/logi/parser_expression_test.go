@@ -0,0 +1,52 @@
+package logi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	tests := map[string]struct {
+		input         string
+		expectedKind  common.ExpressionKind
+		expectedError string
+	}{
+		"literal": {
+			input:        "'logi'",
+			expectedKind: common.LiteralKind,
+		},
+		"variable": {
+			input:        "x",
+			expectedKind: common.VariableKind,
+		},
+		"binary expression on multiple lines": {
+			input:        "x *\n 2",
+			expectedKind: common.BinaryExprKind,
+		},
+		"function call": {
+			input:        "status('led', 1)",
+			expectedKind: common.FuncCallKind,
+		},
+		"invalid": {
+			input:         "1 +",
+			expectedError: "invalid expression",
+		},
+		"multiple expressions": {
+			input:         "1, 2",
+			expectedError: "invalid expression: 1, 2",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			expression, err := ParseExpression(tt.input)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedKind, expression.Kind)
+			}
+		})
+	}
+}
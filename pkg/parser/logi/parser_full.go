@@ -42,6 +42,11 @@ func Parse(logiInput string, macros []macroAst.Macro, enableSourceMap bool) (*lo
 	return ast, err
 }
 
+// ParsePlainAst matches definitions of an already parsed plain ast with the macros
+func ParsePlainAst(plainAst plain.Ast, macros []macroAst.Macro) (*logi.Ast, error) {
+	return prepareAst(plainAst, macroAst.Ast{Macros: macros})
+}
+
 func prepareAst(plainAst plain.Ast, macroAst macroAst.Ast) (*logi.Ast, error) {
 	var result = new(logi.Ast)
 
@@ -50,7 +55,7 @@ func prepareAst(plainAst plain.Ast, macroAst macroAst.Ast) (*logi.Ast, error) {
 		macroDefinition, err := locateMacroDefinition(plainDefinition, macroAst)
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to locate macro definition: %w", err)
+			return nil, &SourceError{Location: plainDefinition.MacroNameSourceLocation, Err: fmt.Errorf("failed to locate macro definition: %w", err)}
 		}
 
 		definition, err := prepareDefinition(plainDefinition, macroDefinition)
@@ -82,7 +87,7 @@ func prepareDefinition(plainDefinition plain.Definition, macroDefinition *macroA
 		err := rsp.parse("")
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse statement: %w", err)
+			return nil, &SourceError{Location: plainStatement.SourceLocation, Err: fmt.Errorf("failed to parse statement: %w", err)}
 		}
 
 		definition.Statements = append(definition.Statements, rsp.statement)
@@ -0,0 +1,720 @@
+package logi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/ast/plain"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const printerIndent = "    "
+
+// maxRenderBranches limits the number of candidate renderings generated for a single statement,
+// combinations and optional elements multiply the number of candidates.
+const maxRenderBranches = 64
+
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Print regenerates logi source of the definition.
+// Statements which still match the plain statement at the same position of the definition's PlainStatements are printed
+// from the plain statement, so unchanged statements keep their original form. Other statements are generated from the macro syntax.
+// Every printed statement is parsed back and compared with the original one, so Parse(Print(def)) results in def.
+func Print(definition logiAst.Definition, macroDefinition macroAst.Macro) (string, error) {
+	p := newPrinter(&macroDefinition)
+
+	var sb strings.Builder
+
+	sb.WriteString(definition.MacroName + " " + definition.Name + " {\n")
+
+	for idx, statement := range definition.Statements {
+		var original *plain.DefinitionStatement
+
+		if idx < len(definition.PlainStatements) {
+			original = &definition.PlainStatements[idx]
+		}
+
+		text, err := p.printStatement(statement, original, 1)
+
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(printerIndent + text + "\n")
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String(), nil
+}
+
+// PrintStatement prints a single statement using the macro syntax of the statement scope.
+func PrintStatement(statement logiAst.Statement, macroDefinition macroAst.Macro) (string, error) {
+	p := newPrinter(&macroDefinition)
+
+	return p.printStatement(statement, nil, 0)
+}
+
+// PrintAst prints all definitions of the ast, macros are located by the macro name of the definitions.
+func PrintAst(ast logiAst.Ast, macros []macroAst.Macro) (string, error) {
+	var parts []string
+
+	for _, definition := range ast.Definitions {
+		macroDefinition, err := locateMacroDefinition(plain.Definition{MacroName: definition.MacroName}, macroAst.Ast{Macros: macros})
+
+		if err != nil {
+			return "", fmt.Errorf("failed to locate macro definition: %w", err)
+		}
+
+		text, err := Print(definition, *macroDefinition)
+
+		if err != nil {
+			return "", fmt.Errorf("failed to print definition %s: %w", definition.Name, err)
+		}
+
+		parts = append(parts, text)
+	}
+
+	return strings.Join(parts, "\n"), nil
+}
+
+type printer struct {
+	macroDefinition *macroAst.Macro
+	printed         map[string]string
+}
+
+func newPrinter(macroDefinition *macroAst.Macro) *printer {
+	return &printer{
+		macroDefinition: macroDefinition,
+		printed:         make(map[string]string),
+	}
+}
+
+// printStatement prints the statement, original is the plain statement at the same position of the source if there is one.
+func (p *printer) printStatement(statement logiAst.Statement, original *plain.DefinitionStatement, indent int) (string, error) {
+	// nested statements are printed once per candidate syntax statement of their parents, so results are cached
+	data, err := json.Marshal(statement)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to print statement: %w", err)
+	}
+
+	var key = fmt.Sprintf("%d:%p:%s", indent, original, data)
+
+	if text, ok := p.printed[key]; ok {
+		return text, nil
+	}
+
+	text, err := p.doPrintStatement(statement, original, indent)
+
+	if err != nil {
+		return "", err
+	}
+
+	p.printed[key] = text
+
+	return text, nil
+}
+
+func (p *printer) doPrintStatement(statement logiAst.Statement, original *plain.DefinitionStatement, indent int) (string, error) {
+	// try to reuse the original plain statement first
+	if original != nil {
+		matched, err := matchPlainStatement(*original, p.macroDefinition, statement.Scope)
+
+		if err == nil && statementEquals(statement, *matched) {
+			return printPlainStatement(*original, indent), nil
+		}
+	}
+
+	syntaxStatements, err := p.scopeSyntaxStatements(statement.Scope)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, syntaxStatement := range syntaxStatements {
+		r := &statementRenderer{
+			printer:   p,
+			statement: statement,
+			original:  original,
+			indent:    indent,
+		}
+
+		branches := r.renderElements([]renderBranch{{}}, syntaxStatement.Elements)
+
+		if r.err != nil {
+			return "", r.err
+		}
+
+		for _, branch := range branches {
+			if branch.subIndex != len(statement.SubStatements) {
+				continue
+			}
+
+			text := strings.Join(branch.parts, " ")
+
+			matched, err := p.matchSource(text, statement.Scope)
+
+			if err == nil && statementEquals(statement, *matched) {
+				return text, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("failed to print statement %s: no syntax statement of scope %q matches it", describeStatement(statement), statement.Scope)
+}
+
+// matchSource parses the given statement source and matches it against the macro syntax of the scope.
+func (p *printer) matchSource(text string, scope string) (*logiAst.Statement, error) {
+	plainAst, err := ParsePlainContent(p.macroDefinition.Name+" printed {\n"+text+"\n}\n", false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plainAst.Definitions) != 1 || len(plainAst.Definitions[0].Statements) != 1 {
+		return nil, fmt.Errorf("printed statement is not a single statement")
+	}
+
+	return matchPlainStatement(plainAst.Definitions[0].Statements[0], p.macroDefinition, scope)
+}
+
+func (p *printer) scopeSyntaxStatements(scope string) ([]macroAst.SyntaxStatement, error) {
+	if scope == "" {
+		return p.macroDefinition.Syntax.Statements, nil
+	}
+
+	for _, item := range p.macroDefinition.Scopes.Scopes {
+		if item.Name == scope {
+			return item.Statements, nil
+		}
+	}
+
+	return nil, fmt.Errorf("scope %s not found", scope)
+}
+
+func (p *printer) lookupType(name string) *macroAst.TypeStatement {
+	for _, typeStatement := range p.macroDefinition.Types.Types {
+		if typeStatement.Name == name {
+			return &typeStatement
+		}
+	}
+
+	return nil
+}
+
+// matchPlainStatement matches a single plain statement against the macro syntax of the given scope.
+func matchPlainStatement(plainStatement plain.DefinitionStatement, macroDefinition *macroAst.Macro, scope string) (*logiAst.Statement, error) {
+	var target = macroDefinition
+
+	if scope != "" {
+		target = nil
+
+		for _, item := range macroDefinition.Scopes.Scopes {
+			if item.Name == scope {
+				target = &macroAst.Macro{
+					Types: macroDefinition.Types,
+					Syntax: macroAst.Syntax{
+						Statements: item.Statements,
+					},
+					Scopes: macroDefinition.Scopes,
+				}
+			}
+		}
+
+		if target == nil {
+			return nil, fmt.Errorf("scope %s not found", scope)
+		}
+	}
+
+	rsp := recursiveStatementParser{
+		plainStatement:  plainStatement,
+		macroDefinition: target,
+	}
+
+	if err := rsp.parse(scope); err != nil {
+		return nil, err
+	}
+
+	return &rsp.statement, nil
+}
+
+// statementEquals compares statements by their json representation.
+// Parameter expressions are only compared if the expected statement has them.
+func statementEquals(expected logiAst.Statement, actual logiAst.Statement) bool {
+	actual, ok := alignExpressions(expected, actual)
+
+	if !ok {
+		return false
+	}
+
+	expectedJson, err := json.Marshal(expected)
+
+	if err != nil {
+		return false
+	}
+
+	actualJson, err := json.Marshal(actual)
+
+	if err != nil {
+		return false
+	}
+
+	return string(expectedJson) == string(actualJson)
+}
+
+func alignExpressions(expected logiAst.Statement, actual logiAst.Statement) (logiAst.Statement, bool) {
+	if len(expected.Parameters) != len(actual.Parameters) || len(expected.SubStatements) != len(actual.SubStatements) {
+		return actual, false
+	}
+
+	if actual.Parameters != nil {
+		var parameters = make([]logiAst.Parameter, len(actual.Parameters))
+		for i, parameter := range actual.Parameters {
+			if expected.Parameters[i].Expression == nil {
+				parameter.Expression = nil
+			}
+			parameters[i] = parameter
+		}
+		actual.Parameters = parameters
+	}
+
+	if actual.SubStatements != nil {
+		var subStatements = make([][]logiAst.Statement, len(actual.SubStatements))
+		for i, items := range actual.SubStatements {
+			if len(items) != len(expected.SubStatements[i]) {
+				return actual, false
+			}
+
+			subStatements[i] = items
+
+			if items != nil {
+				subStatements[i] = make([]logiAst.Statement, len(items))
+			}
+
+			for j, item := range items {
+				aligned, ok := alignExpressions(expected.SubStatements[i][j], item)
+
+				if !ok {
+					return actual, false
+				}
+
+				subStatements[i][j] = aligned
+			}
+		}
+		actual.SubStatements = subStatements
+	}
+
+	return actual, true
+}
+
+func describeStatement(statement logiAst.Statement) string {
+	if statement.Command != "" {
+		return statement.Command
+	}
+
+	var names []string
+	for _, parameter := range statement.Parameters {
+		names = append(names, parameter.Name)
+	}
+
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+type renderBranch struct {
+	parts    []string
+	subIndex int
+	// scopeIndex counts the rendered scopes, it locates the original plain statements of the scope
+	scopeIndex int
+}
+
+func (b renderBranch) with(part string) renderBranch {
+	var parts = make([]string, len(b.parts), len(b.parts)+1)
+	copy(parts, b.parts)
+
+	return renderBranch{parts: append(parts, part), subIndex: b.subIndex, scopeIndex: b.scopeIndex}
+}
+
+// statementRenderer renders a statement using a syntax statement of the macro.
+// As combinations and optional elements can be rendered in multiple ways, it generates all possible candidates.
+type statementRenderer struct {
+	printer   *printer
+	statement logiAst.Statement
+	original  *plain.DefinitionStatement
+	indent    int
+	err       error
+}
+
+func (r *statementRenderer) renderElements(branches []renderBranch, elements []macroAst.SyntaxStatementElement) []renderBranch {
+	for _, element := range elements {
+		var next []renderBranch
+
+		for _, branch := range branches {
+			next = append(next, r.renderElement(branch, element)...)
+		}
+
+		if len(next) > maxRenderBranches {
+			next = next[:maxRenderBranches]
+		}
+
+		branches = next
+	}
+
+	return branches
+}
+
+func (r *statementRenderer) renderElement(branch renderBranch, element macroAst.SyntaxStatementElement) []renderBranch {
+	switch element.Kind {
+	case macroAst.SyntaxStatementElementKindKeyword:
+		return []renderBranch{branch.with(element.KeywordDef.Name)}
+	case macroAst.SyntaxStatementElementKindSymbol:
+		return []renderBranch{branch.with(element.SymbolDef.Name)}
+	case macroAst.SyntaxStatementElementKindTypeReference:
+		typeStatement := r.printer.lookupType(element.TypeReference.Name)
+
+		if typeStatement == nil {
+			return []renderBranch{branch}
+		}
+
+		return r.renderElements([]renderBranch{branch}, typeStatement.Elements)
+	case macroAst.SyntaxStatementElementKindVariableKeyword:
+		return r.renderVariableKeyword(branch, element.VariableKeyword)
+	case macroAst.SyntaxStatementElementKindParameterList:
+		return r.renderParameterList(branch, element.ParameterList)
+	case macroAst.SyntaxStatementElementKindArgumentList:
+		if len(r.statement.Arguments) == 0 {
+			return []renderBranch{branch.with("()")}
+		}
+
+		var arguments []string
+		for _, argument := range r.statement.Arguments {
+			arguments = append(arguments, argument.Name+" "+argument.Type.ToDisplayName())
+		}
+
+		return []renderBranch{branch.with("((" + strings.Join(arguments, ", ") + "))")}
+	case macroAst.SyntaxStatementElementKindAttributeList:
+		if len(r.statement.Attributes) == 0 {
+			return []renderBranch{branch, branch.with("[]")}
+		}
+
+		var attributes []string
+		for _, attribute := range r.statement.Attributes {
+			if attribute.Value != nil {
+				attributes = append(attributes, attribute.Name+" "+printValue(*attribute.Value))
+			} else {
+				attributes = append(attributes, attribute.Name)
+			}
+		}
+
+		return []renderBranch{branch.with("[" + strings.Join(attributes, ", ") + "]")}
+	case macroAst.SyntaxStatementElementKindCombination:
+		var result []renderBranch
+
+		for _, item := range element.Combination.Elements {
+			result = append(result, r.renderElement(branch, item)...)
+		}
+
+		return result
+	case macroAst.SyntaxStatementElementKindScope:
+		if branch.subIndex >= len(r.statement.SubStatements) {
+			return nil
+		}
+
+		var originals = r.originalScope(branch.scopeIndex)
+		var sb strings.Builder
+		sb.WriteString("{\n")
+
+		for idx, subStatement := range r.statement.SubStatements[branch.subIndex] {
+			var original *plain.DefinitionStatement
+
+			if idx < len(originals) {
+				original = &originals[idx]
+			}
+
+			text, err := r.printer.printStatement(subStatement, original, r.indent+1)
+
+			if err != nil {
+				r.err = err
+				return nil
+			}
+
+			sb.WriteString(strings.Repeat(printerIndent, r.indent+1) + text + "\n")
+		}
+
+		sb.WriteString(strings.Repeat(printerIndent, r.indent) + "}")
+
+		next := branch.with(sb.String())
+		next.subIndex++
+		next.scopeIndex++
+
+		return []renderBranch{next}
+	}
+
+	return nil
+}
+
+// originalScope returns the plain statements of the scope at the given index in the original statement.
+func (r *statementRenderer) originalScope(scopeIndex int) []plain.DefinitionStatement {
+	if r.original == nil {
+		return nil
+	}
+
+	for _, element := range r.original.Elements {
+		if element.Kind != plain.DefinitionStatementElementKindStruct {
+			continue
+		}
+
+		if scopeIndex == 0 {
+			return element.Struct.Statements
+		}
+
+		scopeIndex--
+	}
+
+	return nil
+}
+
+func (r *statementRenderer) renderVariableKeyword(branch renderBranch, variableKeyword *macroAst.SyntaxStatementElementVariableKeyword) []renderBranch {
+	if variableKeyword.Type.Name == "array" && len(variableKeyword.Type.SubTypes) > 0 {
+		if branch.subIndex >= len(r.statement.SubStatements) {
+			return nil
+		}
+
+		itemElement := macroAst.SyntaxStatementElement{
+			Kind: macroAst.SyntaxStatementElementKindVariableKeyword,
+			VariableKeyword: &macroAst.SyntaxStatementElementVariableKeyword{
+				Name: variableKeyword.Name,
+				Type: variableKeyword.Type.SubTypes[0],
+			},
+		}
+
+		var items []string
+		for _, item := range r.statement.SubStatements[branch.subIndex] {
+			itemRenderer := &statementRenderer{printer: r.printer, statement: item, indent: r.indent}
+			itemBranches := itemRenderer.renderElement(renderBranch{}, itemElement)
+
+			if len(itemBranches) == 0 {
+				return nil
+			}
+
+			items = append(items, strings.Join(itemBranches[0].parts, " "))
+		}
+
+		next := branch.with("[" + strings.Join(items, ", ") + "]")
+		next.subIndex++
+
+		return []renderBranch{next}
+	}
+
+	if typeStatement := r.printer.lookupType(variableKeyword.Type.Name); typeStatement != nil {
+		return r.renderElements([]renderBranch{branch}, typeStatement.Elements)
+	}
+
+	parameter, found := r.findParameter(variableKeyword.Name)
+
+	if !found {
+		return nil
+	}
+
+	if parameter.Value.Kind == common.ValueKindString && (variableKeyword.Type.Name == "Name" || variableKeyword.Type.Name == "Type") {
+		if !identifierPattern.MatchString(parameter.Value.AsString()) {
+			return nil
+		}
+
+		return []renderBranch{branch.with(parameter.Value.AsString())}
+	}
+
+	return []renderBranch{branch.with(printValue(parameter.Value))}
+}
+
+func (r *statementRenderer) renderParameterList(branch renderBranch, parameterList *macroAst.SyntaxStatementElementParameterList) []renderBranch {
+	var items []string
+
+	if parameterList.Dynamic {
+		for _, parameter := range r.statement.Parameters {
+			items = append(items, parameter.Name+": "+printParameter(parameter))
+		}
+	} else {
+		for _, syntaxParameter := range parameterList.Parameters {
+			parameter, found := r.findParameter(syntaxParameter.Name)
+
+			if !found {
+				break
+			}
+
+			items = append(items, printParameter(parameter))
+		}
+	}
+
+	return []renderBranch{branch.with("(" + strings.Join(items, ", ") + ")")}
+}
+
+func (r *statementRenderer) findParameter(name string) (logiAst.Parameter, bool) {
+	for _, parameter := range r.statement.Parameters {
+		if parameter.Name == name {
+			return parameter, true
+		}
+	}
+
+	return logiAst.Parameter{}, false
+}
+
+func printParameter(parameter logiAst.Parameter) string {
+	if parameter.Expression != nil {
+		return printExpression(*parameter.Expression)
+	}
+
+	return printValue(parameter.Value)
+}
+
+func printPlainStatement(statement plain.DefinitionStatement, indent int) string {
+	var parts []string
+
+	for _, element := range statement.Elements {
+		parts = append(parts, printPlainElement(element, indent))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func printPlainElement(element plain.DefinitionStatementElement, indent int) string {
+	switch element.Kind {
+	case plain.DefinitionStatementElementKindIdentifier:
+		return element.Identifier.Identifier
+	case plain.DefinitionStatementElementKindSymbol:
+		return element.Symbol.Symbol
+	case plain.DefinitionStatementElementKindValue:
+		return printValue(element.Value.Value)
+	case plain.DefinitionStatementElementKindArray:
+		var items []string
+		for _, item := range element.Array.Items {
+			items = append(items, printPlainStatement(item, indent))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+	case plain.DefinitionStatementElementKindStruct:
+		var sb strings.Builder
+		sb.WriteString("{\n")
+
+		for _, statement := range element.Struct.Statements {
+			sb.WriteString(strings.Repeat(printerIndent, indent+1) + printPlainStatement(statement, indent+1) + "\n")
+		}
+
+		sb.WriteString(strings.Repeat(printerIndent, indent) + "}")
+
+		return sb.String()
+	case plain.DefinitionStatementElementKindArgumentList:
+		if len(element.ArgumentList.Arguments) == 0 {
+			return "()"
+		}
+
+		var arguments []string
+		for _, argument := range element.ArgumentList.Arguments {
+			arguments = append(arguments, argument.Name+" "+argument.Type.ToDisplayName())
+		}
+
+		return "((" + strings.Join(arguments, ", ") + "))"
+	case plain.DefinitionStatementElementKindParameterList:
+		var items []string
+		for idx, parameter := range element.ParameterList.Parameters {
+			if len(element.ParameterList.Names) > idx {
+				items = append(items, element.ParameterList.Names[idx]+": "+printExpression(parameter))
+			} else {
+				items = append(items, printExpression(parameter))
+			}
+		}
+
+		return "(" + strings.Join(items, ", ") + ")"
+	case plain.DefinitionStatementElementKindExpression:
+		return printExpression(*element.Expression)
+	}
+
+	return ""
+}
+
+func printExpression(expression common.Expression) string {
+	switch expression.Kind {
+	case common.LiteralKind:
+		return printValue(expression.Literal.Value)
+	case common.VariableKind:
+		return expression.Variable.Name
+	case common.BinaryExprKind:
+		return printExpression(*expression.BinaryExpr.Left) + " " + printOperator(expression.BinaryExpr.Operator) + " " + printExpression(*expression.BinaryExpr.Right)
+	}
+
+	if expression.FuncCall != nil {
+		var arguments []string
+		for _, argument := range expression.FuncCall.Arguments {
+			arguments = append(arguments, printExpression(*argument))
+		}
+
+		return expression.FuncCall.Name + "(" + strings.Join(arguments, ", ") + ")"
+	}
+
+	return ""
+}
+
+// printOperator converts operator back to its source form, logical operators are written with a single symbol.
+func printOperator(operator string) string {
+	switch operator {
+	case "&&":
+		return "&"
+	case "||":
+		return "|"
+	}
+
+	return operator
+}
+
+func printValue(value common.Value) string {
+	switch value.Kind {
+	case common.ValueKindString:
+		return printString(value.AsString())
+	case common.ValueKindBoolean:
+		return strconv.FormatBool(value.AsBoolean())
+	case common.ValueKindInteger:
+		return strconv.FormatInt(value.AsInteger(), 10)
+	case common.ValueKindFloat:
+		var result = strconv.FormatFloat(value.AsFloat(), 'f', -1, 64)
+
+		if !strings.Contains(result, ".") {
+			result += ".0"
+		}
+
+		return result
+	case common.ValueKindArray:
+		var items []string
+		for _, item := range value.AsArray() {
+			items = append(items, printValue(item))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+	case common.ValueKindMap:
+		data, err := json.Marshal(value.AsInterface())
+
+		if err != nil {
+			return "{}"
+		}
+
+		return string(data)
+	default:
+		return "null"
+	}
+}
+
+// printString quotes the string with a quote character which is not used inside it, as strings have no escaping.
+func printString(s string) string {
+	if !strings.Contains(s, "\"") && !strings.Contains(s, "\n") {
+		return "\"" + s + "\""
+	}
+
+	if !strings.Contains(s, "'") && !strings.Contains(s, "\n") {
+		return "'" + s + "'"
+	}
+
+	return "`" + s + "`"
+}
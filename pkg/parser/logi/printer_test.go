@@ -0,0 +1,297 @@
+package logi
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"strings"
+	"testing"
+)
+
+const printerTestRoleMacro = `
+macro role {
+    kind Syntax
+
+    syntax {
+        description <description string>
+        permissions { permissions | conditionalPermissions}
+    }
+
+    scopes {
+        permissions {
+            WRITE <object Name>
+            DENY_WRITE <object Name>
+            WRITE <object Name> properties <properties array<Name>>
+            READ <object Name>
+            CRUD <object Name> [owner bool]
+        }
+
+        conditionalPermissions {
+            when (<condition bool>) { permissions }
+        }
+    }
+}
+`
+
+const printerTestRoleInput = `
+role Role1 {
+    description "Role1 description"
+
+    permissions {
+        WRITE object1
+        READ object2
+        CRUD object4 [owner]
+        WRITE object1 properties [prop1, prop2]
+
+        when (isHoliday()) {
+            WRITE object1
+        }
+    }
+}
+`
+
+func TestPrintRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		macroInput string
+		input      string
+	}{
+		"entity with attributes": {
+			macroInput: `
+				macro entity {
+					kind Syntax
+
+					syntax {
+						<propertyName Name> <propertyType Type> [primary bool, autoincrement bool, required bool, default string]
+					}
+				}
+`,
+			input: `
+				entity User {
+					id int [primary, autoincrement]
+					name string [required, default "John Doe"]
+					description string
+				}
+			`,
+		},
+		"combination": {
+			macroInput: `
+				macro entity {
+					kind Syntax
+
+					types {
+						ParamType1 <value1 string> <value2 string>
+						ParamType2 <value3 int> <value4 int>
+					}
+
+					syntax {
+						(Hello | World) <propertyName Name> (<value ParamType1> | <value ParamType2>)
+					}
+				}
+`,
+			input: `
+				entity User {
+					Hello param1 "11" "22"
+					World param2 1 2
+				}
+			`,
+		},
+		"role": {
+			macroInput: printerTestRoleMacro,
+			input:      printerTestRoleInput,
+		},
+		"backtest": {
+			macroInput: `
+				macro backtest {
+					kind Syntax
+
+					types {
+						Indicator <indicatorName Name> (<period int>) as <alias Name>
+					}
+
+					syntax {
+						InitialCapital <initialCapital int>
+						Ratio <ratio float>
+						Indicators <indicators array<Indicator>>
+						Strategy { strategy }
+					}
+
+					scopes {
+						strategy {
+							if (<condition bool>) { strategy }
+							Buy(<symbol string>, <quantity int>)
+						}
+					}
+				}`,
+			input: `
+				backtest VariableHoldUntil4 {
+					InitialCapital  10000
+					Ratio 1.5
+					Indicators       [sma(20) as sma20, sma(50) as sma50]
+
+					Strategy {
+						if (sma20 < sma50) {
+							Buy(quantity: 100, symbol: "SPY")
+						}
+					}
+				}`,
+		},
+		"interface": {
+			macroInput: `
+				macro interface {
+					kind Syntax
+
+					syntax {
+						<methodName Name> (...[<args Type<string>>]) <returnType Type>
+					}
+				}
+`,
+			input: `
+				interface UserService {
+					createUser ((name string, age int)) User
+				}
+			`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mAst, err := macro.ParseMacroContent(tt.macroInput, false)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			ast, err := Parse(tt.input, mAst.Macros, false)
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for _, definition := range ast.Definitions {
+				source, err := Print(definition, mAst.Macros[0])
+
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assertPrintedDefinition(t, definition, source, mAst.Macros)
+			}
+		})
+	}
+}
+
+func TestPrintModifiedDefinition(t *testing.T) {
+	mAst, err := macro.ParseMacroContent(printerTestRoleMacro, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ast, err := Parse(printerTestRoleInput, mAst.Macros, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var definition = ast.Definitions[0]
+
+	// add a new permission, without touching plain statements
+	definition.Statements[1].SubStatements[0] = append(definition.Statements[1].SubStatements[0], logiAst.Statement{
+		Scope:   "permissions",
+		Command: "DENY_WRITE",
+		Parameters: []logiAst.Parameter{
+			{Name: "object", Value: common.StringValue("object5")},
+		},
+	})
+
+	// modify description
+	definition.Statements[0].Parameters[0].Value = common.StringValue("Updated description")
+
+	source, err := Print(definition, mAst.Macros[0])
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Contains(t, source, `description "Updated description"`)
+	assert.Contains(t, source, "DENY_WRITE object5")
+	assert.Contains(t, source, "CRUD object4 [owner]")
+
+	assertPrintedDefinition(t, definition, source, mAst.Macros)
+}
+
+func TestPrintMovedStatements(t *testing.T) {
+	mAst, err := macro.ParseMacroContent(printerTestRoleMacro, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ast, err := Parse(printerTestRoleInput, mAst.Macros, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var definition = ast.Definitions[0]
+
+	// plain statements are matched by position, moved statements are printed from the macro syntax
+	var permissions = definition.Statements[1].SubStatements[0]
+	permissions[0], permissions[2] = permissions[2], permissions[0]
+
+	source, err := Print(definition, mAst.Macros[0])
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Less(t, strings.Index(source, "CRUD object4 [owner]"), strings.Index(source, "READ object2"))
+	assert.Less(t, strings.Index(source, "READ object2"), strings.Index(source, "WRITE object1\n"))
+
+	assertPrintedDefinition(t, definition, source, mAst.Macros)
+}
+
+func TestPrintInvalidStatement(t *testing.T) {
+	mAst, err := macro.ParseMacroContent(printerTestRoleMacro, false)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = Print(logiAst.Definition{
+		MacroName: "role",
+		Name:      "Role1",
+		Statements: []logiAst.Statement{
+			{
+				Command: "unknown",
+			},
+		},
+	}, mAst.Macros[0])
+
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "unknown"))
+}
+
+func assertPrintedDefinition(t *testing.T, definition logiAst.Definition, source string, macros []macroAst.Macro) {
+	reparsedAst, err := Parse(source, macros, false)
+
+	if !assert.NoErrorf(t, err, "printed source:\n%s", source) {
+		return
+	}
+
+	if !assert.Len(t, reparsedAst.Definitions, 1) {
+		return
+	}
+
+	var reparsed = reparsedAst.Definitions[0]
+
+	expectedJson, _ := json.MarshalIndent(definition.Statements, "", "  ")
+	gotJson, _ := json.MarshalIndent(reparsed.Statements, "", "  ")
+
+	assert.Equal(t, string(expectedJson), string(gotJson), "printed source:\n%s", source)
+	assert.Equal(t, definition.Name, reparsed.Name)
+	assert.Equal(t, definition.MacroName, reparsed.MacroName)
+}
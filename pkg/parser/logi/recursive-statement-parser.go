@@ -2,7 +2,6 @@ package logi
 
 import (
 	"fmt"
-	log "github.com/sirupsen/logrus"
 	"github.com/tislib/logi/pkg/ast/common"
 	logiAst "github.com/tislib/logi/pkg/ast/logi"
 	macroAst "github.com/tislib/logi/pkg/ast/macro"
@@ -94,8 +93,8 @@ func (p *recursiveStatementParser) match() {
 }
 
 func (p *recursiveStatementParser) matchNextElement(syntaxStatementElement macroAst.SyntaxStatementElement, currentElement plain.DefinitionStatementElement) {
-	log.Trace(fmt.Sprintf("matching %s with %s at: %s", syntaxStatementElement.Kind, currentElement.Kind, currentElement.SourceLocation))
-	log.Trace(fmt.Sprintf("Current element: %v", currentElement.AsValue().AsInterface()))
+	trace("matching %s with %s at: %s", syntaxStatementElement.Kind, currentElement.Kind, currentElement.SourceLocation)
+	trace("Current element: %v", currentElement.AsValue().AsInterface())
 
 	switch syntaxStatementElement.Kind {
 	case macroAst.SyntaxStatementElementKindKeyword:
@@ -0,0 +1,9 @@
+//go:build !js
+
+package logi
+
+import log "github.com/sirupsen/logrus"
+
+func trace(format string, args ...interface{}) {
+	log.Tracef(format, args...)
+}
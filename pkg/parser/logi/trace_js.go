@@ -0,0 +1,7 @@
+//go:build js
+
+package logi
+
+// trace is a no-op on js, logrus is not used there
+func trace(format string, args ...interface{}) {
+}
@@ -0,0 +1,380 @@
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"github.com/tislib/logi/pkg/vm"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const help = `Type a macro, a logi definition or an expression, inputs continue on next lines while braces are open.
+  name = expression   set a variable, expressions can use variables
+  :macros             list macros
+  :defs               list definitions
+  :vars               list variables
+  :load <files...>    load macro (.lgm) and logi (.lg) files
+  :reset              remove everything except macro files given at start
+  :history            list previous inputs
+  :help               show this help
+  :quit               exit`
+
+var errQuit = errors.New("quit")
+
+var definitionPattern = regexp.MustCompile(`^[A-Za-z_]\w*\s+[A-Za-z_]\w*\s*\{`)
+var assignmentPattern = regexp.MustCompile(`^([A-Za-z_]\w*)\s*=([^=][\s\S]*)$`)
+
+type Options struct {
+	// MacroFiles are loaded at start, and again on reset
+	MacroFiles []string
+
+	// HistoryFile keeps inputs between sessions, history is not saved if it is empty
+	HistoryFile string
+}
+
+// Repl evaluates macros, logi definitions and expressions typed by the user
+type Repl struct {
+	options Options
+	vm      vm.VirtualMachine
+	history []string
+}
+
+func New(options Options) (*Repl, error) {
+	var r = &Repl{options: options}
+
+	if err := r.reset(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadHistory(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Run reads inputs until the end of the input or :quit, and writes their results
+func (r *Repl) Run(in io.Reader, out io.Writer) error {
+	var scanner = bufio.NewScanner(in)
+	var input strings.Builder
+
+	_, _ = fmt.Fprint(out, "> ")
+
+	for scanner.Scan() {
+		input.WriteString(scanner.Text())
+		input.WriteString("\n")
+
+		if openBraces(input.String()) > 0 {
+			_, _ = fmt.Fprint(out, "... ")
+
+			continue
+		}
+
+		var text = strings.TrimSpace(input.String())
+		input.Reset()
+
+		if text != "" {
+			result, err := r.Eval(text)
+
+			if errors.Is(err, errQuit) {
+				return nil
+			} else if err != nil {
+				_, _ = fmt.Fprintf(out, "error: %v\n", err)
+			} else if result != "" {
+				_, _ = fmt.Fprintln(out, result)
+			}
+		}
+
+		_, _ = fmt.Fprint(out, "> ")
+	}
+
+	return scanner.Err()
+}
+
+// Eval evaluates a complete input, and returns its result
+func (r *Repl) Eval(input string) (string, error) {
+	input = strings.TrimSpace(input)
+
+	if strings.HasPrefix(input, ":") {
+		return r.command(input)
+	}
+
+	r.addHistory(input)
+
+	switch {
+	case strings.HasPrefix(input, "macro ") || strings.HasPrefix(input, "macro\t"):
+		return r.loadMacro(input)
+	case definitionPattern.MatchString(input):
+		return r.loadDefinition(input)
+	case assignmentPattern.MatchString(input):
+		var match = assignmentPattern.FindStringSubmatch(input)
+
+		value, err := r.evaluate(match[2])
+
+		if err != nil {
+			return "", err
+		}
+
+		r.vm.(vm.Extensible).Environment().Declare(match[1], value)
+
+		return match[1] + " = " + formatValue(value), nil
+	default:
+		value, err := r.evaluate(input)
+
+		if err != nil {
+			return "", err
+		}
+
+		return formatValue(value), nil
+	}
+}
+
+func (r *Repl) command(input string) (string, error) {
+	var fields = strings.Fields(input)
+
+	switch fields[0] {
+	case ":macros":
+		var names []string
+
+		for _, macro := range r.vm.GetMacros() {
+			names = append(names, macro.Name)
+		}
+
+		return strings.Join(names, "\n"), nil
+	case ":defs":
+		var names []string
+
+		for _, definition := range r.vm.GetDefinitions() {
+			names = append(names, fmt.Sprintf("%s %s", definition.MacroName, definition.Name))
+		}
+
+		return strings.Join(names, "\n"), nil
+	case ":vars":
+		var vars = r.vm.(vm.Extensible).Environment().Vars()
+		var names []string
+
+		for name := range vars {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for i, name := range names {
+			names[i] = name + " = " + formatValue(vars[name])
+		}
+
+		return strings.Join(names, "\n"), nil
+	case ":load":
+		if len(fields) == 1 {
+			return "", fmt.Errorf("usage: :load <files...>")
+		}
+
+		return r.load(fields[1:])
+	case ":reset":
+		return "", r.reset()
+	case ":history":
+		var lines []string
+
+		for i, entry := range r.history {
+			lines = append(lines, fmt.Sprintf("%d  %s", i+1, entry))
+		}
+
+		return strings.Join(lines, "\n"), nil
+	case ":help":
+		return help, nil
+	case ":quit", ":q":
+		return "", errQuit
+	default:
+		return "", fmt.Errorf("unknown command %s, see :help", fields[0])
+	}
+}
+
+// reset replaces the virtual machine with a new one, which has only the macro files of options
+func (r *Repl) reset() error {
+	var v = vm.New()
+
+	// inputs can be corrected by typing them again
+	v.(vm.Registry).SetDuplicatePolicy(vm.DuplicateOverride)
+
+	if err := v.LoadMacroFile(r.options.MacroFiles...); err != nil {
+		return err
+	}
+
+	r.vm = v
+
+	return nil
+}
+
+func (r *Repl) load(paths []string) (string, error) {
+	var result []string
+
+	for _, path := range paths {
+		if filepath.Ext(path) == ".lgm" {
+			if err := r.vm.LoadMacroFile(path); err != nil {
+				return "", err
+			}
+
+			result = append(result, "loaded "+path)
+
+			continue
+		}
+
+		definitions, err := r.vm.LoadLogiFile(path)
+
+		if err != nil {
+			return "", err
+		}
+
+		result = append(result, fmt.Sprintf("loaded %d definitions from %s", len(definitions), path))
+	}
+
+	return strings.Join(result, "\n"), nil
+}
+
+func (r *Repl) loadMacro(input string) (string, error) {
+	// names are taken from the input, overriding a macro keeps it at its existing index
+	ast, err := macro.ParseMacroContent(input, false)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.vm.LoadMacroContent(input); err != nil {
+		return "", err
+	}
+
+	var names []string
+
+	for _, m := range ast.Macros {
+		names = append(names, m.Name)
+	}
+
+	return "macro " + strings.Join(names, ", ") + " loaded", nil
+}
+
+// loadDefinition loads the definition, and returns its statements as they are matched by the macro
+func (r *Repl) loadDefinition(input string) (string, error) {
+	definitions, err := r.vm.LoadLogiContent(input)
+
+	if err != nil {
+		return "", err
+	}
+
+	for i := range definitions {
+		definitions[i].PlainStatements = nil
+	}
+
+	data, err := json.MarshalIndent(definitions, "", "  ")
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// evaluate evaluates the expression with variables of the session
+func (r *Repl) evaluate(input string) (common.Value, error) {
+	expression, err := logi.ParseExpression(input)
+
+	if err != nil {
+		return common.Value{}, err
+	}
+
+	return r.vm.Evaluate(expression, nil, nil)
+}
+
+func (r *Repl) loadHistory() error {
+	if r.options.HistoryFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.options.HistoryFile)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading history: %v", err)
+	}
+
+	// each line is an input as a json string, so inputs can have multiple lines
+	for _, line := range strings.Split(string(data), "\n") {
+		var entry string
+
+		if json.Unmarshal([]byte(line), &entry) == nil {
+			r.history = append(r.history, entry)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repl) addHistory(input string) {
+	r.history = append(r.history, input)
+
+	if r.options.HistoryFile == "" {
+		return
+	}
+
+	file, err := os.OpenFile(r.options.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return
+	}
+
+	defer file.Close()
+
+	data, _ := json.Marshal(input)
+	_, _ = file.Write(append(data, '\n'))
+}
+
+// openBraces returns the number of braces which are not closed, braces in strings and comments are ignored
+func openBraces(input string) int {
+	var depth = 0
+	var quote rune
+	var comment bool
+	var previous rune
+
+	for _, c := range input {
+		switch {
+		case comment:
+			comment = c != '\n'
+		case quote != 0:
+			// backtick strings are raw, so backslash does not escape them
+			if c == quote && (previous != '\\' || quote == '`') {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '/' && previous == '/':
+			comment = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+
+		previous = c
+	}
+
+	return depth
+}
+
+func formatValue(value common.Value) string {
+	data, err := json.Marshal(value.AsInterface())
+
+	if err != nil {
+		return value.ToDisplayName()
+	}
+
+	return string(data)
+}
@@ -0,0 +1,216 @@
+package repl
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const greeterLgm = `macro greeter {
+    kind Syntax
+
+    syntax {
+        greet <name string>
+    }
+}`
+
+func TestReplEval(t *testing.T) {
+	tests := map[string]struct {
+		inputs         []string
+		expectedOutput string
+		expectedError  string
+	}{
+		"expression": {
+			inputs:         []string{"1 + 2 * 3"},
+			expectedOutput: "7",
+		},
+		"variables": {
+			inputs:         []string{"x = 2", "y = x * 10", "y == x * 10"},
+			expectedOutput: "true",
+		},
+		"list variables": {
+			inputs:         []string{"x = 2", "name = 'logi'", ":vars"},
+			expectedOutput: "name = \"logi\"\nx = 2",
+		},
+		"unknown variable": {
+			inputs:        []string{"x + 1"},
+			expectedError: "variable x not found",
+		},
+		"invalid expression": {
+			inputs:        []string{"1 +"},
+			expectedError: "invalid expression",
+		},
+		"macro": {
+			inputs:         []string{greeterLgm},
+			expectedOutput: "macro greeter loaded",
+		},
+		"macro redefined": {
+			inputs:         []string{greeterLgm, greeterLgm},
+			expectedOutput: "macro greeter loaded",
+		},
+		"list macros": {
+			inputs:         []string{greeterLgm, ":macros"},
+			expectedOutput: "greeter",
+		},
+		"list definitions": {
+			inputs:         []string{greeterLgm, "greeter hello {\n    greet \"world\"\n}", ":defs"},
+			expectedOutput: "greeter hello",
+		},
+		"definition without macro": {
+			inputs:        []string{"greeter hello {\n    greet \"world\"\n}"},
+			expectedError: "greeter",
+		},
+		"reset": {
+			inputs:         []string{greeterLgm, "x = 1", ":reset", ":macros"},
+			expectedOutput: "",
+		},
+		"history": {
+			inputs:         []string{"x = 1", "x + 1", ":history"},
+			expectedOutput: "1  x = 1\n2  x + 1",
+		},
+		"unknown command": {
+			inputs:        []string{":unknown"},
+			expectedError: "unknown command :unknown",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := New(Options{})
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var output string
+
+			for _, input := range tt.inputs {
+				output, err = r.Eval(input)
+			}
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedOutput, output)
+			}
+		})
+	}
+}
+
+func TestReplDefinition(t *testing.T) {
+	r, err := New(Options{})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = r.Eval(greeterLgm)
+	assert.NoError(t, err)
+
+	output, err := r.Eval("greeter hello {\n    greet \"world\"\n}")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var definitions []logiAst.Definition
+
+	if assert.NoError(t, json.Unmarshal([]byte(output), &definitions)) && assert.Len(t, definitions, 1) {
+		assert.Equal(t, "hello", definitions[0].Name)
+		assert.Equal(t, "greet", definitions[0].Statements[0].Command)
+		assert.Equal(t, "world", definitions[0].Statements[0].GetParameter("name").AsString())
+		assert.Empty(t, definitions[0].PlainStatements)
+	}
+}
+
+func TestReplRun(t *testing.T) {
+	var dir = t.TempDir()
+	var macroFile = filepath.Join(dir, "greeter.lgm")
+	var historyFile = filepath.Join(dir, "history")
+
+	assert.NoError(t, os.WriteFile(macroFile, []byte(greeterLgm), 0644))
+
+	r, err := New(Options{MacroFiles: []string{macroFile}, HistoryFile: historyFile})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var input = strings.Join([]string{
+		"macro greeter2 {",
+		"    kind Syntax",
+		"    // { is ignored in comments",
+		"    syntax {",
+		"        greet <name string>",
+		"    }",
+		"}",
+		"greeter hello {",
+		"    greet \"}\"",
+		"}",
+		":defs",
+		"1 +",
+		":quit",
+		"2",
+	}, "\n")
+
+	var out strings.Builder
+
+	assert.NoError(t, r.Run(strings.NewReader(input), &out))
+
+	assert.Contains(t, out.String(), "> ... ... ... ... ... ... macro greeter2 loaded\n")
+	assert.Contains(t, out.String(), "> ... ... [")
+	assert.Contains(t, out.String(), "> greeter hello\n")
+	assert.Contains(t, out.String(), "error: invalid expression")
+	assert.NotContains(t, out.String(), "2\n")
+
+	// history is loaded by next sessions
+	r, err = New(Options{MacroFiles: []string{macroFile}, HistoryFile: historyFile})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	history, err := r.Eval(":history")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"macro greeter2 {", "greeter hello {", "1 +"}, firstLines(history))
+}
+
+func TestOpenBraces(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected int
+	}{
+		"closed":          {input: "a { b { } }", expected: 0},
+		"open":            {input: "a { b {", expected: 2},
+		"double quote":    {input: `a { "}\"}"`, expected: 1},
+		"single quote":    {input: `a { '}'`, expected: 1},
+		"backtick":        {input: "a { `}`", expected: 1},
+		"backtick lines":  {input: "a { `\n}\n", expected: 1},
+		"backtick escape": {input: "a { `\\` }", expected: 0},
+		"comment":         {input: "a { // }\n", expected: 1},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, openBraces(tt.input))
+		})
+	}
+}
+
+// firstLines returns the first line of each history entry
+func firstLines(history string) []string {
+	var result []string
+
+	for _, line := range strings.Split(history, "\n") {
+		if _, entry, found := strings.Cut(line, "  "); found && !strings.HasPrefix(line, " ") {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
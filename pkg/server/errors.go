@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/tislib/logi/pkg/ast/common"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"net/http"
+)
+
+// ErrorBody is the response body of failed requests
+type ErrorBody struct {
+	Error ErrorDetails `json:"error"`
+}
+
+type ErrorDetails struct {
+	Message string `json:"message"`
+
+	// Location is the source location of the error in the request content, if it is known
+	Location *common.SourceLocation `json:"location,omitempty"`
+
+	// At is the token which caused the syntax error
+	At string `json:"at,omitempty"`
+}
+
+// httpError is an error with a response status
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+func withStatus(status int, err error) error {
+	return &httpError{status: status, err: err}
+}
+
+// errorDetails extracts message and source location of parser errors
+func errorDetails(err error) ErrorDetails {
+	var details = ErrorDetails{Message: err.Error()}
+
+	var logiErr *logi.Error
+	var macroErr *macro.Error
+	var sourceErr *logi.SourceError
+
+	if errors.As(err, &logiErr) {
+		details.Message = logiErr.Msg
+		details.At = logiErr.At
+		details.Location = &common.SourceLocation{Line: logiErr.Line, Column: logiErr.Column}
+	} else if errors.As(err, &macroErr) {
+		details.Message = macroErr.Msg
+		details.At = macroErr.At
+		details.Location = &common.SourceLocation{Line: macroErr.Line, Column: macroErr.Column}
+	} else if errors.As(err, &sourceErr) && sourceErr.Location.Line > 0 {
+		details.Location = &sourceErr.Location
+	}
+
+	return details
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var status = http.StatusUnprocessableEntity
+	var statusErr *httpError
+	var maxBytesErr *http.MaxBytesError
+
+	if errors.As(err, &maxBytesErr) {
+		status = http.StatusRequestEntityTooLarge
+	} else if errors.As(err, &statusErr) {
+		status = statusErr.status
+	}
+
+	writeJson(w, status, ErrorBody{Error: errorDetails(err)})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
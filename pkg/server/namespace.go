@@ -0,0 +1,214 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"github.com/tislib/logi/pkg/vm"
+	"net/http"
+	"sync"
+)
+
+// namespace holds macros and definitions loaded through the API, each namespace has its own virtual machine
+type namespace struct {
+	name string
+	vm   vm.VirtualMachine
+
+	// sources maps macro names to the content they are loaded from
+	sources map[string]string
+
+	// maximum number of macros and definitions, zero means no limit
+	maxMacros      int
+	maxDefinitions int
+
+	mu sync.RWMutex
+}
+
+func newNamespace(name string, options Options) *namespace {
+	return &namespace{
+		name:           name,
+		vm:             vm.New(),
+		sources:        make(map[string]string),
+		maxMacros:      options.MaxMacros,
+		maxDefinitions: options.MaxDefinitions,
+	}
+}
+
+// NamespaceResponse describes a namespace
+type NamespaceResponse struct {
+	Name        string `json:"name"`
+	Macros      int    `json:"macros"`
+	Definitions int    `json:"definitions"`
+}
+
+func (n *namespace) describe() NamespaceResponse {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return NamespaceResponse{
+		Name:        n.name,
+		Macros:      len(n.vm.GetMacros()),
+		Definitions: len(n.vm.GetDefinitions()),
+	}
+}
+
+func (n *namespace) macros() []macroAst.Macro {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.vm.GetMacros()
+}
+
+func (n *namespace) macro(name string) (*macroAst.Macro, string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for _, item := range n.vm.GetMacros() {
+		if item.Name == name {
+			return &item, n.sources[name], nil
+		}
+	}
+
+	return nil, "", withStatus(http.StatusNotFound, fmt.Errorf("macro %s not found", name))
+}
+
+// addMacros parses macro content and adds its macros, existing macros are not replaced
+func (n *namespace) addMacros(content string) ([]macroAst.Macro, error) {
+	ast, err := macro.ParseMacroContent(content, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, item := range ast.Macros {
+		if _, found := n.sources[item.Name]; found {
+			return nil, withStatus(http.StatusConflict, fmt.Errorf("macro %s already exists", item.Name))
+		}
+	}
+
+	if n.maxMacros > 0 && len(n.vm.GetMacros())+len(ast.Macros) > n.maxMacros {
+		return nil, withStatus(http.StatusConflict, fmt.Errorf("macro limit of namespace %s is reached: %d", n.name, n.maxMacros))
+	}
+
+	if err := n.vm.LoadMacroAst(*ast); err != nil {
+		return nil, err
+	}
+
+	for _, item := range ast.Macros {
+		n.sources[item.Name] = content
+	}
+
+	return ast.Macros, nil
+}
+
+// deleteMacro removes the macro, macros used by definitions can not be removed
+func (n *namespace) deleteMacro(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, found := n.sources[name]; !found {
+		return withStatus(http.StatusNotFound, fmt.Errorf("macro %s not found", name))
+	}
+
+	for _, definition := range n.vm.GetDefinitions() {
+		if definition.MacroName == name {
+			return withStatus(http.StatusConflict, fmt.Errorf("macro %s is used by definition %s", name, definition.Name))
+		}
+	}
+
+	if err := n.vm.(vm.Registry).RemoveMacro(name); err != nil {
+		return err
+	}
+
+	delete(n.sources, name)
+
+	return nil
+}
+
+// parse parses logi content with the macros of the namespace, without loading it
+func (n *namespace) parse(content string) (*logiAst.Ast, error) {
+	return logi.Parse(content, n.macros(), true)
+}
+
+// addDefinitions parses logi content and loads its definitions, names of definitions must be unique
+func (n *namespace) addDefinitions(content string) ([]logiAst.Definition, error) {
+	return n.loadDefinitions(content, true)
+}
+
+// appendDefinitions loads definitions like addDefinitions, but definitions with existing names are appended
+func (n *namespace) appendDefinitions(content string) ([]logiAst.Definition, error) {
+	return n.loadDefinitions(content, false)
+}
+
+func (n *namespace) loadDefinitions(content string, unique bool) ([]logiAst.Definition, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ast, err := logi.Parse(content, n.vm.GetMacros(), true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var seen = make(map[string]bool)
+
+	for _, definition := range ast.Definitions {
+		if _, err := n.vm.GetDefinitionByName(definition.Name); unique && (err == nil || seen[definition.Name]) {
+			return nil, withStatus(http.StatusConflict, fmt.Errorf("definition %s already exists", definition.Name))
+		}
+
+		seen[definition.Name] = true
+	}
+
+	if n.maxDefinitions > 0 && len(n.vm.GetDefinitions())+len(ast.Definitions) > n.maxDefinitions {
+		return nil, withStatus(http.StatusConflict, fmt.Errorf("definition limit of namespace %s is reached: %d", n.name, n.maxDefinitions))
+	}
+
+	if _, err := n.vm.LoadLogiAst(*ast); err != nil {
+		return nil, err
+	}
+
+	return ast.Definitions, nil
+}
+
+// deleteDefinition removes definitions with the name, all of them are removed if the name is loaded more than once
+func (n *namespace) deleteDefinition(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.vm.(vm.Registry).RemoveDefinition(name); errors.Is(err, vm.ErrNotFound) {
+		return withStatus(http.StatusNotFound, fmt.Errorf("definition %s not found", name))
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// definitions returns definitions filtered by macro and name, empty filters match all definitions
+func (n *namespace) definitions(macroName string, name string) []logiAst.Definition {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	var result = make([]logiAst.Definition, 0)
+
+	for _, definition := range n.vm.GetDefinitions() {
+		if macroName != "" && definition.MacroName != macroName {
+			continue
+		}
+
+		if name != "" && definition.Name != name {
+			continue
+		}
+
+		result = append(result, definition)
+	}
+
+	return result
+}
@@ -0,0 +1,469 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/encoder"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// DefaultNamespace is the namespace used by the endpoints without namespace prefix, it can not be deleted
+const DefaultNamespace = "default"
+
+var namespaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type Options struct {
+	// MaxBodySize is the maximum size of request content in bytes, default is 10MB
+	MaxBodySize int64
+
+	// MaxNamespaces is the maximum number of namespaces including the default namespace, zero means no limit
+	MaxNamespaces int
+
+	// MaxMacros is the maximum number of macros per namespace, zero means no limit
+	MaxMacros int
+
+	// MaxDefinitions is the maximum number of definitions per namespace, zero means no limit
+	MaxDefinitions int
+}
+
+// Server serves REST API for validating and compiling logi content, and managing macros and definitions at runtime.
+// Macros and definitions are kept in namespaces, each namespace has its own virtual machine, so they are isolated from each other.
+// All endpoints are available under /namespaces/{namespace} prefix, endpoints without prefix use the default namespace.
+type Server struct {
+	mux        *http.ServeMux
+	options    Options
+	namespaces map[string]*namespace
+
+	// baseMacros are macro contents loaded before serving, new namespaces start with these macros
+	baseMacros []string
+
+	mu sync.RWMutex
+}
+
+func New(options Options) *Server {
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = 10 << 20
+	}
+
+	s := &Server{
+		mux:     http.NewServeMux(),
+		options: options,
+		namespaces: map[string]*namespace{
+			DefaultNamespace: newNamespace(DefaultNamespace, options),
+		},
+	}
+
+	s.mux.HandleFunc("GET /namespaces", s.handleListNamespaces)
+	s.mux.HandleFunc("POST /namespaces", s.handleCreateNamespace)
+	s.mux.HandleFunc("DELETE /namespaces/{namespace}", s.handleDeleteNamespace)
+	s.handle("GET /namespaces/{namespace}", s.handleGetNamespace)
+
+	for _, prefix := range []string{"", "/namespaces/{namespace}"} {
+		s.handle("POST "+prefix+"/validate", s.handleValidate)
+		s.handle("POST "+prefix+"/compile", s.handleCompile)
+
+		s.handle("GET "+prefix+"/macros", s.handleListMacros)
+		s.handle("POST "+prefix+"/macros", s.handleCreateMacros)
+		s.handle("GET "+prefix+"/macros/{name}", s.handleGetMacro)
+		s.handle("DELETE "+prefix+"/macros/{name}", s.handleDeleteMacro)
+
+		s.handle("GET "+prefix+"/definitions", s.handleListDefinitions)
+		s.handle("POST "+prefix+"/definitions", s.handleCreateDefinitions)
+		s.handle("GET "+prefix+"/definitions/{name}", s.handleGetDefinition)
+		s.handle("DELETE "+prefix+"/definitions/{name}", s.handleDeleteDefinition)
+	}
+
+	// loading definitions by posting to root is kept for compatibility, with its status and appending duplicates
+	s.handle("POST /{$}", s.handleAppendDefinitions)
+
+	return s
+}
+
+// LoadMacroContent loads macros before serving, they are loaded into the default namespace and all namespaces created later
+func (s *Server) LoadMacroContent(content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.namespaces[DefaultNamespace].addMacros(content)
+
+	if err != nil {
+		return err
+	}
+
+	s.baseMacros = append(s.baseMacros, content)
+
+	return nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers a handler which is called with the namespace of the request
+func (s *Server) handle(pattern string, handler func(w http.ResponseWriter, r *http.Request, n *namespace)) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		var name = r.PathValue("namespace")
+
+		if name == "" {
+			name = DefaultNamespace
+		}
+
+		s.mu.RLock()
+		n, found := s.namespaces[name]
+		s.mu.RUnlock()
+
+		if !found {
+			writeError(w, withStatus(http.StatusNotFound, fmt.Errorf("namespace %s not found", name)))
+			return
+		}
+
+		handler(w, r, n)
+	})
+}
+
+func (s *Server) handleListNamespaces(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	var namespaces []*namespace
+
+	for _, n := range s.namespaces {
+		namespaces = append(namespaces, n)
+	}
+	s.mu.RUnlock()
+
+	var result = make([]NamespaceResponse, 0, len(namespaces))
+
+	for _, n := range namespaces {
+		result = append(result, n.describe())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	writeJson(w, http.StatusOK, result)
+}
+
+// CreateNamespaceRequest is the request body of namespace creation
+type CreateNamespaceRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleCreateNamespace(w http.ResponseWriter, r *http.Request) {
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var request CreateNamespaceRequest
+
+	if err := json.Unmarshal([]byte(content), &request); err != nil {
+		writeError(w, withStatus(http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err)))
+		return
+	}
+
+	if !namespaceNamePattern.MatchString(request.Name) {
+		writeError(w, withStatus(http.StatusBadRequest, fmt.Errorf("invalid namespace name: %q", request.Name)))
+		return
+	}
+
+	var n = newNamespace(request.Name, s.options)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.namespaces[request.Name]; found {
+		writeError(w, withStatus(http.StatusConflict, fmt.Errorf("namespace %s already exists", request.Name)))
+		return
+	}
+
+	if s.options.MaxNamespaces > 0 && len(s.namespaces) >= s.options.MaxNamespaces {
+		writeError(w, withStatus(http.StatusConflict, fmt.Errorf("namespace limit is reached: %d", s.options.MaxNamespaces)))
+		return
+	}
+
+	for _, content := range s.baseMacros {
+		if _, err := n.addMacros(content); err != nil {
+			writeError(w, withStatus(http.StatusInternalServerError, err))
+			return
+		}
+	}
+
+	s.namespaces[request.Name] = n
+
+	writeJson(w, http.StatusCreated, n.describe())
+}
+
+func (s *Server) handleDeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	var name = r.PathValue("namespace")
+
+	if name == DefaultNamespace {
+		writeError(w, withStatus(http.StatusBadRequest, fmt.Errorf("default namespace can not be deleted")))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.namespaces[name]; !found {
+		writeError(w, withStatus(http.StatusNotFound, fmt.Errorf("namespace %s not found", name)))
+		return
+	}
+
+	delete(s.namespaces, name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetNamespace(w http.ResponseWriter, r *http.Request, n *namespace) {
+	writeJson(w, http.StatusOK, n.describe())
+}
+
+// MacroResponse is the response of the macro endpoints, source is the content macro is loaded from
+type MacroResponse struct {
+	Name   string      `json:"name"`
+	Source string      `json:"source,omitempty"`
+	Macro  interface{} `json:"macro,omitempty"`
+}
+
+// ValidateResponse is the response of successful validation
+type ValidateResponse struct {
+	Valid       bool     `json:"valid"`
+	Definitions []string `json:"definitions,omitempty"`
+	Macros      []string `json:"macros,omitempty"`
+}
+
+// handleValidate validates logi content, or macro content if type query parameter is `macro`, without storing it
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request, n *namespace) {
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var response = ValidateResponse{Valid: true}
+
+	switch r.URL.Query().Get("type") {
+	case "macro":
+		ast, err := macro.ParseMacroContent(content, true)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for _, item := range ast.Macros {
+			response.Macros = append(response.Macros, item.Name)
+		}
+	case "", "logi":
+		ast, err := n.parse(content)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for _, definition := range ast.Definitions {
+			response.Definitions = append(response.Definitions, definition.Name)
+		}
+	default:
+		writeError(w, withStatus(http.StatusBadRequest, fmt.Errorf("unknown content type: %s", r.URL.Query().Get("type"))))
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// handleCompile compiles logi content with kind and format query parameters, default is normal json
+func (s *Server) handleCompile(w http.ResponseWriter, r *http.Request, n *namespace) {
+	var query = r.URL.Query()
+	var kind = compiler.Kind(query.Get("kind"))
+	var formatName = query.Get("format")
+
+	switch kind {
+	case "":
+		kind = compiler.KindNormal
+	case compiler.KindPlain, compiler.KindNormal, compiler.KindValues:
+	default:
+		writeError(w, withStatus(http.StatusBadRequest, fmt.Errorf("unknown kind: %s", kind)))
+		return
+	}
+
+	if formatName == "" {
+		formatName = "json"
+	}
+
+	format, err := encoder.Get(formatName)
+
+	if err != nil {
+		writeError(w, withStatus(http.StatusBadRequest, err))
+		return
+	}
+
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	value, err := compiler.CompileContent(content, kind, n.macros())
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data, err := format.Encode(value)
+
+	if err != nil {
+		writeError(w, withStatus(http.StatusInternalServerError, fmt.Errorf("error encoding definitions: %v", err)))
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func (s *Server) handleListMacros(w http.ResponseWriter, r *http.Request, n *namespace) {
+	var result = make([]MacroResponse, 0)
+
+	for _, item := range n.macros() {
+		result = append(result, MacroResponse{Name: item.Name})
+	}
+
+	writeJson(w, http.StatusOK, result)
+}
+
+func (s *Server) handleGetMacro(w http.ResponseWriter, r *http.Request, n *namespace) {
+	item, source, err := n.macro(r.PathValue("name"))
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, MacroResponse{Name: item.Name, Source: source, Macro: item})
+}
+
+func (s *Server) handleCreateMacros(w http.ResponseWriter, r *http.Request, n *namespace) {
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	macros, err := n.addMacros(content)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var result []MacroResponse
+
+	for _, item := range macros {
+		result = append(result, MacroResponse{Name: item.Name, Macro: item})
+	}
+
+	writeJson(w, http.StatusCreated, result)
+}
+
+func (s *Server) handleDeleteMacro(w http.ResponseWriter, r *http.Request, n *namespace) {
+	if err := n.deleteMacro(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleListDefinitions lists definitions, filtered by macro and name query parameters
+func (s *Server) handleListDefinitions(w http.ResponseWriter, r *http.Request, n *namespace) {
+	var query = r.URL.Query()
+
+	writeJson(w, http.StatusOK, n.definitions(query.Get("macro"), query.Get("name")))
+}
+
+func (s *Server) handleGetDefinition(w http.ResponseWriter, r *http.Request, n *namespace) {
+	var definitions = n.definitions("", r.PathValue("name"))
+
+	if len(definitions) == 0 {
+		writeError(w, withStatus(http.StatusNotFound, fmt.Errorf("definition %s not found", r.PathValue("name"))))
+		return
+	}
+
+	writeJson(w, http.StatusOK, definitions[0])
+}
+
+func (s *Server) handleDeleteDefinition(w http.ResponseWriter, r *http.Request, n *namespace) {
+	if err := n.deleteDefinition(r.PathValue("name")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateDefinitions(w http.ResponseWriter, r *http.Request, n *namespace) {
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	definitions, err := n.addDefinitions(content)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusCreated, definitions)
+}
+
+func (s *Server) handleAppendDefinitions(w http.ResponseWriter, r *http.Request, n *namespace) {
+	content, err := s.readBody(w, r)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	definitions, err := n.appendDefinitions(content)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, definitions)
+}
+
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.options.MaxBodySize))
+
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+
+		if errors.As(err, &maxBytesErr) {
+			return "", err
+		}
+
+		return "", withStatus(http.StatusBadRequest, fmt.Errorf("error reading request body: %v", err))
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const creditRuleMacro = `
+macro creditRule {
+    kind Syntax
+
+    syntax {
+        creditScore <min int> <max int>
+        age <min int> <max int>
+    }
+}
+`
+
+const roleMacro = `
+macro role {
+    kind Syntax
+
+    syntax {
+        description <description string>
+    }
+}
+`
+
+type request struct {
+	method string
+	path   string
+	body   string
+}
+
+func doRequest(s http.Handler, req request) *httptest.ResponseRecorder {
+	var recorder = httptest.NewRecorder()
+
+	s.ServeHTTP(recorder, httptest.NewRequest(req.method, req.path, strings.NewReader(req.body)))
+
+	return recorder
+}
+
+func TestServer(t *testing.T) {
+	tests := map[string]struct {
+		setup          []request
+		request        request
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+		expectedError  *ErrorDetails
+		expectedNames  []string
+	}{
+		"validate logi": {
+			request:        request{"POST", "/validate", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"valid": true, "definitions": ["Rule1"]}`,
+		},
+		"validate macro": {
+			request:        request{"POST", "/validate?type=macro", roleMacro},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"valid": true, "macros": ["role"]}`,
+		},
+		"validate syntax error": {
+			request:        request{"POST", "/validate", "creditRule Rule1 {\n    age 18 65\n"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  &ErrorDetails{Message: "unexpected $end \"\n\", expecting }", Location: loc(3, 1), At: "\n"},
+		},
+		"validate statement error": {
+			request:        request{"POST", "/validate", "creditRule Rule1 {\n    creditScore 500\n}\n"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  &ErrorDetails{Message: "failed to convert definition: failed to parse statement: failed to match statement: statement is shorter than syntax", Location: loc(2, 5)},
+		},
+		"validate unknown macro": {
+			request:        request{"POST", "/validate", "unknown Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  &ErrorDetails{Message: "failed to locate macro definition: macro definition not found: unknown", Location: loc(1, 1)},
+		},
+		"validate does not store": {
+			setup:          []request{{"POST", "/validate", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"GET", "/definitions", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+		},
+		"compile values yaml": {
+			request:        request{"POST", "/compile?kind=values&format=yaml", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/yaml",
+			expectedBody:   "Rule1:\n    age:\n        max: 65\n        min: 18\n",
+		},
+		"compile unknown format": {
+			request:        request{"POST", "/compile?format=xml", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"compile unknown kind": {
+			request:        request{"POST", "/compile?kind=other", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"list macros": {
+			request:        request{"GET", "/macros", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name": "creditRule"}]`,
+		},
+		"create macro": {
+			setup:          []request{{"POST", "/macros", roleMacro}},
+			request:        request{"GET", "/macros", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name": "creditRule"}, {"name": "role"}]`,
+		},
+		"create existing macro": {
+			request:        request{"POST", "/macros", creditRuleMacro},
+			expectedStatus: http.StatusConflict,
+			expectedError:  &ErrorDetails{Message: "macro creditRule already exists"},
+		},
+		"create invalid macro": {
+			request:        request{"POST", "/macros", "macro role {"},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"get unknown macro": {
+			request:        request{"GET", "/macros/unknown", ""},
+			expectedStatus: http.StatusNotFound,
+		},
+		"delete macro": {
+			setup: []request{
+				{"POST", "/macros", roleMacro},
+				{"DELETE", "/macros/role", ""},
+			},
+			request:        request{"GET", "/macros", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name": "creditRule"}]`,
+		},
+		"delete used macro": {
+			setup:          []request{{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"DELETE", "/macros/creditRule", ""},
+			expectedStatus: http.StatusConflict,
+		},
+		"delete unknown macro": {
+			request:        request{"DELETE", "/macros/unknown", ""},
+			expectedStatus: http.StatusNotFound,
+		},
+		"query definitions": {
+			setup: []request{
+				{"POST", "/macros", roleMacro},
+				{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\ncreditRule Rule2 {\n    age 21 65\n}\n"},
+				{"POST", "/definitions", "role Admin {\n    description \"admin\"\n}\n"},
+			},
+			request:        request{"GET", "/definitions?macro=creditRule&name=Rule2", ""},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Rule2"},
+		},
+		"get definition": {
+			setup:          []request{{"POST", "/", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"GET", "/definitions/Rule1", ""},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Rule1"},
+		},
+		"load definitions to root": {
+			setup:          []request{{"POST", "/", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"POST", "/", "creditRule Rule1 {\n    age 21 65\n}\n"},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Rule1"},
+		},
+		"duplicates loaded to root": {
+			setup: []request{
+				{"POST", "/", "creditRule Rule1 {\n    age 18 65\n}\n"},
+				{"POST", "/", "creditRule Rule1 {\n    age 21 65\n}\n"},
+			},
+			request:        request{"GET", "/definitions?name=Rule1", ""},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Rule1", "Rule1"},
+		},
+		"get unknown definition": {
+			request:        request{"GET", "/definitions/Rule1", ""},
+			expectedStatus: http.StatusNotFound,
+		},
+		"create existing definition": {
+			setup:          []request{{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusConflict,
+		},
+		"create namespace": {
+			setup:          []request{{"POST", "/namespaces", `{"name": "tenant1"}`}},
+			request:        request{"GET", "/namespaces", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"name": "default", "macros": 1, "definitions": 0}, {"name": "tenant1", "macros": 1, "definitions": 0}]`,
+		},
+		"create invalid namespace": {
+			request:        request{"POST", "/namespaces", `{"name": "../x"}`},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"create existing namespace": {
+			setup:          []request{{"POST", "/namespaces", `{"name": "tenant1"}`}},
+			request:        request{"POST", "/namespaces", `{"name": "tenant1"}`},
+			expectedStatus: http.StatusConflict,
+		},
+		"namespace limit": {
+			setup:          []request{{"POST", "/namespaces", `{"name": "tenant1"}`}},
+			request:        request{"POST", "/namespaces", `{"name": "tenant2"}`},
+			expectedStatus: http.StatusConflict,
+		},
+		"namespaces are isolated": {
+			setup: []request{
+				{"POST", "/namespaces", `{"name": "tenant1"}`},
+				{"POST", "/namespaces/tenant1/macros", roleMacro},
+				{"POST", "/namespaces/tenant1/definitions", "role Admin {\n    description \"admin\"\n}\n"},
+				{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			},
+			request:        request{"GET", "/namespaces/tenant1/definitions", ""},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Admin"},
+		},
+		"macros of other namespace are not visible": {
+			setup: []request{
+				{"POST", "/namespaces", `{"name": "tenant1"}`},
+				{"POST", "/namespaces/tenant1/macros", roleMacro},
+			},
+			request:        request{"POST", "/validate", "role Admin {\n    description \"admin\"\n}\n"},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		"delete namespace": {
+			setup: []request{
+				{"POST", "/namespaces", `{"name": "tenant1"}`},
+				{"DELETE", "/namespaces/tenant1", ""},
+			},
+			request:        request{"GET", "/namespaces/tenant1/macros", ""},
+			expectedStatus: http.StatusNotFound,
+		},
+		"delete default namespace": {
+			request:        request{"DELETE", "/namespaces/default", ""},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"get namespace": {
+			setup:          []request{{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"}},
+			request:        request{"GET", "/namespaces/default", ""},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"name": "default", "macros": 1, "definitions": 1}`,
+		},
+		"definition limit": {
+			setup:          []request{{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\ncreditRule Rule2 {\n    age 18 65\n}\n"}},
+			request:        request{"POST", "/definitions", "creditRule Rule3 {\n    age 18 65\n}\ncreditRule Rule4 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusConflict,
+		},
+		"delete definition": {
+			setup: []request{
+				{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\ncreditRule Rule2 {\n    age 18 65\n}\n"},
+				{"DELETE", "/definitions/Rule1", ""},
+			},
+			request:        request{"GET", "/definitions", ""},
+			expectedStatus: http.StatusOK,
+			expectedNames:  []string{"Rule2"},
+		},
+		"delete definition in namespace": {
+			setup: []request{
+				{"POST", "/namespaces", `{"name": "tenant1"}`},
+				{"POST", "/namespaces/tenant1/definitions", "creditRule Rule1 {\n    age 18 65\n}\n"},
+			},
+			request:        request{"DELETE", "/namespaces/tenant1/definitions/Rule1", ""},
+			expectedStatus: http.StatusNoContent,
+		},
+		"delete unknown definition": {
+			request:        request{"DELETE", "/definitions/Rule1", ""},
+			expectedStatus: http.StatusNotFound,
+		},
+		"create definition after delete at limit": {
+			setup: []request{
+				{"POST", "/definitions", "creditRule Rule1 {\n    age 18 65\n}\ncreditRule Rule2 {\n    age 18 65\n}\ncreditRule Rule3 {\n    age 18 65\n}\n"},
+				{"DELETE", "/definitions/Rule1", ""},
+			},
+			request:        request{"POST", "/definitions", "creditRule Rule4 {\n    age 18 65\n}\n"},
+			expectedStatus: http.StatusCreated,
+			expectedNames:  []string{"Rule4"},
+		},
+		"request too large": {
+			request:        request{"POST", "/validate", strings.Repeat(" ", 10<<20+1)},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := New(Options{MaxNamespaces: 2, MaxMacros: 3, MaxDefinitions: 3})
+
+			if !assert.NoError(t, s.LoadMacroContent(creditRuleMacro)) {
+				return
+			}
+
+			for _, req := range tt.setup {
+				response := doRequest(s, req)
+
+				if !assert.Less(t, response.Code, 300, response.Body.String()) {
+					return
+				}
+			}
+
+			response := doRequest(s, tt.request)
+
+			assert.Equal(t, tt.expectedStatus, response.Code, response.Body.String())
+
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, response.Header().Get("Content-Type"))
+			}
+
+			if tt.expectedBody != "" {
+				if tt.expectedType == "" {
+					assert.JSONEq(t, tt.expectedBody, response.Body.String())
+				} else {
+					assert.Equal(t, tt.expectedBody, response.Body.String())
+				}
+			}
+
+			if tt.expectedNames != nil {
+				var names []string
+				var definitions []logiAst.Definition
+
+				body := response.Body.String()
+
+				if !strings.HasPrefix(body, "[") {
+					body = "[" + body + "]"
+				}
+
+				assert.NoError(t, json.Unmarshal([]byte(body), &definitions))
+
+				for _, definition := range definitions {
+					names = append(names, definition.Name)
+				}
+
+				assert.Equal(t, tt.expectedNames, names)
+			}
+
+			if tt.expectedError != nil {
+				var body ErrorBody
+
+				assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+				assert.Equal(t, *tt.expectedError, body.Error)
+			}
+		})
+	}
+}
+
+func loc(line, column int) *common.SourceLocation {
+	return &common.SourceLocation{Line: line, Column: column}
+}
+
+func TestServerConcurrency(t *testing.T) {
+	s := New(Options{})
+
+	if !assert.NoError(t, s.LoadMacroContent(creditRuleMacro)) {
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			var ns = fmt.Sprintf("/namespaces/tenant%d", i)
+
+			assert.Equal(t, http.StatusCreated, doRequest(s, request{"POST", "/namespaces", fmt.Sprintf(`{"name": "tenant%d"}`, i)}).Code)
+
+			for j := 0; j < 10; j++ {
+				var content = fmt.Sprintf("creditRule Rule%d {\n    age 18 65\n}\n", j)
+
+				assert.Equal(t, http.StatusCreated, doRequest(s, request{"POST", ns + "/definitions", content}).Code)
+				assert.Equal(t, http.StatusOK, doRequest(s, request{"POST", "/validate", content}).Code)
+				assert.Equal(t, http.StatusOK, doRequest(s, request{"GET", ns + "/definitions", ""}).Code)
+				assert.Equal(t, http.StatusOK, doRequest(s, request{"GET", "/namespaces", ""}).Code)
+			}
+
+			assert.Equal(t, http.StatusNoContent, doRequest(s, request{"DELETE", ns, ""}).Code)
+		}(i)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,28 @@
+package transport
+
+import (
+	"errors"
+	"github.com/sourcegraph/jsonrpc2"
+	"io"
+	"os"
+)
+
+// NewObjectStream reads and writes JSON objects framed with Content-Length headers, like LSP and DAP messages
+func NewObjectStream(stream io.ReadWriteCloser) jsonrpc2.ObjectStream {
+	return jsonrpc2.NewBufferedStream(stream, jsonrpc2.VSCodeObjectCodec{})
+}
+
+// Stdio is the stream of standard input and output of the process
+type Stdio struct{}
+
+func (Stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (Stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (Stdio) Close() error {
+	return errors.Join(os.Stdin.Close(), os.Stdout.Close())
+}
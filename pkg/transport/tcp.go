@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"crypto/tls"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"os"
+)
+
+// Listen listens on the address, with TLS if TLS_CERT and TLS_KEY environment variables are set
+func Listen(network string, address string) (net.Listener, error) {
+	listener, err := net.Listen(network, address)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not bind to address %s: %w", address, err)
+	}
+
+	cert := os.Getenv("TLS_CERT")
+	key := os.Getenv("TLS_KEY")
+
+	if cert != "" && key != "" {
+		certificate, err := tls.X509KeyPair([]byte(cert), []byte(key))
+
+		if err != nil {
+			_ = listener.Close()
+
+			return nil, fmt.Errorf("invalid TLS certificate: %w", err)
+		}
+
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+		})
+	}
+
+	return listener, nil
+}
+
+// ServeTCP accepts TCP connections, and serves each of them in its own goroutine
+func ServeTCP(address string, serve func(stream io.ReadWriteCloser)) error {
+	listener, err := Listen("tcp", address)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("listening for TCP connections on %s", listener.Addr())
+
+	return Serve(listener, serve)
+}
+
+// Serve accepts connections of the listener until it is closed, and serves each of them in its own goroutine
+func Serve(listener net.Listener, serve func(stream io.ReadWriteCloser)) error {
+	for {
+		connection, err := listener.Accept()
+
+		if err != nil {
+			return err
+		}
+
+		go serve(connection)
+	}
+}
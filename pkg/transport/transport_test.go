@@ -0,0 +1,55 @@
+package transport
+
+import (
+	"context"
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	listener, err := Listen("tcp", "127.0.0.1:0")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer listener.Close()
+
+	go func() {
+		_ = Serve(listener, func(stream io.ReadWriteCloser) {
+			var handler = jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) (interface{}, error) {
+				return request.Method, nil
+			})
+
+			<-jsonrpc2.NewConn(context.Background(), NewObjectStream(stream), handler).DisconnectNotify()
+		})
+	}()
+
+	// each connection is served with Content-Length framed messages
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var client = jsonrpc2.NewConn(context.Background(), NewObjectStream(conn), jsonrpc2.HandlerWithError(func(ctx context.Context, conn *jsonrpc2.Conn, request *jsonrpc2.Request) (interface{}, error) {
+			return nil, nil
+		}))
+
+		var result string
+
+		assert.NoError(t, client.Call(context.Background(), "echo", nil, &result))
+		assert.Equal(t, "echo", result)
+		assert.NoError(t, client.Close())
+	}
+}
+
+func TestListenInvalidAddress(t *testing.T) {
+	_, err := Listen("tcp", "invalid address")
+
+	assert.ErrorContains(t, err, "could not bind to address invalid address")
+}
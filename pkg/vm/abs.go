@@ -1,9 +1,11 @@
 package vm
 
 import (
+	"context"
 	"github.com/tislib/logi/pkg/ast/common"
 	logiAst "github.com/tislib/logi/pkg/ast/logi"
 	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"io"
 )
 
 type ImplementerFunc func(vm VirtualMachine, statement logiAst.Statement, passNext func(statement logiAst.Statement) error) error
@@ -12,6 +14,18 @@ type Implementer interface {
 	Call(vm VirtualMachine, statement logiAst.Statement) error
 }
 
+// VirtualMachine loads macros and logi definitions and executes them.
+//
+// All methods are safe for concurrent use:
+//   - Loads, removals and replacements are atomic, concurrent readers either see all macros/definitions of a load call
+//     item or none of them.
+//     Logi content is matched against the macros loaded at the time parsing starts.
+//   - GetMacros and GetDefinitions return snapshots, later loads never modify them, so they can be used without locking.
+//     Returned macros and definitions are shared, they must not be modified by the caller.
+//   - Execute does not lock the virtual machine while running, so definitions can be executed from many goroutines at
+//     the same time, and implementers can call virtual machine methods. Implementers are responsible for their own state.
+//   - Evaluate does not use any state of the virtual machine.
+//   - Implementers receive an Execution as the virtual machine, which applies the context and limits of ExecuteContext.
 type VirtualMachine interface {
 	// loads macro files from the given paths
 	LoadMacroFile(path ...string) error
@@ -24,9 +38,70 @@ type VirtualMachine interface {
 	LoadLogiAst(ast ...logiAst.Ast) ([]logiAst.Definition, error)
 	GetMacroContent(name string) string
 	GetMacros() []macroAst.Macro
+	GetDefinitions() []logiAst.Definition
 	GetDefinitionByName(name string) (*logiAst.Definition, error)
 
 	// VM functions
 	Execute(def *logiAst.Definition, implementer Implementer) error
 	Evaluate(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error)
 }
+
+// Virtual machines returned by New and NewFromProgram, and executions given to implementers, also implement the
+// optional interfaces below, callers type-assert the virtual machine to use them.
+
+// Registry removes and replaces loaded macros and definitions
+type Registry interface {
+	// sets what happens when a macro or definition with an already loaded name is loaded, default is DuplicateAllow
+	SetDuplicatePolicy(policy DuplicatePolicy)
+
+	// removes or replaces loaded macros and definitions, all items with the name are affected when names are duplicated,
+	// ErrNotFound is returned if there is nothing to remove
+	RemoveDefinition(name string) error
+	ReplaceDefinition(definition logiAst.Definition) error
+	RemoveMacro(name string) error
+
+	// removes everything loaded from the source, source of files is their path
+	UnloadSource(source string) error
+	GetSources() []string
+}
+
+// Loader loads compiled artifacts and keeps files loaded as they change
+type Loader interface {
+	// loads macros and definitions from compiled artifact, without parsing
+	LoadCompiled(r io.Reader) ([]logiAst.Definition, error)
+
+	// watches macro and logi files of the given paths, and reloads them on change
+	Watch(paths ...string) (*Watcher, error)
+}
+
+// Executor executes definitions with a context and limits, or on events
+type Executor interface {
+	// context aware variants, execution stops when the context is done or a limit is exceeded
+	ExecuteContext(ctx context.Context, def *logiAst.Definition, implementer Implementer, limits Limits) error
+	EvaluateContext(ctx context.Context, expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error)
+
+	// returns a runtime which executes handlers of definitions on events
+	NewRuntime(implementer Implementer, clock Clock) *Runtime
+}
+
+// Extensible adds variables, functions and constructs to executions
+type Extensible interface {
+	// global variables and functions, executions and evaluations see them
+	Environment() *Environment
+	SetFunction(name string, fn func(args ...common.Value) (common.Value, error))
+
+	// maps macro statements to constructs which are executed by the virtual machine instead of implementers
+	Annotate(macroName string, scope string, command string, construct Construct)
+}
+
+// Inspector gives source locations and statements of loaded definitions, and traces executions, e.g. for debuggers
+type Inspector interface {
+	// keeps source locations of loaded content, they are used by query results, it must be set before loading
+	EnableSourceMap(enable bool)
+
+	// returns statements of loaded definitions matching the query, see Query for its syntax
+	Query(query string) ([]QueryMatch, error)
+
+	// sets the tracer of executions, e.g. Debugger.Trace
+	SetTracer(tracer Tracer)
+}
@@ -0,0 +1,119 @@
+package vm
+
+import (
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+)
+
+// Builder collects macros and logi content, and builds an immutable Program.
+// Logi content is parsed on Build, so it is matched against all macros regardless of the order they are added.
+// Builder is not safe for concurrent use.
+type Builder struct {
+	macros      []macroAst.Macro
+	definitions []logiAst.Definition
+	contents    []builderContent
+
+	enableSourceMap bool
+}
+
+type builderContent struct {
+	source  string
+	content string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// EnableSourceMap enables source locations of macros and definitions
+func (b *Builder) EnableSourceMap(enable bool) *Builder {
+	b.enableSourceMap = enable
+
+	return b
+}
+
+func (b *Builder) AddMacroFile(path ...string) error {
+	for _, p := range path {
+		data, err := readFile(p)
+
+		if err != nil {
+			return fmt.Errorf("error reading file: %v", err)
+		}
+
+		if err := b.AddMacroContent(string(data)); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Builder) AddMacroContent(content ...string) error {
+	for _, c := range content {
+		ast, err := macro.ParseMacroContent(c, b.enableSourceMap)
+
+		if err != nil {
+			return fmt.Errorf("error parsing macro content: %w", err)
+		}
+
+		b.macros = append(b.macros, ast.Macros...)
+	}
+
+	return nil
+}
+
+func (b *Builder) AddMacroAst(ast ...macroAst.Ast) {
+	for _, a := range ast {
+		b.macros = append(b.macros, a.Macros...)
+	}
+}
+
+func (b *Builder) AddLogiFile(path ...string) error {
+	for _, p := range path {
+		data, err := readFile(p)
+
+		if err != nil {
+			return fmt.Errorf("error reading file: %v", err)
+		}
+
+		b.contents = append(b.contents, builderContent{source: p, content: string(data)})
+	}
+
+	return nil
+}
+
+func (b *Builder) AddLogiContent(content ...string) {
+	for _, c := range content {
+		b.contents = append(b.contents, builderContent{content: c})
+	}
+}
+
+func (b *Builder) AddLogiAst(ast ...logiAst.Ast) {
+	for _, a := range ast {
+		b.definitions = append(b.definitions, a.Definitions...)
+	}
+}
+
+// Build parses logi content and builds the program, macro and definition names must be unique
+func (b *Builder) Build() (*Program, error) {
+	var definitions = append([]logiAst.Definition{}, b.definitions...)
+
+	for _, c := range b.contents {
+		ast, err := logi.Parse(c.content, b.macros, b.enableSourceMap)
+
+		if err != nil {
+			if c.source != "" {
+				return nil, fmt.Errorf("error parsing logi content %s: %w", c.source, err)
+			}
+
+			return nil, fmt.Errorf("error parsing logi content: %w", err)
+		}
+
+		definitions = append(definitions, ast.Definitions...)
+	}
+
+	return newProgram(append([]macroAst.Macro{}, b.macros...), definitions)
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is used by timers of the runtime, it can be replaced to control time in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock returns the clock of the system
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// ManualClock is a clock which only moves when it is advanced, timers fire when the clock reaches their time
+type ManualClock struct {
+	now    time.Time
+	timers []manualTimer
+	mu     sync.Mutex
+}
+
+type manualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ch = make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- c.now
+
+		return ch
+	}
+
+	c.timers = append(c.timers, manualTimer{at: c.now.Add(d), ch: ch})
+
+	return ch
+}
+
+// Advance moves the clock and fires the timers which are due, in order of their time
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].at.Before(c.timers[j].at)
+	})
+
+	var pending []manualTimer
+
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.ch <- timer.at
+		}
+	}
+
+	c.timers = pending
+}
+
+// Waiters returns the number of timers which are not fired yet
+func (c *ManualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
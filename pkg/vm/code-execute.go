@@ -3,42 +3,66 @@ package vm
 import (
 	"fmt"
 	"github.com/tislib/logi/pkg/ast/common"
-	logiAst "github.com/tislib/logi/pkg/ast/logi"
 )
 
-func (v *vm) Execute(def *logiAst.Definition, implementer Implementer) error {
-	for _, statement := range def.Statements {
-		if err := implementer.Call(v, statement); err != nil {
-			return fmt.Errorf("failed to execute statement: %w at %v", err, statement)
-		}
+func (v *vm) Evaluate(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error) {
+	return v.evaluate(expression, vars, fns, nil)
+}
+
+// evaluate evaluates the expression, each node is counted and traced by the execution, if it is given
+func (v *vm) evaluate(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error), e *Execution) (common.Value, error) {
+	if e == nil {
+		return v.evaluateNode(expression, vars, fns, e)
+	}
+
+	if err := e.evaluated(); err != nil {
+		return common.Value{}, err
+	}
+
+	value, err := v.evaluateNode(expression, vars, fns, e)
+
+	if err == nil && e.tracer != nil {
+		e.trace(TraceEvent{Kind: TraceExpression, Expression: &expression, Value: value})
 	}
-	return nil
+
+	return value, err
 }
 
-func (v *vm) Evaluate(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error) {
+func (v *vm) evaluateNode(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error), e *Execution) (common.Value, error) {
 	switch expression.Kind {
 	case common.LiteralKind:
 		return expression.Literal.Value, nil
 	case common.VariableKind:
 		value, ok := vars[expression.Variable.Name]
 
+		// variables which are not given are looked up in the environment
+		if !ok && e != nil {
+			value, ok = e.env.Get(expression.Variable.Name)
+		} else if !ok {
+			value, ok = v.globals.Get(expression.Variable.Name)
+		}
+
 		if !ok {
 			return common.Value{}, fmt.Errorf("variable %s not found", expression.Variable.Name)
 		}
 
 		return value, nil
 	case common.BinaryExprKind:
-		return v.evaluateBinaryExpression(expression.BinaryExpr, vars, fns)
+		return v.evaluateBinaryExpression(expression.BinaryExpr, vars, fns, e)
 	case common.FuncCallKind:
 		fn, ok := fns[expression.FuncCall.Name]
 
+		if !ok {
+			fn, ok = v.function(expression.FuncCall.Name)
+		}
+
 		if !ok {
 			return common.Value{}, fmt.Errorf("function %s not found", expression.FuncCall.Name)
 		}
 
 		args := make([]common.Value, 0)
 		for _, arg := range expression.FuncCall.Arguments {
-			value, err := v.Evaluate(*arg, vars, fns)
+			value, err := v.evaluate(*arg, vars, fns, e)
 			if err != nil {
 				return common.Value{}, fmt.Errorf("failed to evaluate argument: %w", err)
 			}
@@ -51,13 +75,13 @@ func (v *vm) Evaluate(expression common.Expression, vars map[string]common.Value
 	}
 }
 
-func (v *vm) evaluateBinaryExpression(expr *common.BinaryExpression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error) {
-	leftValue, err := v.Evaluate(*expr.Left, vars, fns)
+func (v *vm) evaluateBinaryExpression(expr *common.BinaryExpression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error), e *Execution) (common.Value, error) {
+	leftValue, err := v.evaluate(*expr.Left, vars, fns, e)
 	if err != nil {
 		return common.Value{}, fmt.Errorf("failed to evaluate left expression: %w", err)
 	}
 
-	rightValue, err := v.Evaluate(*expr.Right, vars, fns)
+	rightValue, err := v.evaluate(*expr.Right, vars, fns, e)
 	if err != nil {
 		return common.Value{}, fmt.Errorf("failed to evaluate right expression: %w", err)
 	}
@@ -0,0 +1,259 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+)
+
+// Construct is executed by the virtual machine instead of the implementer, for statements annotated with it
+type Construct interface {
+	Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error
+}
+
+type annotationKey struct {
+	macroName string
+	scope     string
+	command   string
+}
+
+// Annotate maps statements of the macro with the scope and command to the construct, e.g.
+//
+//	v.Annotate("program", "statement", "set", vm.Assign{Name: "name", Value: "value"})
+//
+// for the macro statement `set <name Name> <value int>`. Annotated statements are not passed to implementers.
+func (v *vm) Annotate(macroName string, scope string, command string, construct Construct) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.annotations[annotationKey{macroName: macroName, scope: scope, command: command}] = construct
+}
+
+func (v *vm) annotation(macroName string, scope string, command string) Construct {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.annotations[annotationKey{macroName: macroName, scope: scope, command: command}]
+}
+
+// Assign sets a variable, visible to the next statements of the same block and their sub statements
+type Assign struct {
+	// Name is the parameter with the name of the variable
+	Name string
+
+	// Value is the parameter with the value, it can be an expression
+	Value string
+
+	// Local declares the variable in the current block, even if a variable with the same name is visible from an
+	// outer block. Otherwise, the visible variable is updated, and new variables are declared in the current block.
+	Local bool
+
+	// Global sets the global variable of the virtual machine. Global variables are shared by all executions,
+	// other assignments only update variables of the definition and its blocks.
+	Global bool
+}
+
+func (a Assign) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	var name = statement.GetParameter(a.Name).AsString()
+
+	if name == "" {
+		return fmt.Errorf("variable name is required in parameter %s", a.Name)
+	}
+
+	value, err := e.Parameter(statement, a.Value)
+
+	if err != nil {
+		return err
+	}
+
+	if a.Global {
+		e.vm.globals.Set(name, value)
+	} else if a.Local {
+		e.env.Declare(name, value)
+	} else {
+		e.env.Set(name, value)
+	}
+
+	return nil
+}
+
+var ErrMaxIterations = errors.New("maximum number of iterations exceeded")
+
+// errBreak and errReturn stop execution of the statements until the enclosing loop or definition
+var errBreak = errors.New("break outside of loop")
+var errReturn = errors.New("return")
+
+// Block executes sub statements of the statement as blocks, e.g. for `main { statement }`
+type Block struct{}
+
+func (b Block) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	for _, subStatements := range statement.SubStatements {
+		if err := e.block(implementer, subStatements, NewEnvironment(e.env)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// If executes the block of the first condition which is true, e.g. for
+// `if (<condition bool>) { statement } else if (<condition2 bool>) { statement } else { statement }`.
+// Blocks are matched with conditions in order, the block after the last condition of the statement is the else block.
+type If struct {
+	// Condition is the parameter of the condition
+	Condition string
+
+	// ElseIf are the parameters of else if conditions, the ones missing in the statement are ignored
+	ElseIf []string
+}
+
+func (c If) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	var index = 0
+
+	for _, name := range append([]string{c.Condition}, c.ElseIf...) {
+		if !hasParameter(statement, name) {
+			break
+		}
+
+		value, err := e.condition(statement, name)
+
+		if err != nil {
+			return err
+		}
+
+		if value {
+			break
+		}
+
+		index++
+	}
+
+	// no condition is true and there is no else block
+	if index >= len(statement.SubStatements) {
+		return nil
+	}
+
+	return e.block(implementer, statement.SubStatements[index], NewEnvironment(e.env))
+}
+
+// ForEach executes the block for each item of an array, e.g. for `each (<item Name>, <items array<int>>) { statement }`
+type ForEach struct {
+	// Item is the parameter with the name of the variable, which is set to the current item in the block
+	Item string
+
+	// Items is the parameter with the array
+	Items string
+}
+
+func (f ForEach) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	var name = statement.GetParameter(f.Item).AsString()
+
+	if name == "" {
+		return fmt.Errorf("variable name is required in parameter %s", f.Item)
+	}
+
+	items, err := e.Parameter(statement, f.Items)
+
+	if err != nil {
+		return err
+	}
+
+	if items.Kind != common.ValueKindArray {
+		return fmt.Errorf("%s must be an array, got %s", f.Items, items.Kind)
+	}
+
+	for _, item := range items.AsArray() {
+		var env = NewEnvironment(e.env)
+		env.Declare(name, item)
+
+		if err := e.block(implementer, body(statement), env); errors.Is(err, errBreak) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// While executes the block while the condition is true, e.g. for `while (<condition bool>) { statement }`
+type While struct {
+	// Condition is the parameter of the condition
+	Condition string
+
+	// MaxIterations fails the loop with ErrMaxIterations if it is exceeded, zero is unlimited
+	MaxIterations int
+}
+
+func (w While) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	for i := 0; ; i++ {
+		value, err := e.condition(statement, w.Condition)
+
+		if err != nil {
+			return err
+		}
+
+		if !value {
+			return nil
+		}
+
+		if w.MaxIterations > 0 && i >= w.MaxIterations {
+			return fmt.Errorf("%w: %d", ErrMaxIterations, w.MaxIterations)
+		}
+
+		if err := e.block(implementer, body(statement), NewEnvironment(e.env)); errors.Is(err, errBreak) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Break stops the innermost loop
+type Break struct{}
+
+func (b Break) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	return errBreak
+}
+
+// Return stops execution of the definition
+type Return struct{}
+
+func (r Return) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	return errReturn
+}
+
+// condition evaluates the parameter as a boolean condition
+func (e *Execution) condition(statement logiAst.Statement, name string) (bool, error) {
+	value, err := e.Parameter(statement, name)
+
+	if err != nil {
+		return false, err
+	}
+
+	if value.Kind != common.ValueKindBoolean {
+		return false, fmt.Errorf("condition %s must be boolean, got %s", name, value.Kind)
+	}
+
+	return value.AsBoolean(), nil
+}
+
+// body returns the last sub statement list of the statement, which is the body of loops
+func body(statement logiAst.Statement) []logiAst.Statement {
+	if len(statement.SubStatements) == 0 {
+		return nil
+	}
+
+	return statement.SubStatements[len(statement.SubStatements)-1]
+}
+
+func hasParameter(statement logiAst.Statement, name string) bool {
+	for _, parameter := range statement.Parameters {
+		if parameter.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
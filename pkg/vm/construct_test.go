@@ -0,0 +1,188 @@
+package vm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"testing"
+)
+
+const controlFlowLgm = `macro program {
+    kind Syntax
+
+    syntax {
+        main { statement }
+    }
+
+    scopes {
+        statement {
+            set (<name Name>, <value int>)
+            print (<value int>)
+            if (<condition bool>) { statement } else if (<condition2 bool>) { statement } else { statement }
+            each (<item Name>, <items array<int>>) { statement }
+            while (<condition bool>) { statement }
+            break
+            return
+        }
+    }
+}`
+
+func TestVmControlFlow(t *testing.T) {
+	tests := map[string]struct {
+		statements     []string
+		expectedPrints []int64
+		expectedError  error
+	}{
+		"if": {
+			statements: []string{
+				"if (limit == 1) {",
+				"    print(1)",
+				"} else if (true) {",
+				"    print(2)",
+				"} else {",
+				"    print(3)",
+				"}",
+			},
+			expectedPrints: []int64{1},
+		},
+		"else if": {
+			statements: []string{
+				"if (false) {",
+				"    print(1)",
+				"} else if (even(4)) {",
+				"    print(2)",
+				"} else {",
+				"    print(3)",
+				"}",
+			},
+			expectedPrints: []int64{2},
+		},
+		"else": {
+			statements: []string{
+				"if (false) {",
+				"    print(1)",
+				"} else if (even(3)) {",
+				"    print(2)",
+				"} else {",
+				"    print(3)",
+				"}",
+			},
+			expectedPrints: []int64{3},
+		},
+		"for each": {
+			statements: []string{
+				"set(sum, 0)",
+				"each (x, items) {",
+				"    set(sum, sum + x)",
+				"    print(x)",
+				"}",
+				"print(sum)",
+			},
+			expectedPrints: []int64{1, 2, 3, 6},
+		},
+		"break": {
+			statements: []string{
+				"each (x, items) {",
+				"    if (x == 2) {",
+				"        break",
+				"    } else if (false) {",
+				"        print(-1)",
+				"    } else {",
+				"        print(x)",
+				"    }",
+				"    print(x)",
+				"}",
+				"print(0)",
+			},
+			expectedPrints: []int64{1, 1, 0},
+		},
+		"while": {
+			statements: []string{
+				"set(i, 0)",
+				"while (i < 3) {",
+				"    set(i, i + 1)",
+				"    print(i)",
+				"}",
+			},
+			expectedPrints: []int64{1, 2, 3},
+		},
+		"while with max iterations": {
+			statements: []string{
+				"while (true) {",
+				"    print(1)",
+				"}",
+			},
+			expectedPrints: []int64{1, 1, 1, 1, 1},
+			expectedError:  ErrMaxIterations,
+		},
+		"return": {
+			statements: []string{
+				"print(1)",
+				"each (x, items) {",
+				"    return",
+				"}",
+				"print(2)",
+			},
+			expectedPrints: []int64{1},
+		},
+		"break outside of loop": {
+			statements:    []string{"break"},
+			expectedError: errBreak,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := New().(*vm)
+			v.Environment().Declare("limit", common.IntegerValue(1))
+			v.Environment().Declare("items", common.ArrayValue(common.IntegerValue(1), common.IntegerValue(2), common.IntegerValue(3)))
+			v.SetFunction("even", func(args ...common.Value) (common.Value, error) {
+				return common.BooleanValue(args[0].AsInteger()%2 == 0), nil
+			})
+
+			v.Annotate("program", "", "main", Block{})
+			v.Annotate("program", "statement", "set", Assign{Name: "name", Value: "value"})
+			v.Annotate("program", "statement", "if", If{Condition: "condition", ElseIf: []string{"condition2"}})
+			v.Annotate("program", "statement", "each", ForEach{Item: "item", Items: "items"})
+			v.Annotate("program", "statement", "while", While{Condition: "condition", MaxIterations: 5})
+			v.Annotate("program", "statement", "break", Break{})
+			v.Annotate("program", "statement", "return", Return{})
+
+			if !assert.NoError(t, v.LoadMacroContent(controlFlowLgm)) {
+				return
+			}
+
+			definitions, err := v.LoadLogiContent(programContent(tt.statements...))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var prints []int64
+
+			// the implementer only receives leaf commands
+			var implementer = NewRouter().
+				Handle("statement", "print", func(vm VirtualMachine, statement logiAst.Statement) error {
+					value, err := vm.(*Execution).Parameter(statement, "value")
+
+					if err != nil {
+						return err
+					}
+
+					prints = append(prints, value.AsInteger())
+
+					return nil
+				})
+
+			err = v.Execute(&definitions[0], implementer)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedPrints, prints)
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package vm
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+var ErrNotPaused = errors.New("execution is not paused")
+
+type StopReason string
+
+const (
+	StopBreakpoint StopReason = "breakpoint"
+	StopStep       StopReason = "step"
+	StopPause      StopReason = "pause"
+)
+
+// Breakpoint pauses executions before a statement, it matches the statement by path, or by line if the definition is
+// loaded with source map
+type Breakpoint struct {
+	// Definition is the name of the definition, empty matches all definitions
+	Definition string
+
+	Path []int
+	Line int
+}
+
+// Stop is a paused execution
+type Stop struct {
+	Reason StopReason
+	Event  TraceEvent
+}
+
+type stepMode int
+
+const (
+	stepNone stepMode = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// stepState is the step an execution is resumed with, depth is the depth of the statement it is resumed from
+type stepState struct {
+	mode  stepMode
+	depth int
+}
+
+// Debugger pauses executions on breakpoints and steps, it is attached to a virtual machine as its tracer:
+//
+//	debugger := vm.NewDebugger(func(stop vm.Stop) { ... })
+//	v.(vm.Inspector).SetTracer(debugger.Trace)
+//
+// onStop is called by the paused execution, which waits until Continue or one of the step methods is called, from
+// another goroutine, or until its context is done. Only one execution is paused at a time, others wait for it before
+// they can pause. Steps apply to the execution which is resumed, other executions only stop on breakpoints.
+type Debugger struct {
+	onStop      func(stop Stop)
+	breakpoints []Breakpoint
+
+	// pausing is set by Pause, the next statement of any execution is paused
+	pausing bool
+
+	// steps are kept per execution, until it ends
+	steps map[*executionCounters]stepState
+
+	// resume is set while an execution is paused
+	resume chan stepMode
+
+	mu sync.Mutex
+
+	// paused holds a token while an execution is paused
+	paused chan struct{}
+}
+
+func NewDebugger(onStop func(stop Stop)) *Debugger {
+	return &Debugger{
+		onStop: onStop,
+		steps:  make(map[*executionCounters]stepState),
+		paused: make(chan struct{}, 1),
+	}
+}
+
+// SetBreakpoints replaces all breakpoints
+func (d *Debugger) SetBreakpoints(breakpoints ...Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = breakpoints
+}
+
+func (d *Debugger) Breakpoints() []Breakpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.breakpoints
+}
+
+// Continue resumes the paused execution until the next breakpoint
+func (d *Debugger) Continue() error {
+	return d.resumeWith(stepNone)
+}
+
+// StepIn resumes the paused execution until the next statement
+func (d *Debugger) StepIn() error {
+	return d.resumeWith(stepIn)
+}
+
+// StepOver resumes the paused execution until the next statement which is not a sub statement of the current one
+func (d *Debugger) StepOver() error {
+	return d.resumeWith(stepOver)
+}
+
+// StepOut resumes the paused execution until the next statement after the parent of the current one
+func (d *Debugger) StepOut() error {
+	return d.resumeWith(stepOut)
+}
+
+// Pause pauses the next statement executed
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pausing = true
+}
+
+// Trace is the tracer of the debugger, it pauses executions before statements
+func (d *Debugger) Trace(event TraceEvent) {
+	if event.Kind == TraceEnd {
+		d.mu.Lock()
+		delete(d.steps, event.execution)
+		d.mu.Unlock()
+
+		return
+	}
+
+	if event.Kind != TraceStatement {
+		return
+	}
+
+	d.mu.Lock()
+	_, stop := d.stopReason(event)
+	d.mu.Unlock()
+
+	if !stop {
+		return
+	}
+
+	var done <-chan struct{}
+
+	if event.Context != nil {
+		done = event.Context.Done()
+	}
+
+	// wait for the paused execution, if there is one
+	select {
+	case d.paused <- struct{}{}:
+	case <-done:
+		return
+	}
+
+	defer func() {
+		<-d.paused
+	}()
+
+	d.mu.Lock()
+	// steps and pause may be consumed while waiting
+	reason, stop := d.stopReason(event)
+
+	if !stop {
+		d.mu.Unlock()
+
+		return
+	}
+
+	var resume = make(chan stepMode, 1)
+	d.resume = resume
+	d.pausing = false
+	delete(d.steps, event.execution)
+	d.mu.Unlock()
+
+	d.onStop(Stop{Reason: reason, Event: event})
+
+	select {
+	case mode := <-resume:
+		if mode != stepNone {
+			d.mu.Lock()
+			d.steps[event.execution] = stepState{mode: mode, depth: event.Depth}
+			d.mu.Unlock()
+		}
+	case <-done:
+		d.mu.Lock()
+		if d.resume == resume {
+			d.resume = nil
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *Debugger) stopReason(event TraceEvent) (StopReason, bool) {
+	if d.pausing {
+		return StopPause, true
+	}
+
+	if step, found := d.steps[event.execution]; found {
+		switch {
+		case step.mode == stepIn,
+			step.mode == stepOver && event.Depth <= step.depth,
+			step.mode == stepOut && event.Depth < step.depth:
+			return StopStep, true
+		}
+	}
+
+	for _, breakpoint := range d.breakpoints {
+		if breakpoint.matches(event) {
+			return StopBreakpoint, true
+		}
+	}
+
+	return "", false
+}
+
+func (d *Debugger) resumeWith(mode stepMode) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.resume == nil {
+		return ErrNotPaused
+	}
+
+	d.resume <- mode
+	d.resume = nil
+
+	return nil
+}
+
+func (b Breakpoint) matches(event TraceEvent) bool {
+	if event.Definition == nil || (b.Definition != "" && b.Definition != event.Definition.Name) {
+		return false
+	}
+
+	if b.Line > 0 {
+		var location = event.Location()
+
+		return location != nil && location.Line == b.Line
+	}
+
+	return len(b.Path) > 0 && slices.Equal(b.Path, event.Path)
+}
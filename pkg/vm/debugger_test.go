@@ -0,0 +1,286 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineOf returns the line number of the first line of the content which contains the text
+func lineOf(content string, text string) int {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, text) {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+func TestDebugger(t *testing.T) {
+	tests := map[string]struct {
+		breakpoints   []Breakpoint
+		actions       []func(d *Debugger) error
+		expectedStops []string
+	}{
+		"no breakpoints": {},
+		"breakpoint by path and continue": {
+			breakpoints:   []Breakpoint{{Definition: "simple1", Path: []int{1, 0, 2}}, {Path: []int{1, 0, 3, 0, 1}}},
+			actions:       []func(d *Debugger) error{(*Debugger).Continue, (*Debugger).Continue},
+			expectedStops: []string{"breakpoint on_click [1 0 2]", "breakpoint on [1 0 3 0 1]"},
+		},
+		"breakpoint of other definition": {
+			breakpoints: []Breakpoint{{Definition: "other", Path: []int{1, 0, 2}}},
+		},
+		"breakpoint by line": {
+			breakpoints:   []Breakpoint{{Line: lineOf(circuitLg, "on_click(button2)")}},
+			actions:       []func(d *Debugger) error{(*Debugger).Continue},
+			expectedStops: []string{"breakpoint on_click [1 0 3]"},
+		},
+		"stepping": {
+			breakpoints: []Breakpoint{{Path: []int{1, 0, 2}}},
+			actions: []func(d *Debugger) error{
+				(*Debugger).StepIn,
+				(*Debugger).StepIn,
+				(*Debugger).StepOver,
+				(*Debugger).StepOut,
+				(*Debugger).StepIn,
+				(*Debugger).StepOut,
+			},
+			expectedStops: []string{
+				"breakpoint on_click [1 0 2]",
+				"step if [1 0 2 0 0]",
+				"step on [1 0 2 0 0 0 0]",
+				"step on [1 0 2 0 0 0 1]",
+				"step on_click [1 0 3]",
+				"step off [1 0 3 0 0]",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, definitions := newTracedCircuit(t)
+
+			var stops = make(chan Stop)
+			var debugger = NewDebugger(func(stop Stop) {
+				stops <- stop
+			})
+
+			debugger.SetBreakpoints(tt.breakpoints...)
+			v.SetTracer(debugger.Trace)
+
+			var done = make(chan error, 1)
+
+			go func() {
+				done <- v.Execute(&definitions[0], NewRouter().Unknown(DescendUnknown))
+			}()
+
+			assert.ErrorIs(t, debugger.Continue(), ErrNotPaused)
+
+			var result []string
+
+			for _, action := range tt.actions {
+				select {
+				case stop := <-stops:
+					result = append(result, fmt.Sprint(stop.Reason, " ", stop.Event.Statement.Command, " ", stop.Event.Path))
+				case <-time.After(time.Second):
+					t.Fatal("execution is not paused")
+				}
+
+				assert.NoError(t, action(debugger))
+			}
+
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case stop := <-stops:
+				t.Fatal("unexpected stop at", stop.Event.Path)
+			}
+
+			assert.Equal(t, tt.expectedStops, result)
+
+			// steps of the execution are released when it ends
+			assert.Zero(t, stepCount(debugger))
+		})
+	}
+}
+
+func stepCount(d *Debugger) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.steps)
+}
+
+func TestDebuggerPause(t *testing.T) {
+	v, definitions := newTracedCircuit(t)
+
+	var stops = make(chan Stop, 1)
+	var debugger = NewDebugger(func(stop Stop) {
+		stops <- stop
+	})
+
+	v.SetTracer(debugger.Trace)
+	debugger.Pause()
+
+	var done = make(chan error, 1)
+
+	go func() {
+		done <- v.Execute(&definitions[0], NewRouter().Unknown(DescendUnknown))
+	}()
+
+	var stop = <-stops
+
+	assert.Equal(t, StopPause, stop.Reason)
+	assert.Equal(t, []int{0}, stop.Event.Path)
+
+	value, found := stop.Event.Environment.Get("button2")
+	assert.True(t, found)
+	assert.Equal(t, "button2", value.AsString())
+
+	assert.NoError(t, debugger.Continue())
+	assert.NoError(t, <-done)
+}
+
+func TestDebuggerCancel(t *testing.T) {
+	v, definitions := newTracedCircuit(t)
+
+	var stops = make(chan Stop, 1)
+	var debugger = NewDebugger(func(stop Stop) {
+		stops <- stop
+	})
+
+	debugger.SetBreakpoints(Breakpoint{Path: []int{1, 0, 2}})
+	v.SetTracer(debugger.Trace)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var done = make(chan error, 1)
+
+	go func() {
+		done <- v.ExecuteContext(ctx, &definitions[0], NewRouter().Unknown(DescendUnknown), Limits{})
+	}()
+
+	<-stops
+
+	// cancelled execution stops waiting, so it does not block other executions
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Eventually(t, func() bool {
+		return errors.Is(debugger.Continue(), ErrNotPaused)
+	}, time.Second, time.Millisecond)
+
+	go func() {
+		done <- v.Execute(&definitions[0], NewRouter().Unknown(DescendUnknown))
+	}()
+
+	select {
+	case stop := <-stops:
+		assert.Equal(t, []int{1, 0, 2}, stop.Event.Path)
+	case <-time.After(time.Second):
+		t.Fatal("execution is not paused after cancelled one")
+	}
+
+	assert.NoError(t, debugger.Continue())
+	assert.NoError(t, <-done)
+}
+
+func TestDebuggerStepsPerExecution(t *testing.T) {
+	v, definitions := newTracedCircuit(t)
+
+	var stops = make(chan Stop)
+	var debugger = NewDebugger(func(stop Stop) {
+		stops <- stop
+	})
+
+	debugger.SetBreakpoints(Breakpoint{Path: []int{1, 0, 2}})
+	v.SetTracer(debugger.Trace)
+
+	var done = make(chan error, 2)
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- v.Execute(&definitions[0], NewRouter().Unknown(DescendUnknown))
+		}()
+	}
+
+	// first execution steps in, second one only stops on the breakpoint
+	var first = <-stops
+	assert.Equal(t, StopBreakpoint, first.Reason)
+	assert.NoError(t, debugger.StepIn())
+
+	var reasons = map[StopReason]int{}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case stop := <-stops:
+			reasons[stop.Reason]++
+
+			if stop.Reason == StopStep {
+				assert.Same(t, first.Event.execution, stop.Event.execution)
+				assert.Equal(t, []int{1, 0, 2, 0, 0}, stop.Event.Path)
+			} else {
+				assert.NotSame(t, first.Event.execution, stop.Event.execution)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("execution is not paused")
+		}
+
+		assert.NoError(t, debugger.Continue())
+	}
+
+	assert.Equal(t, map[StopReason]int{StopStep: 1, StopBreakpoint: 1}, reasons)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+}
+
+func TestDebuggerStepsReleasedOnCancel(t *testing.T) {
+	v, definitions := newTracedCircuit(t)
+
+	var stops = make(chan Stop, 1)
+	var debugger = NewDebugger(func(stop Stop) {
+		stops <- stop
+	})
+
+	debugger.SetBreakpoints(Breakpoint{Path: []int{1, 0, 2, 0, 0}})
+	v.SetTracer(debugger.Trace)
+
+	var blocked = make(chan struct{})
+	var unblock = make(chan struct{})
+
+	// on(blueLed) blocks the execution while it is stepping over the if statement
+	var implementer = NewRouter().Handle("*", "on", func(vm VirtualMachine, statement logiAst.Statement) error {
+		if statement.GetParameter("component").AsString() == "blueLed" {
+			close(blocked)
+			<-unblock
+		}
+
+		return nil
+	}).Unknown(DescendUnknown)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var done = make(chan error, 1)
+
+	go func() {
+		done <- v.ExecuteContext(ctx, &definitions[0], implementer, Limits{})
+	}()
+
+	<-stops
+	assert.NoError(t, debugger.StepOver())
+	<-blocked
+
+	assert.Equal(t, 1, stepCount(debugger))
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	close(unblock)
+
+	assert.Eventually(t, func() bool {
+		return stepCount(debugger) == 0
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,95 @@
+package vm
+
+import (
+	"github.com/tislib/logi/pkg/ast/common"
+	"sync"
+)
+
+// Environment is a frame of variables, variables which are not found in the frame are looked up in its parent frames.
+// The virtual machine has a global environment, executions push a frame for each definition, and for each list of
+// sub statements executed with ExecuteBlock.
+type Environment struct {
+	parent *Environment
+	vars   map[string]common.Value
+	mu     sync.RWMutex
+
+	// definition is true for the frame of a definition, Set does not update variables of its parents
+	definition bool
+}
+
+func NewEnvironment(parent *Environment) *Environment {
+	return &Environment{
+		parent: parent,
+		vars:   make(map[string]common.Value),
+	}
+}
+
+func (e *Environment) Parent() *Environment {
+	return e.parent
+}
+
+// Get returns the variable from the nearest frame which has it
+func (e *Environment) Get(name string) (common.Value, bool) {
+	for frame := e; frame != nil; frame = frame.parent {
+		frame.mu.RLock()
+		value, found := frame.vars[name]
+		frame.mu.RUnlock()
+
+		if found {
+			return value, true
+		}
+	}
+
+	return common.Value{}, false
+}
+
+// Declare sets the variable in this frame, it hides the variable with the same name in parent frames
+func (e *Environment) Declare(name string, value common.Value) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.vars[name] = value
+}
+
+// Set updates the variable in the nearest frame which has it, if there is none, it is declared in this frame.
+// Frames outside of the definition frame are not updated, global variables are shared by all executions,
+// they are only updated explicitly.
+func (e *Environment) Set(name string, value common.Value) {
+	for frame := e; frame != nil; frame = frame.parent {
+		frame.mu.Lock()
+
+		if _, found := frame.vars[name]; found {
+			frame.vars[name] = value
+			frame.mu.Unlock()
+
+			return
+		}
+
+		frame.mu.Unlock()
+
+		if frame.definition {
+			break
+		}
+	}
+
+	e.Declare(name, value)
+}
+
+// Vars returns all visible variables, variables of inner frames hide the ones of parent frames
+func (e *Environment) Vars() map[string]common.Value {
+	var result = make(map[string]common.Value)
+
+	for frame := e; frame != nil; frame = frame.parent {
+		frame.mu.RLock()
+
+		for name, value := range frame.vars {
+			if _, found := result[name]; !found {
+				result[name] = value
+			}
+		}
+
+		frame.mu.RUnlock()
+	}
+
+	return result
+}
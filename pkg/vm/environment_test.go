@@ -0,0 +1,222 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const programLgm = `macro program {
+    kind Syntax
+
+    syntax {
+        main { statement }
+    }
+
+    scopes {
+        statement {
+            set <name Name> <value int>
+            global <name Name> <value int>
+            let (<name Name>, <value int>)
+            print (<value int>)
+            block { statement }
+        }
+    }
+}`
+
+// programContent returns a program definition with the statements in its main block
+func programContent(statements ...string) string {
+	return "program p {\n    main {\n        " + strings.Join(statements, "\n        ") + "\n    }\n}"
+}
+
+func TestEnvironment(t *testing.T) {
+	var globals = NewEnvironment(nil)
+	var frame = NewEnvironment(globals)
+
+	globals.Declare("a", common.IntegerValue(1))
+	globals.Declare("b", common.IntegerValue(2))
+
+	frame.Declare("b", common.IntegerValue(3))
+	frame.Set("a", common.IntegerValue(4))
+	frame.Set("c", common.IntegerValue(5))
+
+	assert.Equal(t, map[string]common.Value{
+		"a": common.IntegerValue(4),
+		"b": common.IntegerValue(3),
+		"c": common.IntegerValue(5),
+	}, frame.Vars())
+
+	assert.Equal(t, map[string]common.Value{
+		"a": common.IntegerValue(4),
+		"b": common.IntegerValue(2),
+	}, globals.Vars())
+
+	_, found := globals.Get("c")
+	assert.False(t, found)
+}
+
+func TestVmVariables(t *testing.T) {
+	tests := map[string]struct {
+		statements     []string
+		expectedPrints []int64
+		expectedError  string
+		expectedLimit  int64
+	}{
+		"global": {
+			statements:     []string{"print(limit)"},
+			expectedPrints: []int64{10},
+			expectedLimit:  10,
+		},
+		"assignment": {
+			statements:     []string{"set x 1", "let(y, x + 1)", "print(x + y)"},
+			expectedPrints: []int64{3},
+			expectedLimit:  10,
+		},
+		"blocks": {
+			statements: []string{
+				"set x 1",
+				"set y 2",
+				"block {",
+				"    let(x, 10)",
+				"    set y 5",
+				"    print(x + y)",
+				"}",
+				"print(x + y)",
+			},
+			expectedPrints: []int64{15, 6},
+			expectedLimit:  10,
+		},
+		"global is hidden by assignment": {
+			statements:     []string{"set limit 11", "print(limit)"},
+			expectedPrints: []int64{11},
+			expectedLimit:  10,
+		},
+		"global is updated explicitly": {
+			statements:     []string{"global limit 12", "print(limit)"},
+			expectedPrints: []int64{12},
+			expectedLimit:  12,
+		},
+		"variable of block is not visible after it": {
+			statements: []string{
+				"block {",
+				"    set z 1",
+				"}",
+				"print(z)",
+			},
+			expectedError: "variable z not found",
+			expectedLimit: 10,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := New().(*vm)
+			v.Environment().Declare("limit", common.IntegerValue(10))
+
+			v.Annotate("program", "statement", "set", Assign{Name: "name", Value: "value"})
+			v.Annotate("program", "statement", "let", Assign{Name: "name", Value: "value", Local: true})
+			v.Annotate("program", "statement", "global", Assign{Name: "name", Value: "value", Global: true})
+
+			if !assert.NoError(t, v.LoadMacroContent(programLgm)) {
+				return
+			}
+
+			definitions, err := v.LoadLogiContent(programContent(tt.statements...))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var prints []int64
+
+			var router = NewRouter().
+				Handle("statement", "print", func(vm VirtualMachine, statement logiAst.Statement) error {
+					value, err := vm.(*Execution).Parameter(statement, "value")
+
+					if err != nil {
+						return err
+					}
+
+					prints = append(prints, value.AsInteger())
+
+					return nil
+				}).
+				Unknown(DescendUnknown)
+
+			err = v.Execute(&definitions[0], router)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedPrints, prints)
+
+			limit, _ := v.Environment().Get("limit")
+			assert.Equal(t, tt.expectedLimit, limit.AsInteger())
+		})
+	}
+}
+
+func TestVmConcurrentAssignments(t *testing.T) {
+	v := New().(*vm)
+	v.Environment().Declare("limit", common.IntegerValue(10))
+	v.Annotate("program", "statement", "set", Assign{Name: "name", Value: "value"})
+
+	if !assert.NoError(t, v.LoadMacroContent(programLgm)) {
+		return
+	}
+
+	var executions = 10
+	var results = make(chan int64, executions)
+	var wg sync.WaitGroup
+
+	for i := 0; i < executions; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			definitions, err := v.LoadLogiContent(programContent(fmt.Sprintf("set limit %d", i), "block {", "    print(limit)", "}"))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var router = NewRouter().
+				Handle("statement", "print", func(vm VirtualMachine, statement logiAst.Statement) error {
+					value, err := vm.(*Execution).Parameter(statement, "value")
+
+					if err != nil {
+						return err
+					}
+
+					// each execution sees its own assignment
+					if value.AsInteger() != int64(i) {
+						return fmt.Errorf("expected %d, got %d", i, value.AsInteger())
+					}
+
+					results <- value.AsInteger()
+
+					return nil
+				}).
+				Unknown(DescendUnknown)
+
+			assert.NoError(t, v.Execute(&definitions[0], router))
+		}(i)
+	}
+
+	wg.Wait()
+	close(results)
+
+	assert.Len(t, results, executions)
+
+	// assignments of executions do not leak into the shared global
+	limit, _ := v.Environment().Get("limit")
+	assert.Equal(t, int64(10), limit.AsInteger())
+}
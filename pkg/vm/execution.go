@@ -0,0 +1,312 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrMaxDepth = errors.New("maximum depth exceeded")
+var ErrMaxStatements = errors.New("maximum number of statements exceeded")
+var ErrMaxEvaluations = errors.New("expression evaluation budget exceeded")
+
+// Limits of an execution, zero values are unlimited
+type Limits struct {
+	// MaxDepth is the maximum nesting of statements, top level statements of definitions are at depth 1
+	MaxDepth int
+
+	// MaxStatements is the maximum number of statements executed
+	MaxStatements int
+
+	// MaxEvaluations is the maximum number of expression nodes evaluated, e.g. `a + f(b)` costs 4
+	MaxEvaluations int
+}
+
+// Execution is a running execution of a definition, it is passed to implementers as the virtual machine.
+// Statements executed with Call, and expressions evaluated with Evaluate, are checked against the context and limits
+// of the execution. Each block of the execution has its own Execution value, with its own environment frame.
+type Execution struct {
+	*vm
+
+	ctx       context.Context
+	limits    Limits
+	counters  *executionCounters
+	macroName string
+	env       *Environment
+	runtime   *Runtime
+
+	// definition and statement being executed, they are used for tracing
+	definition *logiAst.Definition
+	frame      *statementFrame
+	tracer     Tracer
+}
+
+// statementFrame is a statement being executed, with its position in the definition
+type statementFrame struct {
+	parent    *statementFrame
+	statement *logiAst.Statement
+
+	// list is the index of the sub statement list in the parent statement, index is the index in the list,
+	// they are -1 if unknown
+	list  int
+	index int
+}
+
+type executionCounters struct {
+	depth       int64
+	statements  int64
+	evaluations int64
+
+	// tracedErr is the last error passed to the tracer, it is not traced again by the parent statements
+	tracedErr error
+	mu        sync.Mutex
+}
+
+// Call executes the statement with the implementer, within the limits of the execution if the virtual machine is an
+// Execution. Implementers which execute sub statements themselves should use it instead of calling themselves directly.
+func Call(vm VirtualMachine, implementer Implementer, statement logiAst.Statement) error {
+	if e, ok := vm.(*Execution); ok {
+		return e.call(implementer, statement)
+	}
+
+	return implementer.Call(vm, statement)
+}
+
+// ExecuteBlock executes the statements in a new environment frame, so variables declared by them are only visible
+// in the block. Implementers which execute sub statements themselves should use it for each list of sub statements.
+func ExecuteBlock(vm VirtualMachine, implementer Implementer, statements []logiAst.Statement) error {
+	if e, ok := vm.(*Execution); ok {
+		return e.block(implementer, statements, NewEnvironment(e.env))
+	}
+
+	for _, statement := range statements {
+		if err := Call(vm, implementer, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *vm) newExecution(ctx context.Context, limits Limits) *Execution {
+	return &Execution{
+		vm:       v,
+		ctx:      ctx,
+		limits:   limits,
+		counters: new(executionCounters),
+		env:      v.globals,
+		tracer:   v.getTracer(),
+	}
+}
+
+func (v *vm) Execute(def *logiAst.Definition, implementer Implementer) error {
+	return v.ExecuteContext(context.Background(), def, implementer, Limits{})
+}
+
+// ExecuteContext executes the definition, it returns as soon as the context is done, even if an implementer is blocked.
+// In that case the implementer keeps running in background until it returns, implementers can stop early by checking
+// the context of the execution.
+func (v *vm) ExecuteContext(ctx context.Context, def *logiAst.Definition, implementer Implementer, limits Limits) error {
+	var e = v.newExecution(ctx, limits)
+
+	if ctx.Done() == nil {
+		defer e.traceEnd()
+
+		return e.Execute(def, implementer)
+	}
+
+	type result struct {
+		err   error
+		panic interface{}
+	}
+
+	var done = make(chan result, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{panic: r}
+			}
+		}()
+
+		// end is traced when the execution returns, which can be after the context is done
+		defer e.traceEnd()
+
+		done <- result{err: e.Execute(def, implementer)}
+	}()
+
+	select {
+	case r := <-done:
+		if r.panic != nil {
+			panic(r.panic)
+		}
+
+		return r.err
+	case <-ctx.Done():
+		return fmt.Errorf("execution is interrupted: %w", ctx.Err())
+	}
+}
+
+func (v *vm) EvaluateContext(ctx context.Context, expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error) {
+	return v.newExecution(ctx, Limits{}).Evaluate(expression, vars, fns)
+}
+
+// Context returns the context of the execution
+func (e *Execution) Context() context.Context {
+	return e.ctx
+}
+
+// Environment returns the environment frame of the current block
+func (e *Execution) Environment() *Environment {
+	return e.env
+}
+
+// Parameter returns the value of the parameter of the statement, expressions are evaluated in the current environment
+func (e *Execution) Parameter(statement logiAst.Statement, name string) (common.Value, error) {
+	for _, parameter := range statement.Parameters {
+		if parameter.Name != name {
+			continue
+		}
+
+		if parameter.Expression != nil {
+			return e.Evaluate(*parameter.Expression, nil, nil)
+		}
+
+		return parameter.Value, nil
+	}
+
+	return common.Value{}, fmt.Errorf("parameter %s not found in %s", name, statement.Command)
+}
+
+// Execute executes statements of the definition within the same execution, in a new frame for the definition
+func (e *Execution) Execute(def *logiAst.Definition, implementer Implementer) error {
+	var env = NewEnvironment(e.vm.globals)
+	env.definition = true
+
+	var d = e.withEnvironment(env)
+	d.macroName = def.MacroName
+	d.definition = def
+	d.frame = nil
+
+	for i, statement := range def.Statements {
+		if err := d.callAt(implementer, statement, -1, i); errors.Is(err, errReturn) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to execute statement: %w at %v", err, statement)
+		}
+	}
+
+	return nil
+}
+
+func (e *Execution) Evaluate(expression common.Expression, vars map[string]common.Value, fns map[string]func(args ...common.Value) (common.Value, error)) (common.Value, error) {
+	return e.vm.evaluate(expression, vars, fns, e)
+}
+
+func (e *Execution) call(implementer Implementer, statement logiAst.Statement) error {
+	return e.callAt(implementer, statement, -1, -1)
+}
+
+// callAt executes the statement, which is at the index of the sub statement list of the current statement
+func (e *Execution) callAt(implementer Implementer, statement logiAst.Statement, list int, index int) error {
+	if err := e.ctx.Err(); err != nil {
+		return fmt.Errorf("execution is interrupted: %w", err)
+	}
+
+	if statements := atomic.AddInt64(&e.counters.statements, 1); e.limits.MaxStatements > 0 && statements > int64(e.limits.MaxStatements) {
+		return fmt.Errorf("%w: %d", ErrMaxStatements, e.limits.MaxStatements)
+	}
+
+	defer atomic.AddInt64(&e.counters.depth, -1)
+
+	if depth := atomic.AddInt64(&e.counters.depth, 1); e.limits.MaxDepth > 0 && depth > int64(e.limits.MaxDepth) {
+		return fmt.Errorf("%w: %d", ErrMaxDepth, e.limits.MaxDepth)
+	}
+
+	var s = *e
+	s.frame = &statementFrame{parent: e.frame, statement: &statement, list: list, index: index}
+
+	if s.tracer != nil {
+		s.trace(TraceEvent{Kind: TraceStatement})
+	}
+
+	var err error
+
+	if construct := e.vm.annotation(e.macroName, statement.Scope, statement.Command); construct != nil {
+		err = construct.Execute(&s, implementer, statement)
+	} else {
+		err = implementer.Call(&s, statement)
+	}
+
+	if err != nil && s.tracer != nil {
+		s.traceError(err)
+	}
+
+	return err
+}
+
+// block executes the statements with the environment
+func (e *Execution) block(implementer Implementer, statements []logiAst.Statement, env *Environment) error {
+	var b = e.withEnvironment(env)
+	var list = e.frame.listIndex(statements)
+
+	for i, statement := range statements {
+		if err := b.callAt(implementer, statement, list, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Execution) withEnvironment(env *Environment) *Execution {
+	var result = *e
+	result.env = env
+
+	return &result
+}
+
+// evaluated is called for each evaluated expression node
+func (e *Execution) evaluated() error {
+	if err := e.ctx.Err(); err != nil {
+		return fmt.Errorf("evaluation is interrupted: %w", err)
+	}
+
+	if evaluations := atomic.AddInt64(&e.counters.evaluations, 1); e.limits.MaxEvaluations > 0 && evaluations > int64(e.limits.MaxEvaluations) {
+		return fmt.Errorf("%w: %d", ErrMaxEvaluations, e.limits.MaxEvaluations)
+	}
+
+	return nil
+}
+
+// path returns the path of the statement in the definition, see StatementRef
+func (f *statementFrame) path() []int {
+	if f == nil {
+		return nil
+	}
+
+	if f.parent == nil {
+		return []int{f.index}
+	}
+
+	return append(f.parent.path(), f.list, f.index)
+}
+
+// listIndex returns the index of the statements in sub statement lists of the statement of the frame
+func (f *statementFrame) listIndex(statements []logiAst.Statement) int {
+	if f == nil || len(statements) == 0 {
+		return -1
+	}
+
+	for i, subStatements := range f.statement.SubStatements {
+		if len(subStatements) > 0 && &subStatements[0] == &statements[0] {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,165 @@
+package vm
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"testing"
+	"time"
+)
+
+func TestVmExecuteContext(t *testing.T) {
+	v := New().(*vm)
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(circuitLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var fns = map[string]func(args ...common.Value) (common.Value, error){
+		"status": func(args ...common.Value) (common.Value, error) {
+			return common.StringValue("on"), nil
+		},
+	}
+
+	// router evaluates conditions of if statements and descends into everything else
+	var router = func(blocked chan struct{}) *Router {
+		return NewRouter().
+			Handle("command", "if", func(vm VirtualMachine, statement logiAst.Statement) error {
+				_, err := vm.Evaluate(*statement.Parameters[0].Expression, map[string]common.Value{
+					"button2": common.StringValue("button2"),
+				}, fns)
+
+				return err
+			}).
+			Handle("command", "off", func(vm VirtualMachine, statement logiAst.Statement) error {
+				if blocked != nil {
+					<-blocked
+				}
+
+				return nil
+			}).
+			Unknown(DescendUnknown)
+	}
+
+	tests := map[string]struct {
+		ctx           func() (context.Context, context.CancelFunc)
+		limits        Limits
+		blocked       bool
+		expectedError error
+	}{
+		"no limits": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithCancel(context.Background())
+			},
+		},
+		"cancelled": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return ctx, cancel
+			},
+			expectedError: context.Canceled,
+		},
+		"deadline with blocked implementer": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			blocked:       true,
+			expectedError: context.DeadlineExceeded,
+		},
+		"max depth": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			// actions / on_click / if / on
+			limits:        Limits{MaxDepth: 3},
+			expectedError: ErrMaxDepth,
+		},
+		"max depth not exceeded": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			limits: Limits{MaxDepth: 4},
+		},
+		"max statements": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			limits:        Limits{MaxStatements: 10},
+			expectedError: ErrMaxStatements,
+		},
+		"max evaluations": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			// status(button2) == 'on' costs 4
+			limits:        Limits{MaxEvaluations: 3},
+			expectedError: ErrMaxEvaluations,
+		},
+		"max evaluations not exceeded": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.Background(), func() {}
+			},
+			limits: Limits{MaxEvaluations: 4},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			var blocked chan struct{}
+
+			if tt.blocked {
+				blocked = make(chan struct{})
+				defer close(blocked)
+			}
+
+			var start = time.Now()
+
+			err := v.ExecuteContext(ctx, &definitions[0], router(blocked), tt.limits)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Less(t, time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestVmEvaluateContext(t *testing.T) {
+	v := New().(*vm)
+
+	var expression = common.Expression{
+		Kind: common.BinaryExprKind,
+		BinaryExpr: &common.BinaryExpression{
+			Operator: "+",
+			Left:     &common.Expression{Kind: common.LiteralKind, Literal: &common.Literal{Value: common.IntegerValue(1)}},
+			Right:    &common.Expression{Kind: common.LiteralKind, Literal: &common.Literal{Value: common.IntegerValue(2)}},
+		},
+	}
+
+	value, err := v.EvaluateContext(context.Background(), expression, nil, nil)
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), value.AsInteger())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = v.EvaluateContext(ctx, expression, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
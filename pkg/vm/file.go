@@ -0,0 +1,9 @@
+//go:build !js
+
+package vm
+
+import "os"
+
+func readFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
@@ -0,0 +1,13 @@
+//go:build js
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// readFile fails on js, content must be loaded instead of files
+func readFile(path string) ([]byte, error) {
+	return nil, fmt.Errorf("open %s: %w", path, errors.ErrUnsupported)
+}
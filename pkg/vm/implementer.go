@@ -10,7 +10,7 @@ type simpleImplementer struct {
 
 func (s simpleImplementer) Call(vm VirtualMachine, statement logiAst.Statement) error {
 	return s.fn(vm, statement, func(next logiAst.Statement) error {
-		return s.Call(vm, next)
+		return Call(vm, s, next)
 	})
 }
 
@@ -4,27 +4,29 @@ import (
 	"fmt"
 	logiAst "github.com/tislib/logi/pkg/ast/logi"
 	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/compiled"
 	"github.com/tislib/logi/pkg/parser/logi"
 	"github.com/tislib/logi/pkg/parser/macro"
-	"os"
+	"io"
 )
 
 func (v *vm) LoadMacroFile(path ...string) error {
 	for _, p := range path {
-		data, err := os.ReadFile(p)
+		data, err := readFile(p)
 
 		if err != nil {
 			return fmt.Errorf("error reading file: %v", err)
 		}
 
-		ast, err := macro.ParseMacroContent(string(data), v.enableSourceMap)
+		ast, err := macro.ParseMacroContent(string(data), v.sourceMapEnabled())
 
 		if err != nil {
 			return fmt.Errorf("error parsing macro content: %v", err)
 		}
 
-		v.Macros = append(v.Macros, ast.Macros...)
-		v.MacroContents[p] = string(data)
+		if err := v.addMacros(ast.Macros, p, string(data)); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -32,14 +34,15 @@ func (v *vm) LoadMacroFile(path ...string) error {
 
 func (v *vm) LoadMacroContent(content ...string) error {
 	for _, c := range content {
-		ast, err := macro.ParseMacroContent(c, v.enableSourceMap)
+		ast, err := macro.ParseMacroContent(c, v.sourceMapEnabled())
 
 		if err != nil {
 			return fmt.Errorf("error parsing macro content: %v", err)
 		}
 
-		v.Macros = append(v.Macros, ast.Macros...)
-		v.MacroContents[c] = c
+		if err := v.addMacros(ast.Macros, "", c); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -47,7 +50,9 @@ func (v *vm) LoadMacroContent(content ...string) error {
 
 func (v *vm) LoadMacroAst(ast ...macroAst.Ast) error {
 	for _, a := range ast {
-		v.Macros = append(v.Macros, a.Macros...)
+		if err := v.addMacros(a.Macros, "", ""); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -57,19 +62,23 @@ func (v *vm) LoadLogiFile(path ...string) ([]logiAst.Definition, error) {
 	var result []logiAst.Definition
 
 	for _, p := range path {
-		data, err := os.ReadFile(p)
+		data, err := readFile(p)
 
 		if err != nil {
 			return nil, fmt.Errorf("error reading file: %v", err)
 		}
 
-		ast, err := logi.Parse(string(data), v.Macros, false)
+		ast, err := logi.Parse(string(data), v.GetMacros(), v.sourceMapEnabled())
 
 		if err != nil {
 			return nil, fmt.Errorf("error parsing logi content: %v", err)
 		}
 
-		v.Definitions = append(v.Definitions, ast.Definitions...)
+		if err := v.addDefinitions(ast.Definitions, p); err != nil {
+			return nil, err
+		}
+
+		result = append(result, ast.Definitions...)
 	}
 
 	return result, nil
@@ -79,13 +88,16 @@ func (v *vm) LoadLogiContent(content ...string) ([]logiAst.Definition, error) {
 	var result []logiAst.Definition
 
 	for _, c := range content {
-		ast, err := logi.Parse(c, v.Macros, false)
+		ast, err := logi.Parse(c, v.GetMacros(), v.sourceMapEnabled())
 
 		if err != nil {
 			return nil, fmt.Errorf("error parsing logi content: %v", err)
 		}
 
-		v.Definitions = append(v.Definitions, ast.Definitions...)
+		if err := v.addDefinitions(ast.Definitions, ""); err != nil {
+			return nil, err
+		}
+
 		result = append(result, ast.Definitions...)
 	}
 
@@ -96,8 +108,60 @@ func (v *vm) LoadLogiAst(ast ...logiAst.Ast) ([]logiAst.Definition, error) {
 	var result []logiAst.Definition
 
 	for _, item := range ast {
-		v.Definitions = append(v.Definitions, item.Definitions...)
+		if err := v.addDefinitions(item.Definitions, ""); err != nil {
+			return nil, err
+		}
+
+		result = append(result, item.Definitions...)
 	}
 
 	return result, nil
 }
+
+func (v *vm) LoadCompiled(r io.Reader) ([]logiAst.Definition, error) {
+	artifact, err := compiled.Read(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("error loading compiled artifact: %w", err)
+	}
+
+	var set loadSet
+	set.addMacros("", artifact.Macros...)
+	set.addDefinitions("", artifact.Definitions...)
+
+	if err := v.apply(nil, set); err != nil {
+		return nil, err
+	}
+
+	return artifact.Definitions, nil
+}
+
+// addMacros loads macros from the source, content is kept by macro file path
+func (v *vm) addMacros(macros []macroAst.Macro, source string, content string) error {
+	var set loadSet
+	set.addMacros(source, macros...)
+
+	if err := v.apply(nil, set); err != nil {
+		return err
+	}
+
+	if content != "" {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		if source != "" {
+			v.MacroContents[source] = content
+		} else {
+			v.MacroContents[content] = content
+		}
+	}
+
+	return nil
+}
+
+func (v *vm) addDefinitions(definitions []logiAst.Definition, source string) error {
+	var set loadSet
+	set.addDefinitions(source, definitions...)
+
+	return v.apply(nil, set)
+}
@@ -0,0 +1,180 @@
+package vm
+
+import (
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"github.com/tislib/logi/pkg/compiled"
+	"io"
+)
+
+// Program is an immutable set of macros and definitions with indexed lookups, it is built by Builder.
+// Program is safe for concurrent use. Returned macros, definitions and statements are shared, they must not be modified.
+type Program struct {
+	macros      []macroAst.Macro
+	definitions []logiAst.Definition
+
+	macroIndex      map[string]int
+	definitionIndex map[string]int
+	byMacro         map[string][]int
+	byCommand       map[string][]StatementRef
+}
+
+// StatementRef is a statement located in a definition
+type StatementRef struct {
+	Definition string
+
+	// Path is the index of the statement in the definition, for nested statements
+	// it continues with the index of the sub statement list and the index in that list, for each level
+	Path []int
+
+	Statement logiAst.Statement
+}
+
+func newProgram(macros []macroAst.Macro, definitions []logiAst.Definition) (*Program, error) {
+	p := &Program{
+		macros:          macros,
+		definitions:     definitions,
+		macroIndex:      make(map[string]int),
+		definitionIndex: make(map[string]int),
+		byMacro:         make(map[string][]int),
+		byCommand:       make(map[string][]StatementRef),
+	}
+
+	for i, item := range macros {
+		if _, found := p.macroIndex[item.Name]; found {
+			return nil, fmt.Errorf("duplicate macro: %s", item.Name)
+		}
+
+		p.macroIndex[item.Name] = i
+	}
+
+	for i, definition := range definitions {
+		if _, found := p.definitionIndex[definition.Name]; found {
+			return nil, fmt.Errorf("duplicate definition: %s", definition.Name)
+		}
+
+		p.definitionIndex[definition.Name] = i
+		p.byMacro[definition.MacroName] = append(p.byMacro[definition.MacroName], i)
+		p.indexStatements(definition.Name, nil, definition.Statements)
+	}
+
+	return p, nil
+}
+
+func (p *Program) indexStatements(definition string, path []int, statements []logiAst.Statement) {
+	for i, statement := range statements {
+		var statementPath = append(path[:len(path):len(path)], i)
+
+		if statement.Command != "" {
+			p.byCommand[statement.Command] = append(p.byCommand[statement.Command], StatementRef{
+				Definition: definition,
+				Path:       statementPath,
+				Statement:  statement,
+			})
+		}
+
+		for j, subStatements := range statement.SubStatements {
+			p.indexStatements(definition, append(statementPath[:len(statementPath):len(statementPath)], j), subStatements)
+		}
+	}
+}
+
+func (p *Program) Macros() []macroAst.Macro {
+	return p.macros
+}
+
+func (p *Program) Macro(name string) (*macroAst.Macro, bool) {
+	i, found := p.macroIndex[name]
+
+	if !found {
+		return nil, false
+	}
+
+	return &p.macros[i], true
+}
+
+func (p *Program) Definitions() []logiAst.Definition {
+	return p.definitions
+}
+
+func (p *Program) Definition(name string) (*logiAst.Definition, bool) {
+	i, found := p.definitionIndex[name]
+
+	if !found {
+		return nil, false
+	}
+
+	return &p.definitions[i], true
+}
+
+// DefinitionsByMacro returns definitions of the macro in the order they are added
+func (p *Program) DefinitionsByMacro(macroName string) []logiAst.Definition {
+	var result []logiAst.Definition
+
+	for _, i := range p.byMacro[macroName] {
+		result = append(result, p.definitions[i])
+	}
+
+	return result
+}
+
+// StatementsByCommand returns statements with the command in all definitions, including nested statements
+func (p *Program) StatementsByCommand(command string) []StatementRef {
+	return p.byCommand[command]
+}
+
+// Query returns statements matching the query, see Query for its syntax
+func (p *Program) Query(query string) ([]QueryMatch, error) {
+	q, err := ParseQuery(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Match(p.definitions), nil
+}
+
+// WriteTo serializes the program in compiled binary format, it can be loaded back with ReadProgram without parsing
+func (p *Program) WriteTo(w io.Writer) (int64, error) {
+	var counter = &countingWriter{w: w}
+
+	err := compiled.Write(counter, compiled.Artifact{Macros: p.macros, Definitions: p.definitions})
+
+	return counter.n, err
+}
+
+// ReadProgram loads a program serialized with WriteTo, or any compiled artifact
+func ReadProgram(r io.Reader) (*Program, error) {
+	artifact, err := compiled.Read(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading program: %w", err)
+	}
+
+	return newProgram(artifact.Macros, artifact.Definitions)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(data []byte) (int, error) {
+	n, err := c.w.Write(data)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// NewFromProgram creates a virtual machine with macros and definitions of the program
+func NewFromProgram(program *Program) VirtualMachine {
+	v := New().(*vm)
+
+	v.Macros = program.macros[:len(program.macros):len(program.macros)]
+	v.Definitions = program.definitions[:len(program.definitions):len(program.definitions)]
+	v.macroSources = make([]string, len(program.macros))
+	v.definitionSources = make([]string, len(program.definitions))
+
+	return v
+}
@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/compiled"
+	"testing"
+)
+
+func buildCircuitProgram(t *testing.T, names ...string) *Program {
+	b := NewBuilder()
+
+	// logi content can be added before macros
+	for _, name := range names {
+		b.AddLogiContent(circuitContent(name))
+	}
+
+	if !assert.NoError(t, b.AddMacroContent(circuitLgm)) {
+		t.FailNow()
+	}
+
+	program, err := b.Build()
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return program
+}
+
+func TestProgramLookups(t *testing.T) {
+	program := buildCircuitProgram(t, "first", "second")
+
+	definition, found := program.Definition("second")
+
+	assert.True(t, found)
+	assert.Equal(t, "second", definition.Name)
+
+	_, found = program.Definition("third")
+
+	assert.False(t, found)
+
+	circuitMacro, found := program.Macro("circuit")
+
+	assert.True(t, found)
+	assert.Equal(t, "circuit", circuitMacro.Name)
+	assert.Len(t, program.DefinitionsByMacro("circuit"), 2)
+	assert.Empty(t, program.DefinitionsByMacro("unknown"))
+
+	var refs = program.StatementsByCommand("on_click")
+
+	if !assert.Len(t, refs, 4) {
+		return
+	}
+
+	assert.Equal(t, "first", refs[0].Definition)
+	assert.Equal(t, []int{1, 0, 2}, refs[0].Path)
+	assert.Equal(t, "button1", refs[0].Statement.GetParameter("component").AsString())
+
+	// nested statement in if
+	var offRefs = program.StatementsByCommand("off")
+
+	if !assert.NotEmpty(t, offRefs) {
+		return
+	}
+
+	assert.Equal(t, []int{1, 0, 2, 0, 0, 1, 0}, offRefs[0].Path)
+}
+
+func TestProgramDuplicateDefinition(t *testing.T) {
+	b := NewBuilder()
+
+	assert.NoError(t, b.AddMacroContent(circuitLgm))
+
+	b.AddLogiContent(circuitContent("first"), circuitContent("first"))
+
+	_, err := b.Build()
+
+	assert.EqualError(t, err, "duplicate definition: first")
+}
+
+func TestProgramSerialization(t *testing.T) {
+	program := buildCircuitProgram(t, "first", "second")
+
+	var buf bytes.Buffer
+
+	_, err := program.WriteTo(&buf)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	loaded, err := ReadProgram(&buf)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected, _ := json.Marshal(program.Definitions())
+	actual, _ := json.Marshal(loaded.Definitions())
+
+	assert.JSONEq(t, string(expected), string(actual))
+	assert.Equal(t, program.StatementsByCommand("on"), loaded.StatementsByCommand("on"))
+
+	// loaded program can be executed
+	v := NewFromProgram(loaded)
+
+	definition, err := v.GetDefinitionByName("first")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	implementer := &TestLedImplementor{
+		leds:           make(map[string]int64),
+		buttons:        make(map[string]int64),
+		ledState:       make(map[string]bool),
+		buttonState:    make(map[string]bool),
+		buttonHandlers: make(map[string]func() error),
+	}
+
+	assert.NoError(t, v.Execute(definition, implementer))
+	assert.Equal(t, map[string]bool{"yellowLed": true, "redLed": true}, implementer.ledState)
+}
+
+func TestVmLoadCompiled(t *testing.T) {
+	program := buildCircuitProgram(t, "first")
+
+	var buf bytes.Buffer
+
+	_, err := program.WriteTo(&buf)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	v := New().(*vm)
+
+	definitions, err := v.LoadCompiled(&buf)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, definitions, 1)
+	assert.Len(t, v.GetMacros(), 1)
+
+	// macros of compiled artifact can be used to load more definitions
+	_, err = v.LoadLogiContent(circuitContent("second"))
+
+	assert.NoError(t, err)
+
+	_, err = v.LoadCompiled(bytes.NewReader([]byte("circuit simple1 {}")))
+
+	assert.ErrorIs(t, err, compiled.ErrNotCompiled)
+}
@@ -0,0 +1,411 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/ast/plain"
+	"strconv"
+	"strings"
+)
+
+// Query selects definitions and statements with a path of selectors separated by `/`, e.g.
+// `circuit[simple1]/actions/on_click[component=button1]`.
+//
+// The first selector matches definitions, by macro name and optionally by definition name in brackets, `*` matches
+// any macro. Next selectors match statements of the next level by command, `*` matches any command. Statement
+// selectors can be filtered in brackets:
+//   - `[name]` the statement has a parameter or attribute with the name
+//   - `[name=value]` the parameter or attribute has the value, values can be quoted with ' or "
+//   - `[@scope=name]` the statement is in the scope
+//
+// `**` matches any number of levels, e.g. `circuit/**/on[component=redLed]`.
+// A query with only the definition selector matches definitions themselves.
+type Query struct {
+	macroName      string
+	definitionName string
+	statements     []querySelector
+}
+
+type querySelector struct {
+	command  string
+	anyDepth bool
+	filters  []queryFilter
+}
+
+type queryFilter struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// QueryMatch is a statement matched by a query, for definition matches path is empty.
+// Location of the statement is set only if content is loaded with source map.
+type QueryMatch struct {
+	StatementRef
+
+	MacroName string
+	Location  *common.SourceLocation
+}
+
+// ParseQuery parses the query, see Query for its syntax
+func ParseQuery(query string) (*Query, error) {
+	segments, err := splitQuery(query)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %v", query, err)
+	}
+
+	var result = new(Query)
+
+	for i, segment := range segments {
+		selector, err := parseQuerySelector(segment)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %v", query, err)
+		}
+
+		if i > 0 {
+			// consecutive `**` are the same as one
+			if selector.anyDepth && len(result.statements) > 0 && result.statements[len(result.statements)-1].anyDepth {
+				continue
+			}
+
+			result.statements = append(result.statements, selector)
+			continue
+		}
+
+		if selector.anyDepth {
+			return nil, fmt.Errorf("invalid query %q: first selector must match definitions", query)
+		}
+
+		result.macroName = selector.command
+
+		for _, filter := range selector.filters {
+			if filter.hasValue || result.definitionName != "" {
+				return nil, fmt.Errorf("invalid query %q: definitions can only be filtered by name, e.g. %s[name]", query, selector.command)
+			}
+
+			result.definitionName = filter.key
+		}
+	}
+
+	if len(result.statements) > 0 && result.statements[len(result.statements)-1].anyDepth {
+		return nil, fmt.Errorf("invalid query %q: query can not end with **", query)
+	}
+
+	return result, nil
+}
+
+// Match returns matches of the query in the definitions, in the order they appear in the definitions
+func (q *Query) Match(definitions []logiAst.Definition) []QueryMatch {
+	var result []QueryMatch
+
+	for _, definition := range definitions {
+		if q.macroName != "*" && definition.MacroName != q.macroName {
+			continue
+		}
+
+		if q.definitionName != "" && definition.Name != q.definitionName {
+			continue
+		}
+
+		if len(q.statements) == 0 {
+			result = append(result, QueryMatch{
+				StatementRef: StatementRef{Definition: definition.Name},
+				MacroName:    definition.MacroName,
+			})
+
+			continue
+		}
+
+		var m = queryMatcher{definition: definition, seen: make(map[string]bool)}
+		m.match(definition.Statements, nil, q.statements)
+
+		result = append(result, m.result...)
+	}
+
+	return result
+}
+
+type queryMatcher struct {
+	definition logiAst.Definition
+	seen       map[string]bool
+	result     []QueryMatch
+}
+
+func (m *queryMatcher) match(statements []logiAst.Statement, path []int, selectors []querySelector) {
+	for i, statement := range statements {
+		var statementPath = append(path[:len(path):len(path)], i)
+
+		if !selectors[0].anyDepth {
+			m.matchStatement(statement, statementPath, selectors)
+			continue
+		}
+
+		// `**` matches no level, or the statement and more levels below it
+		m.matchStatement(statement, statementPath, selectors[1:])
+
+		for j, subStatements := range statement.SubStatements {
+			m.match(subStatements, append(statementPath[:len(statementPath):len(statementPath)], j), selectors)
+		}
+	}
+}
+
+func (m *queryMatcher) matchStatement(statement logiAst.Statement, path []int, selectors []querySelector) {
+	if !selectors[0].matches(statement) {
+		return
+	}
+
+	if len(selectors) > 1 {
+		for j, subStatements := range statement.SubStatements {
+			m.match(subStatements, append(path[:len(path):len(path)], j), selectors[1:])
+		}
+
+		return
+	}
+
+	// the same statement can be reached through different levels with `**`
+	var key = fmt.Sprint(path)
+
+	if m.seen[key] {
+		return
+	}
+
+	m.seen[key] = true
+	m.result = append(m.result, QueryMatch{
+		StatementRef: StatementRef{
+			Definition: m.definition.Name,
+			Path:       path,
+			Statement:  statement,
+		},
+		MacroName: m.definition.MacroName,
+		Location:  statementLocation(m.definition, path),
+	})
+}
+
+func (s querySelector) matches(statement logiAst.Statement) bool {
+	if s.command != "*" && statement.Command != s.command {
+		return false
+	}
+
+	for _, filter := range s.filters {
+		if !filter.matches(statement) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f queryFilter) matches(statement logiAst.Statement) bool {
+	if f.key == "@scope" {
+		return statement.Scope == f.value
+	}
+
+	for _, parameter := range statement.Parameters {
+		if parameter.Name == f.key && (!f.hasValue || queryValue(parameter.Value) == f.value) {
+			return true
+		}
+	}
+
+	for _, attribute := range statement.Attributes {
+		if attribute.Name != f.key {
+			continue
+		}
+
+		if !f.hasValue {
+			return true
+		}
+
+		if attribute.Value != nil && queryValue(*attribute.Value) == f.value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryValue formats the value as it is written in queries
+func queryValue(value common.Value) string {
+	if value.Kind == common.ValueKindFloat {
+		return strconv.FormatFloat(*value.Float, 'f', -1, 64)
+	}
+
+	return value.ToDisplayName()
+}
+
+// statementLocation finds the plain statement of the statement path, sub statement lists of a statement
+// are parsed from its scope and array elements in order
+func statementLocation(definition logiAst.Definition, path []int) *common.SourceLocation {
+	if len(path) == 0 || path[0] >= len(definition.PlainStatements) {
+		return nil
+	}
+
+	var statement = definition.PlainStatements[path[0]]
+
+	for i := 1; i+1 < len(path); i += 2 {
+		var lists [][]plain.DefinitionStatement
+
+		for _, element := range statement.Elements {
+			if element.Kind == plain.DefinitionStatementElementKindStruct {
+				lists = append(lists, element.Struct.Statements)
+			} else if element.Kind == plain.DefinitionStatementElementKindArray {
+				lists = append(lists, element.Array.Items)
+			}
+		}
+
+		if path[i] >= len(lists) || path[i+1] >= len(lists[path[i]]) {
+			return nil
+		}
+
+		statement = lists[path[i]][path[i+1]]
+	}
+
+	return sourceLocation(statement.SourceLocation)
+}
+
+// sourceLocation returns nil for empty locations of content loaded without source map
+func sourceLocation(location common.SourceLocation) *common.SourceLocation {
+	if location.Line == 0 {
+		return nil
+	}
+
+	return &location
+}
+
+// splitQuery splits the query by `/`, ignoring the ones in brackets and quotes
+func splitQuery(query string) ([]string, error) {
+	var segments []string
+	var current strings.Builder
+	var quote rune
+	var inBrackets bool
+
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			if !inBrackets {
+				return nil, fmt.Errorf("unexpected %c", c)
+			}
+
+			quote = c
+		case c == '[':
+			if inBrackets {
+				return nil, fmt.Errorf("unexpected [")
+			}
+
+			inBrackets = true
+		case c == ']':
+			if !inBrackets {
+				return nil, fmt.Errorf("unexpected ]")
+			}
+
+			inBrackets = false
+		case c == '/' && !inBrackets:
+			segments = append(segments, current.String())
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(c)
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+
+	if inBrackets {
+		return nil, fmt.Errorf("unterminated [")
+	}
+
+	return append(segments, current.String()), nil
+}
+
+func parseQuerySelector(segment string) (querySelector, error) {
+	var selector querySelector
+	var name, rest, _ = strings.Cut(strings.TrimSpace(segment), "[")
+
+	selector.command = strings.TrimSpace(name)
+
+	if selector.command == "" {
+		return selector, fmt.Errorf("empty selector")
+	}
+
+	if selector.command == "**" {
+		if rest != "" {
+			return selector, fmt.Errorf("** can not be filtered")
+		}
+
+		selector.anyDepth = true
+
+		return selector, nil
+	}
+
+	for rest != "" {
+		var content string
+		var found bool
+
+		content, rest, found = cutFilter(rest)
+
+		if !found {
+			return selector, fmt.Errorf("invalid filter in %s", segment)
+		}
+
+		var filter queryFilter
+		var key, value, hasValue = strings.Cut(content, "=")
+
+		filter.key = strings.TrimSpace(key)
+		filter.value = unquote(strings.TrimSpace(value))
+		filter.hasValue = hasValue
+
+		if filter.key == "" {
+			return selector, fmt.Errorf("empty filter in %s", segment)
+		}
+
+		selector.filters = append(selector.filters, filter)
+	}
+
+	return selector, nil
+}
+
+// cutFilter cuts the filter content until the closing bracket, and returns the rest after the next opening bracket
+func cutFilter(s string) (string, string, bool) {
+	var quote rune
+
+	for i, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			var rest = strings.TrimSpace(s[i+1:])
+
+			if rest == "" {
+				return s[:i], "", true
+			}
+
+			if rest[0] != '[' {
+				return "", "", false
+			}
+
+			return s[:i], rest[1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
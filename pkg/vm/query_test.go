@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	"testing"
+)
+
+func TestVmQuery(t *testing.T) {
+	v := New().(*vm)
+	v.EnableSourceMap(true)
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	_, err := v.LoadLogiContent(circuitLg, circuitContent("simple2"))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type match struct {
+		definition string
+		path       []int
+		command    string
+		location   *common.SourceLocation
+	}
+
+	tests := map[string]struct {
+		query         string
+		expected      []match
+		expectedError string
+	}{
+		"definition and parameter value": {
+			query: "circuit[simple1]/actions/on_click[component=button1]",
+			expected: []match{
+				{definition: "simple1", path: []int{1, 0, 2}, command: "on_click", location: &common.SourceLocation{Line: 14, Column: 9}},
+			},
+		},
+		"any depth": {
+			query: "circuit[simple1]/**/on[component=redLed]",
+			expected: []match{
+				{definition: "simple1", path: []int{1, 0, 1}, command: "on", location: &common.SourceLocation{Line: 12, Column: 9}},
+				{definition: "simple1", path: []int{1, 0, 2, 0, 0, 0, 2}, command: "on", location: &common.SourceLocation{Line: 18, Column: 17}},
+				{definition: "simple1", path: []int{1, 0, 3, 0, 2}, command: "on", location: &common.SourceLocation{Line: 27, Column: 13}},
+			},
+		},
+		"any macro and command": {
+			query: "*/components/*[pin=17]",
+			expected: []match{
+				{definition: "simple1", path: []int{0, 0, 3}, command: "Button", location: &common.SourceLocation{Line: 6, Column: 9}},
+				{definition: "simple2", path: []int{0, 0, 3}, command: "Button", location: &common.SourceLocation{Line: 6, Column: 9}},
+			},
+		},
+		"scope": {
+			query: "circuit[simple2]/**/*[@scope=handler]",
+			expected: []match{
+				{definition: "simple2", path: []int{1, 0, 2}, command: "on_click", location: &common.SourceLocation{Line: 14, Column: 9}},
+				{definition: "simple2", path: []int{1, 0, 3}, command: "on_click", location: &common.SourceLocation{Line: 24, Column: 9}},
+			},
+		},
+		"else branch": {
+			query: "circuit[simple1]/actions/on_click/if/off",
+			expected: []match{
+				{definition: "simple1", path: []int{1, 0, 2, 0, 0, 1, 0}, command: "off", location: &common.SourceLocation{Line: 20, Column: 17}},
+			},
+		},
+		"quoted value": {
+			query: "circuit[simple1]/components/Led[component='blueLed']",
+			expected: []match{
+				{definition: "simple1", path: []int{0, 0, 2}, command: "Led", location: &common.SourceLocation{Line: 5, Column: 9}},
+			},
+		},
+		"definitions": {
+			query: "circuit",
+			expected: []match{
+				{definition: "simple1"},
+				{definition: "simple2"},
+			},
+		},
+		"no match": {
+			query: "circuit/actions/on_click[component=button3]",
+		},
+		"unknown macro": {
+			query: "robot/actions",
+		},
+		"invalid filter": {
+			query:         "circuit/actions[component",
+			expectedError: "unterminated [",
+		},
+		"invalid definition filter": {
+			query:         "circuit[name=simple1]",
+			expectedError: "definitions can only be filtered by name",
+		},
+		"trailing any depth": {
+			query:         "circuit/**",
+			expectedError: "query can not end with **",
+		},
+		"empty selector": {
+			query:         "circuit//on",
+			expectedError: "empty selector",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			matches, err := v.Query(tt.query)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var actual []match
+
+			for _, item := range matches {
+				actual = append(actual, match{
+					definition: item.Definition,
+					path:       item.Path,
+					command:    item.Statement.Command,
+					location:   item.Location,
+				})
+			}
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestProgramQuery(t *testing.T) {
+	program := buildCircuitProgram(t, "first", "second")
+
+	matches, err := program.Query("circuit[second]/actions/on_click")
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, "second", matches[0].Definition)
+		assert.Equal(t, "circuit", matches[0].MacroName)
+		assert.Equal(t, "button1", matches[0].Statement.GetParameter("component").AsString())
+
+		// program is built without source map
+		assert.Nil(t, matches[0].Location)
+	}
+}
@@ -0,0 +1,273 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	macroAst "github.com/tislib/logi/pkg/ast/macro"
+)
+
+// DuplicatePolicy decides what happens when a macro or definition is loaded with a name which is already loaded
+type DuplicatePolicy int
+
+const (
+	// DuplicateAllow keeps both, lookups by name return the first one, it is the default
+	DuplicateAllow DuplicatePolicy = iota
+	// DuplicateError rejects the load, nothing is loaded
+	DuplicateError
+	// DuplicateOverride replaces the existing macro or definition
+	DuplicateOverride
+)
+
+var ErrDuplicate = errors.New("duplicate name")
+var ErrNotFound = errors.New("not found")
+
+// loadSet is a set of macros and definitions with the sources they are loaded from
+type loadSet struct {
+	macros            []macroAst.Macro
+	macroSources      []string
+	definitions       []logiAst.Definition
+	definitionSources []string
+}
+
+func (s *loadSet) addMacros(source string, macros ...macroAst.Macro) {
+	for _, item := range macros {
+		s.macros = append(s.macros, item)
+		s.macroSources = append(s.macroSources, source)
+	}
+}
+
+func (s *loadSet) addDefinitions(source string, definitions ...logiAst.Definition) {
+	for _, item := range definitions {
+		s.definitions = append(s.definitions, item)
+		s.definitionSources = append(s.definitionSources, source)
+	}
+}
+
+func (v *vm) SetDuplicatePolicy(policy DuplicatePolicy) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.duplicatePolicy = policy
+}
+
+// apply removes macros and definitions loaded from removed sources, and adds the new ones, applying duplicate policy.
+// It is atomic, if it fails nothing is changed. Slices are replaced instead of being modified, so snapshots stay valid.
+func (v *vm) apply(removedSources map[string]bool, added loadSet) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	macros, macroSources, err := mergeNamed(v.Macros, v.macroSources, removedSources, added.macros, added.macroSources, func(item macroAst.Macro) string {
+		return item.Name
+	}, v.duplicatePolicy)
+
+	if err != nil {
+		return fmt.Errorf("failed to load macro: %w", err)
+	}
+
+	definitions, definitionSources, err := mergeNamed(v.Definitions, v.definitionSources, removedSources, added.definitions, added.definitionSources, func(item logiAst.Definition) string {
+		return item.Name
+	}, v.duplicatePolicy)
+
+	if err != nil {
+		return fmt.Errorf("failed to load definition: %w", err)
+	}
+
+	v.Macros, v.macroSources = macros, macroSources
+	v.Definitions, v.definitionSources = definitions, definitionSources
+
+	return nil
+}
+
+func mergeNamed[T any](items []T, sources []string, removedSources map[string]bool, added []T, addedSources []string, name func(T) string, policy DuplicatePolicy) ([]T, []string, error) {
+	var resultItems = make([]T, 0, len(items)+len(added))
+	var resultSources = make([]string, 0, len(items)+len(added))
+
+	for i, item := range items {
+		if !removedSources[sources[i]] {
+			resultItems = append(resultItems, item)
+			resultSources = append(resultSources, sources[i])
+		}
+	}
+
+	for i, item := range added {
+		var existing = -1
+
+		for j, current := range resultItems {
+			if name(current) == name(item) {
+				existing = j
+				break
+			}
+		}
+
+		if existing == -1 || policy == DuplicateAllow {
+			resultItems = append(resultItems, item)
+			resultSources = append(resultSources, addedSources[i])
+			continue
+		}
+
+		if policy == DuplicateError {
+			return nil, nil, fmt.Errorf("%w: %s", ErrDuplicate, name(item))
+		}
+
+		resultItems[existing] = item
+		resultSources[existing] = addedSources[i]
+	}
+
+	return resultItems, resultSources, nil
+}
+
+// RemoveDefinition removes all definitions with the name
+func (v *vm) RemoveDefinition(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	definitions, sources, removed := removeWhere(v.Definitions, v.definitionSources, func(i int) bool {
+		return v.Definitions[i].Name == name
+	})
+
+	if removed == 0 {
+		return fmt.Errorf("definition %s %w", name, ErrNotFound)
+	}
+
+	v.Definitions, v.definitionSources = definitions, sources
+
+	return nil
+}
+
+// ReplaceDefinition replaces all definitions with the same name, keeping their positions and sources
+func (v *vm) ReplaceDefinition(definition logiAst.Definition) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var definitions = append([]logiAst.Definition{}, v.Definitions...)
+	var replaced = 0
+
+	for i, item := range definitions {
+		if item.Name == definition.Name {
+			definitions[i] = definition
+			replaced++
+		}
+	}
+
+	if replaced == 0 {
+		return fmt.Errorf("definition %s %w", definition.Name, ErrNotFound)
+	}
+
+	v.Definitions = definitions
+
+	return nil
+}
+
+// RemoveMacro removes all macros with the name, macros used by definitions can not be removed
+func (v *vm) RemoveMacro(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, definition := range v.Definitions {
+		if definition.MacroName == name {
+			return fmt.Errorf("macro %s is used by definition %s", name, definition.Name)
+		}
+	}
+
+	macros, sources, removed := removeWhere(v.Macros, v.macroSources, func(i int) bool {
+		return v.Macros[i].Name == name
+	})
+
+	if removed == 0 {
+		return fmt.Errorf("macro %s %w", name, ErrNotFound)
+	}
+
+	v.Macros, v.macroSources = macros, sources
+
+	return nil
+}
+
+// UnloadSource removes macros and definitions loaded from the source, source of files is their path
+func (v *vm) UnloadSource(source string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	macros, macroSources, removedMacros := removeWhere(v.Macros, v.macroSources, func(i int) bool {
+		return v.macroSources[i] == source
+	})
+
+	definitions, definitionSources, removedDefinitions := removeWhere(v.Definitions, v.definitionSources, func(i int) bool {
+		return v.definitionSources[i] == source
+	})
+
+	if removedMacros == 0 && removedDefinitions == 0 {
+		return fmt.Errorf("source %s %w", source, ErrNotFound)
+	}
+
+	// remaining definitions must not use a removed macro, unless another macro with the same name is left
+	for _, definition := range definitions {
+		if !containsMacro(macros, definition.MacroName) && containsMacro(v.Macros, definition.MacroName) {
+			return fmt.Errorf("macro %s is used by definition %s", definition.MacroName, definition.Name)
+		}
+	}
+
+	v.Macros, v.macroSources = macros, macroSources
+	v.Definitions, v.definitionSources = definitions, definitionSources
+
+	return nil
+}
+
+func containsMacro(macros []macroAst.Macro, name string) bool {
+	for _, item := range macros {
+		if item.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetSources returns sources of loaded macros and definitions, content loaded without a file has an empty source
+func (v *vm) GetSources() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var seen = make(map[string]bool)
+	var result []string
+
+	for _, source := range append(append([]string{}, v.macroSources...), v.definitionSources...) {
+		if !seen[source] {
+			seen[source] = true
+			result = append(result, source)
+		}
+	}
+
+	return result
+}
+
+// macrosExcept returns macros which are not loaded from the given sources
+func (v *vm) macrosExcept(sources map[string]bool) []macroAst.Macro {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var result []macroAst.Macro
+
+	for i, item := range v.Macros {
+		if !sources[v.macroSources[i]] {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// removeWhere returns new slices without the items matching by their index
+func removeWhere[T any](items []T, sources []string, matches func(i int) bool) ([]T, []string, int) {
+	var resultItems = make([]T, 0, len(items))
+	var resultSources = make([]string, 0, len(items))
+
+	for i, item := range items {
+		if !matches(i) {
+			resultItems = append(resultItems, item)
+			resultSources = append(resultSources, sources[i])
+		}
+	}
+
+	return resultItems, resultSources, len(items) - len(resultItems)
+}
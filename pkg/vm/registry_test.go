@@ -0,0 +1,299 @@
+package vm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestVmDuplicatePolicy(t *testing.T) {
+	tests := map[string]struct {
+		policy             DuplicatePolicy
+		expectedMacroError string
+		expectedError      string
+		expectedNames      []string
+		expectedStmts      int
+	}{
+		"error": {
+			policy:             DuplicateError,
+			expectedMacroError: "duplicate name: circuit",
+			expectedError:      "duplicate name: first",
+			expectedNames:      []string{"first"},
+			expectedStmts:      2,
+		},
+		"override": {
+			policy:        DuplicateOverride,
+			expectedNames: []string{"first", "second"},
+			expectedStmts: 3,
+		},
+		"allow": {
+			policy:        DuplicateAllow,
+			expectedNames: []string{"first", "first", "second"},
+			expectedStmts: 2,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := New().(*vm)
+			v.SetDuplicatePolicy(tt.policy)
+
+			if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+				return
+			}
+
+			_, err := v.LoadLogiContent(circuitContent("first"))
+			assert.NoError(t, err)
+
+			// the same macro is loaded again
+			err = v.LoadMacroContent(circuitLgm)
+
+			if tt.expectedMacroError != "" {
+				assert.ErrorContains(t, err, tt.expectedMacroError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			// the load is atomic, second definition is not loaded if first one is duplicate
+			var changed = circuitContent("first")[:len(circuitContent("first"))-2] + "\n    components {\n        Led redLed 6\n    }\n}\n"
+			_, err = v.LoadLogiContent(changed + "\n" + circuitContent("second"))
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedNames, definitionNames(v))
+
+			definition, err := v.GetDefinitionByName("first")
+
+			if assert.NoError(t, err) {
+				assert.Len(t, definition.Statements, tt.expectedStmts)
+			}
+		})
+	}
+}
+
+func TestVmRemoveAndReplace(t *testing.T) {
+	tests := map[string]struct {
+		apply              func(v *vm) error
+		expectedError      string
+		expectedNames      []string
+		expectedMacro      bool
+		expectedStatements []int
+	}{
+		"remove definition": {
+			apply: func(v *vm) error {
+				return v.RemoveDefinition("first")
+			},
+			expectedNames: []string{"second"},
+			expectedMacro: true,
+		},
+		"remove unknown definition": {
+			apply: func(v *vm) error {
+				return v.RemoveDefinition("unknown")
+			},
+			expectedError: "not found",
+			expectedNames: []string{"first", "second"},
+			expectedMacro: true,
+		},
+		"replace definition": {
+			apply: func(v *vm) error {
+				definition, err := v.GetDefinitionByName("second")
+
+				if err != nil {
+					return err
+				}
+
+				definition.Statements = definition.Statements[:1]
+
+				return v.ReplaceDefinition(*definition)
+			},
+			expectedNames: []string{"first", "second"},
+			expectedMacro: true,
+		},
+		"remove duplicate definitions": {
+			apply: func(v *vm) error {
+				if _, err := v.LoadLogiContent(circuitContent("second")); err != nil {
+					return err
+				}
+
+				return v.RemoveDefinition("second")
+			},
+			expectedNames: []string{"first"},
+			expectedMacro: true,
+		},
+		"replace duplicate definitions": {
+			apply: func(v *vm) error {
+				if _, err := v.LoadLogiContent(circuitContent("second")); err != nil {
+					return err
+				}
+
+				definition, err := v.GetDefinitionByName("second")
+
+				if err != nil {
+					return err
+				}
+
+				definition.Statements = definition.Statements[:1]
+
+				return v.ReplaceDefinition(*definition)
+			},
+			expectedNames:      []string{"first", "second", "second"},
+			expectedMacro:      true,
+			expectedStatements: []int{2, 1, 1},
+		},
+		"replace unknown definition": {
+			apply: func(v *vm) error {
+				definition, err := v.GetDefinitionByName("second")
+
+				if err != nil {
+					return err
+				}
+
+				definition.Name = "unknown"
+
+				return v.ReplaceDefinition(*definition)
+			},
+			expectedError: "not found",
+			expectedNames: []string{"first", "second"},
+			expectedMacro: true,
+		},
+		"remove used macro": {
+			apply: func(v *vm) error {
+				return v.RemoveMacro("circuit")
+			},
+			expectedError: "macro circuit is used by definition first",
+			expectedNames: []string{"first", "second"},
+			expectedMacro: true,
+		},
+		"remove macro": {
+			apply: func(v *vm) error {
+				if err := v.RemoveDefinition("first"); err != nil {
+					return err
+				}
+
+				if err := v.RemoveDefinition("second"); err != nil {
+					return err
+				}
+
+				return v.RemoveMacro("circuit")
+			},
+			expectedMacro: false,
+		},
+		"remove unknown macro": {
+			apply: func(v *vm) error {
+				return v.RemoveMacro("unknown")
+			},
+			expectedError: "not found",
+			expectedNames: []string{"first", "second"},
+			expectedMacro: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := New().(*vm)
+
+			if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+				return
+			}
+
+			_, err := v.LoadLogiContent(circuitContent("first"), circuitContent("second"))
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var snapshot = v.GetDefinitions()
+
+			err = tt.apply(v)
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedNames, definitionNames(v))
+			assert.Equal(t, tt.expectedMacro, len(v.GetMacros()) > 0)
+
+			if tt.expectedStatements != nil {
+				var statements []int
+
+				for _, definition := range v.GetDefinitions() {
+					statements = append(statements, len(definition.Statements))
+				}
+
+				assert.Equal(t, tt.expectedStatements, statements)
+			}
+
+			// snapshots returned before are not modified
+			assert.Len(t, snapshot, 2)
+			assert.Len(t, snapshot[1].Statements, 2)
+		})
+	}
+}
+
+func TestVmUnloadSource(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "circuit.lgm"), circuitLgm)
+	writeFile(t, filepath.Join(dir, "first.lg"), circuitContent("first"))
+	writeFile(t, filepath.Join(dir, "second.lg"), circuitContent("second"))
+
+	v := New().(*vm)
+	v.SetDuplicatePolicy(DuplicateError)
+
+	if !assert.NoError(t, v.LoadMacroFile(filepath.Join(dir, "circuit.lgm"))) {
+		return
+	}
+
+	_, err := v.LoadLogiFile(filepath.Join(dir, "first.lg"), filepath.Join(dir, "second.lg"))
+	assert.NoError(t, err)
+
+	_, err = v.LoadLogiContent(circuitContent("third"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "circuit.lgm"),
+		filepath.Join(dir, "first.lg"),
+		filepath.Join(dir, "second.lg"),
+		"",
+	}, v.GetSources())
+
+	assert.NoError(t, v.UnloadSource(filepath.Join(dir, "first.lg")))
+	assert.Equal(t, []string{"second", "third"}, definitionNames(v))
+
+	// unloaded file can be loaded again without duplicate error
+	_, err = v.LoadLogiFile(filepath.Join(dir, "first.lg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "third", "first"}, definitionNames(v))
+
+	assert.ErrorIs(t, v.UnloadSource(filepath.Join(dir, "unknown.lg")), ErrNotFound)
+
+	// macros used by remaining definitions can not be unloaded
+	assert.EqualError(t, v.UnloadSource(filepath.Join(dir, "circuit.lgm")), "macro circuit is used by definition second")
+	assert.Len(t, v.GetMacros(), 1)
+
+	// unused macros can be unloaded
+	for _, source := range []string{filepath.Join(dir, "first.lg"), filepath.Join(dir, "second.lg"), ""} {
+		assert.NoError(t, v.UnloadSource(source))
+	}
+
+	assert.NoError(t, v.UnloadSource(filepath.Join(dir, "circuit.lgm")))
+	assert.Empty(t, v.GetMacros())
+}
+
+func TestVmDefaultDuplicatePolicy(t *testing.T) {
+	v := New().(*vm)
+
+	// names can be loaded twice by default
+	assert.NoError(t, v.LoadMacroContent(circuitLgm))
+	assert.NoError(t, v.LoadMacroContent(circuitLgm))
+
+	_, err := v.LoadLogiContent(circuitContent("first"), circuitContent("first"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "first"}, definitionNames(v))
+}
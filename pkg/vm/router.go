@@ -0,0 +1,151 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+)
+
+// HandlerFunc handles a statement routed by Router
+type HandlerFunc func(vm VirtualMachine, statement logiAst.Statement) error
+
+// AfterFunc is called after a statement and its sub statements are executed, with the result of execution.
+// The returned error replaces the result, so it can be used to recover or to wrap errors.
+type AfterFunc func(vm VirtualMachine, statement logiAst.Statement, err error) error
+
+// SkipSubStatements can be returned by handlers and before hooks to not descend into sub statements of the statement,
+// e.g. if the handler executes them itself. It is not returned as an error by Router.
+var SkipSubStatements = errors.New("skip sub statements")
+
+var ErrUnknownCommand = errors.New("unknown command")
+
+// IgnoreUnknown is an unknown command handler which skips unknown statements with their sub statements
+func IgnoreUnknown(vm VirtualMachine, statement logiAst.Statement) error {
+	return SkipSubStatements
+}
+
+// DescendUnknown is an unknown command handler which skips unknown statements, but executes their sub statements
+func DescendUnknown(vm VirtualMachine, statement logiAst.Statement) error {
+	return nil
+}
+
+// Router is an implementer which calls handlers registered by scope and command of statements.
+// After the handler of a statement returns, statements of its scopes are executed in order as blocks, array items are
+// not routed.
+// Top level statements of definitions have an empty scope.
+//
+// Handlers are looked up in order: exact scope and command, scope with `*` command, `*` scope with the command,
+// and `*` for both.
+// Statements without a handler are passed to the unknown command handler, by default they fail with ErrUnknownCommand.
+//
+// Router must be configured before execution, it is safe for concurrent use while executing.
+type Router struct {
+	handlers map[routeKey]HandlerFunc
+	before   []HandlerFunc
+	after    []AfterFunc
+	unknown  HandlerFunc
+}
+
+type routeKey struct {
+	scope   string
+	command string
+}
+
+func NewRouter() *Router {
+	return &Router{
+		handlers: make(map[routeKey]HandlerFunc),
+	}
+}
+
+// Handle registers the handler for statements with the scope and command, `*` matches any scope or command
+func (r *Router) Handle(scope string, command string, fn HandlerFunc) *Router {
+	r.handlers[routeKey{scope: scope, command: command}] = fn
+
+	return r
+}
+
+// Before registers a hook which is called before each statement, in registration order.
+// If it returns an error, the statement is not executed.
+func (r *Router) Before(fn HandlerFunc) *Router {
+	r.before = append(r.before, fn)
+
+	return r
+}
+
+// After registers a hook which is called after each statement, in registration order
+func (r *Router) After(fn AfterFunc) *Router {
+	r.after = append(r.after, fn)
+
+	return r
+}
+
+// Unknown sets the handler of statements without a registered handler, e.g. IgnoreUnknown or DescendUnknown
+func (r *Router) Unknown(fn HandlerFunc) *Router {
+	r.unknown = fn
+
+	return r
+}
+
+func (r *Router) Call(vm VirtualMachine, statement logiAst.Statement) error {
+	var err = r.call(vm, statement)
+
+	for _, fn := range r.after {
+		err = fn(vm, statement, err)
+	}
+
+	return err
+}
+
+func (r *Router) call(vm VirtualMachine, statement logiAst.Statement) error {
+	for _, fn := range r.before {
+		if err := fn(vm, statement); err != nil {
+			return skipped(err)
+		}
+	}
+
+	if err := r.handler(statement)(vm, statement); err != nil {
+		return skipped(err)
+	}
+
+	for _, subStatements := range statement.SubStatements {
+		if logiAst.IsArrayItems(subStatements) {
+			continue
+		}
+
+		if err := ExecuteBlock(vm, r, subStatements); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Router) handler(statement logiAst.Statement) HandlerFunc {
+	for _, key := range []routeKey{
+		{scope: statement.Scope, command: statement.Command},
+		{scope: statement.Scope, command: "*"},
+		{scope: "*", command: statement.Command},
+		{scope: "*", command: "*"},
+	} {
+		if fn, found := r.handlers[key]; found {
+			return fn
+		}
+	}
+
+	if r.unknown != nil {
+		return r.unknown
+	}
+
+	return func(vm VirtualMachine, statement logiAst.Statement) error {
+		return fmt.Errorf("%w: %s in scope %q", ErrUnknownCommand, statement.Command, statement.Scope)
+	}
+}
+
+// skipped converts SkipSubStatements to nil, as it is not an error
+func skipped(err error) error {
+	if errors.Is(err, SkipSubStatements) {
+		return nil
+	}
+
+	return err
+}
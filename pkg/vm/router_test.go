@@ -0,0 +1,145 @@
+package vm
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	v := New()
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(circuitLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// record returns a handler which records statements as command(component)
+	var record = func(calls *[]string) HandlerFunc {
+		return func(vm VirtualMachine, statement logiAst.Statement) error {
+			*calls = append(*calls, statement.Command+"("+statement.GetParameter("component").AsString()+")")
+
+			return nil
+		}
+	}
+
+	tests := map[string]struct {
+		router        func(calls *[]string) *Router
+		expectedCalls []string
+		expectedError string
+	}{
+		"scope and command": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("components", "Button", record(calls)).
+					Handle("handler", "on_click", func(vm VirtualMachine, statement logiAst.Statement) error {
+						*calls = append(*calls, "click")
+
+						return SkipSubStatements
+					}).
+					Unknown(DescendUnknown)
+			},
+			expectedCalls: []string{"Button(button1)", "Button(button2)", "click", "click"},
+		},
+		"any command of scope": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("", "*", DescendUnknown).
+					Handle("components", "*", record(calls)).
+					Unknown(IgnoreUnknown)
+			},
+			expectedCalls: []string{"Led(yellowLed)", "Led(redLed)", "Led(blueLed)", "Button(button1)", "Button(button2)"},
+		},
+		"any scope of command": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("*", "on", record(calls)).
+					Handle("command", "if", func(vm VirtualMachine, statement logiAst.Statement) error {
+						// branches of conditions are not executed
+						return SkipSubStatements
+					}).
+					Unknown(DescendUnknown)
+			},
+			expectedCalls: []string{"on(yellowLed)", "on(redLed)", "on(yellowLed)", "on(redLed)"},
+		},
+		"unknown command": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("", "components", DescendUnknown).
+					Handle("components", "Led", record(calls))
+			},
+			expectedCalls: []string{"Led(yellowLed)", "Led(redLed)", "Led(blueLed)"},
+			expectedError: "unknown command: Button in scope \"components\"",
+		},
+		"before and after hooks": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("", "components", DescendUnknown).
+					Handle("components", "*", record(calls)).
+					Before(func(vm VirtualMachine, statement logiAst.Statement) error {
+						if statement.Command == "Led" {
+							*calls = append(*calls, "before")
+						}
+
+						// actions are not executed
+						if statement.Command == "actions" {
+							return SkipSubStatements
+						}
+
+						return nil
+					}).
+					After(func(vm VirtualMachine, statement logiAst.Statement, err error) error {
+						if statement.Command == "Button" {
+							*calls = append(*calls, "after")
+						}
+
+						return err
+					})
+			},
+			expectedCalls: []string{"before", "Led(yellowLed)", "before", "Led(redLed)", "before", "Led(blueLed)", "Button(button1)", "after", "Button(button2)", "after"},
+		},
+		"after hook recovers error": {
+			router: func(calls *[]string) *Router {
+				return NewRouter().
+					Handle("*", "*", func(vm VirtualMachine, statement logiAst.Statement) error {
+						if statement.Command == "on_click" {
+							return errors.New("failed")
+						}
+
+						return nil
+					}).
+					After(func(vm VirtualMachine, statement logiAst.Statement, err error) error {
+						if err != nil {
+							*calls = append(*calls, "recovered "+statement.Command)
+						}
+
+						return nil
+					})
+			},
+			expectedCalls: []string{"recovered on_click", "recovered on_click"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls []string
+
+			err := v.Execute(&definitions[0], tt.router(&calls))
+
+			if tt.expectedError != "" {
+				assert.ErrorIs(t, err, ErrUnknownCommand)
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
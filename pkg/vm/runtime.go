@@ -0,0 +1,214 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Runtime executes definitions which react to events, e.g. `on_click(button1) { ... }`. Statements annotated with
+// Handler are registered when the definition is started, and executed each time a matching event is emitted.
+type Runtime struct {
+	vm          *vm
+	implementer Implementer
+	clock       Clock
+
+	handlers []*eventHandler
+	mu       sync.RWMutex
+}
+
+type eventHandler struct {
+	event     string
+	handler   Handler
+	statement logiAst.Statement
+
+	// execution which registered the handler, its environment is the parent of the handler environment
+	execution *Execution
+}
+
+// NewRuntime returns a runtime which executes statements with the implementer, if clock is nil the system clock is used
+func (v *vm) NewRuntime(implementer Implementer, clock Clock) *Runtime {
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	return &Runtime{
+		vm:          v,
+		implementer: implementer,
+		clock:       clock,
+	}
+}
+
+// Start executes the definition top to bottom and registers its handlers, limits also apply to each handler execution.
+// Starting a definition again replaces the handlers registered by its previous start.
+func (r *Runtime) Start(ctx context.Context, def *logiAst.Definition, limits Limits) error {
+	r.unregister(def.Name)
+
+	var e = r.vm.newExecution(ctx, limits)
+	e.runtime = r
+	defer e.traceEnd()
+
+	return e.Execute(def, r.implementer)
+}
+
+func (r *Runtime) Emit(event string, args map[string]common.Value) error {
+	return r.EmitContext(context.Background(), event, args)
+}
+
+// EmitContext executes the handlers of the event, whose parameters are equal to the arguments with the same name, in
+// order of their registration. Arguments are variables of the handler body.
+func (r *Runtime) EmitContext(ctx context.Context, event string, args map[string]common.Value) error {
+	r.mu.RLock()
+	var handlers = r.handlers
+	r.mu.RUnlock()
+
+	for _, h := range handlers {
+		if h.event != event || !h.matches(args) {
+			continue
+		}
+
+		var e = h.execution.withEnvironment(NewEnvironment(h.execution.env))
+		e.ctx = ctx
+		e.counters = new(executionCounters)
+
+		for name, value := range args {
+			e.env.Declare(name, value)
+		}
+
+		if h.handler.Condition != "" {
+			value, err := e.condition(h.statement, h.handler.Condition)
+
+			if err != nil {
+				return fmt.Errorf("failed to evaluate condition of %s: %w", event, err)
+			}
+
+			if !value {
+				continue
+			}
+		}
+
+		var err = e.block(r.implementer, body(h.statement), e.env)
+		e.traceEnd()
+
+		if err != nil && !errors.Is(err, errReturn) {
+			return fmt.Errorf("failed to handle %s: %w", event, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runtime) register(h *eventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// handlers are replaced instead of modified in place, so Emit can iterate over them without locking
+	r.handlers = append(r.handlers[:len(r.handlers):len(r.handlers)], h)
+}
+
+// unregister removes the handlers registered by executions of the definition
+func (r *Runtime) unregister(definition string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var handlers []*eventHandler
+
+	for _, h := range r.handlers {
+		if h.execution.definition.Name != definition {
+			handlers = append(handlers, h)
+		}
+	}
+
+	r.handlers = handlers
+}
+
+func (h *eventHandler) matches(args map[string]common.Value) bool {
+	for _, parameter := range h.statement.Parameters {
+		if parameter.Name == h.handler.Condition {
+			continue
+		}
+
+		arg, found := args[parameter.Name]
+
+		if !found || !reflect.DeepEqual(arg.AsInterface(), parameter.Value.AsInterface()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Handler registers the body of the statement as a handler of an event instead of executing it, e.g. for
+// `on_click(<component Name>) { command }`. It can only be executed by a Runtime.
+type Handler struct {
+	// Event is the name of the event, default is the command of the statement
+	Event string
+
+	// Condition is an optional bool parameter, which is evaluated when the event is emitted, it is not matched
+	// with arguments of the event
+	Condition string
+}
+
+func (h Handler) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	if e.runtime == nil {
+		return fmt.Errorf("handler %s can only be executed by a runtime", statement.Command)
+	}
+
+	var event = h.Event
+
+	if event == "" {
+		event = statement.Command
+	}
+
+	e.runtime.register(&eventHandler{
+		event:     event,
+		handler:   h,
+		statement: statement,
+		execution: e,
+	})
+
+	return nil
+}
+
+// Wait pauses the execution for a number of seconds, e.g. for `wait(<seconds float>)`, with the clock of the runtime
+type Wait struct {
+	// Seconds is the parameter with the duration, it can be an integer or a float
+	Seconds string
+}
+
+func (w Wait) Execute(e *Execution, implementer Implementer, statement logiAst.Statement) error {
+	value, err := e.Parameter(statement, w.Seconds)
+
+	if err != nil {
+		return err
+	}
+
+	var seconds float64
+
+	switch value.Kind {
+	case common.ValueKindFloat:
+		seconds = value.AsFloat()
+	case common.ValueKindInteger:
+		seconds = float64(value.AsInteger())
+	default:
+		return fmt.Errorf("%s must be a number, got %s", w.Seconds, value.Kind)
+	}
+
+	var clock = SystemClock()
+
+	if e.runtime != nil {
+		clock = e.runtime.clock
+	}
+
+	select {
+	case <-clock.After(time.Duration(seconds * float64(time.Second))):
+		return nil
+	case <-e.ctx.Done():
+		return fmt.Errorf("execution is interrupted: %w", e.ctx.Err())
+	}
+}
@@ -0,0 +1,231 @@
+package vm
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"strings"
+	"testing"
+	"time"
+)
+
+const blinkerLg = `circuit blinker {
+    components {
+        Led 	led 5
+        Button 	button 17
+    }
+
+    actions {
+        on(led)
+
+        on_click(button) {
+            if (status('led') == 'on') {
+                off(led)
+            } else {
+                on(led)
+            }
+        }
+
+        on_press(button, 2) {
+            off(led)
+            wait(1.5)
+            on(led)
+        }
+    }
+}`
+
+const alarmLgm = `macro alarm {
+    kind Syntax
+
+    syntax {
+        handlers { handler }
+    }
+
+    scopes {
+        handler {
+            on_temperature(<condition bool>) { action }
+        }
+        action {
+            alert(<value int>)
+        }
+    }
+}`
+
+const alarmLg = `alarm a {
+    handlers {
+        on_temperature(value > limit) {
+            alert(value)
+        }
+    }
+}`
+
+func TestRuntime(t *testing.T) {
+	v := New().(*vm)
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(blinkerLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var status = make(map[string]string)
+	var commands []string
+
+	v.SetFunction("status", func(args ...common.Value) (common.Value, error) {
+		return common.StringValue(status[args[0].AsString()]), nil
+	})
+
+	v.Annotate("circuit", "", "actions", Block{})
+	v.Annotate("circuit", "command", "if", If{Condition: "condition"})
+	v.Annotate("circuit", "command", "wait", Wait{Seconds: "seconds"})
+	v.Annotate("circuit", "handler", "on_click", Handler{})
+	v.Annotate("circuit", "handler", "on_press", Handler{})
+
+	var setStatus = func(value string) HandlerFunc {
+		return func(vm VirtualMachine, statement logiAst.Statement) error {
+			var component = statement.GetParameter("component").AsString()
+
+			status[component] = value
+			commands = append(commands, statement.Command+" "+component)
+
+			return nil
+		}
+	}
+
+	var implementer = NewRouter().
+		Handle("command", "on", setStatus("on")).
+		Handle("command", "off", setStatus("off")).
+		Unknown(IgnoreUnknown)
+
+	var clock = NewManualClock(time.Unix(0, 0))
+	var runtime = v.NewRuntime(implementer, clock)
+
+	if !assert.NoError(t, runtime.Start(context.Background(), &definitions[0], Limits{})) {
+		return
+	}
+
+	assert.Equal(t, []string{"on led"}, commands)
+
+	var button = common.StringValue("button")
+
+	// on_click(button) matches clicks of any count, on_press(button, 2) only double presses
+	assert.NoError(t, runtime.Emit("on_click", map[string]common.Value{"component": button, "count": common.IntegerValue(1)}))
+	assert.Equal(t, []string{"on led", "off led"}, commands)
+
+	assert.NoError(t, runtime.Emit("on_click", map[string]common.Value{"component": common.StringValue("other")}))
+	assert.NoError(t, runtime.Emit("on_press", map[string]common.Value{"component": button, "count": common.IntegerValue(1)}))
+	assert.Equal(t, []string{"on led", "off led"}, commands)
+
+	var done = make(chan error, 1)
+
+	go func() {
+		done <- runtime.Emit("on_press", map[string]common.Value{"component": button, "count": common.IntegerValue(2)})
+	}()
+
+	assert.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, clock.Waiters())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, []string{"on led", "off led", "off led", "on led"}, commands)
+}
+
+func TestRuntimeCondition(t *testing.T) {
+	v := New().(*vm)
+	v.Environment().Declare("limit", common.IntegerValue(25))
+	v.Annotate("alarm", "", "handlers", Block{})
+	v.Annotate("alarm", "handler", "on_temperature", Handler{Condition: "condition"})
+
+	if !assert.NoError(t, v.LoadMacroContent(alarmLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(alarmLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var alerts []int64
+
+	var runtime = v.NewRuntime(NewRouter().Handle("action", "alert", func(vm VirtualMachine, statement logiAst.Statement) error {
+		value, err := vm.(*Execution).Parameter(statement, "value")
+
+		alerts = append(alerts, value.AsInteger())
+
+		return err
+	}), nil)
+
+	if !assert.NoError(t, runtime.Start(context.Background(), &definitions[0], Limits{})) {
+		return
+	}
+
+	for _, value := range []int64{20, 30, 25, 40} {
+		assert.NoError(t, runtime.Emit("on_temperature", map[string]common.Value{"value": common.IntegerValue(value)}))
+	}
+
+	assert.Equal(t, []int64{30, 40}, alerts)
+}
+
+func TestRuntimeRestart(t *testing.T) {
+	v := New().(*vm)
+	v.Environment().Declare("limit", common.IntegerValue(25))
+	v.Annotate("alarm", "", "handlers", Block{})
+	v.Annotate("alarm", "handler", "on_temperature", Handler{Condition: "condition"})
+
+	if !assert.NoError(t, v.LoadMacroContent(alarmLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(alarmLg, strings.Replace(alarmLg, "alarm a", "alarm b", 1))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var alerts []int64
+
+	var runtime = v.NewRuntime(NewRouter().Handle("action", "alert", func(vm VirtualMachine, statement logiAst.Statement) error {
+		value, err := vm.(*Execution).Parameter(statement, "value")
+
+		alerts = append(alerts, value.AsInteger())
+
+		return err
+	}), nil)
+
+	// restarted definition replaces its handlers, handlers of other definitions are kept
+	for _, definition := range []*logiAst.Definition{&definitions[0], &definitions[1], &definitions[0]} {
+		if !assert.NoError(t, runtime.Start(context.Background(), definition, Limits{})) {
+			return
+		}
+	}
+
+	assert.NoError(t, runtime.Emit("on_temperature", map[string]common.Value{"value": common.IntegerValue(30)}))
+	assert.Equal(t, []int64{30, 30}, alerts)
+}
+
+func TestHandlerWithoutRuntime(t *testing.T) {
+	v := New().(*vm)
+	v.Annotate("alarm", "handler", "on_temperature", Handler{Condition: "condition"})
+	v.Annotate("alarm", "", "handlers", Block{})
+
+	if !assert.NoError(t, v.LoadMacroContent(alarmLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(alarmLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.ErrorContains(t, v.Execute(&definitions[0], NewRouter()), "can only be executed by a runtime")
+}
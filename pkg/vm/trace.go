@@ -0,0 +1,129 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"sync/atomic"
+)
+
+type TraceKind string
+
+const (
+	// TraceStatement is traced before a statement is executed
+	TraceStatement TraceKind = "statement"
+
+	// TraceExpression is traced after an expression node is evaluated, with its value
+	TraceExpression TraceKind = "expression"
+
+	// TraceError is traced when a statement fails, only for the statement which returned the error first
+	TraceError TraceKind = "error"
+
+	// TraceEnd is traced when an execution ends, including executions which are interrupted
+	TraceEnd TraceKind = "end"
+)
+
+// TraceEvent is an event of an execution, it is passed to the tracer of the virtual machine
+type TraceEvent struct {
+	Kind TraceKind
+
+	// Definition and Path of the statement being executed, see StatementRef for the path.
+	// Indexes which can not be known, e.g. of statements executed with Call, are -1.
+	Definition *logiAst.Definition
+	Path       []int
+	Statement  *logiAst.Statement
+
+	// Depth is the nesting of the statement in the execution, top level statements of definitions are at depth 1
+	Depth int
+
+	// Expression and its Value, for expression events
+	Expression *common.Expression
+	Value      common.Value
+
+	// Err is the error of the statement, for error events
+	Err error
+
+	// Environment of the statement, it must not be modified by the tracer
+	Environment *Environment
+
+	// Context of the execution, tracers which block the execution should stop waiting when it is done
+	Context context.Context
+
+	// execution identifies the execution the event belongs to
+	execution *executionCounters
+}
+
+// Location returns the source location of the statement, if the definition is loaded with source map
+func (t TraceEvent) Location() *common.SourceLocation {
+	if t.Definition == nil || len(t.Path) == 0 {
+		return nil
+	}
+
+	return statementLocation(*t.Definition, t.Path)
+}
+
+// Tracer is called synchronously by executions, so it can pause them, e.g. Debugger.Trace
+type Tracer func(event TraceEvent)
+
+// Tracers returns a tracer which calls all the tracers in order
+func Tracers(tracers ...Tracer) Tracer {
+	return func(event TraceEvent) {
+		for _, tracer := range tracers {
+			tracer(event)
+		}
+	}
+}
+
+// SetTracer sets the tracer of executions which are started after it, nil disables tracing
+func (v *vm) SetTracer(tracer Tracer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.tracer = tracer
+}
+
+func (v *vm) getTracer() Tracer {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.tracer
+}
+
+func (e *Execution) trace(event TraceEvent) {
+	if e.frame != nil {
+		event.Statement = e.frame.statement
+	}
+
+	event.Definition = e.definition
+	event.Path = e.frame.path()
+	event.Depth = int(atomic.LoadInt64(&e.counters.depth))
+	event.Environment = e.env
+	event.Context = e.ctx
+	event.execution = e.counters
+
+	e.tracer(event)
+}
+
+// traceEnd traces the end of the execution, so tracers can release what they keep for it
+func (e *Execution) traceEnd() {
+	if e.tracer != nil {
+		e.trace(TraceEvent{Kind: TraceEnd})
+	}
+}
+
+func (e *Execution) traceError(err error) {
+	// control flow signals are not errors
+	if errors.Is(err, errBreak) || errors.Is(err, errReturn) || errors.Is(err, SkipSubStatements) {
+		return
+	}
+
+	e.counters.mu.Lock()
+	var traced = e.counters.tracedErr != nil && errors.Is(err, e.counters.tracedErr)
+	e.counters.tracedErr = err
+	e.counters.mu.Unlock()
+
+	if !traced {
+		e.trace(TraceEvent{Kind: TraceError, Err: err})
+	}
+}
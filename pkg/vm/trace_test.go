@@ -0,0 +1,121 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/tislib/logi/pkg/ast/common"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"testing"
+)
+
+// newTracedCircuit returns a virtual machine with circuit definitions loaded, which executes if statements itself
+func newTracedCircuit(t *testing.T) (*vm, []logiAst.Definition) {
+	v := New().(*vm)
+	v.EnableSourceMap(true)
+	v.Environment().Declare("button2", common.StringValue("button2"))
+	v.SetFunction("status", func(args ...common.Value) (common.Value, error) {
+		return common.StringValue("on"), nil
+	})
+	v.Annotate("circuit", "command", "if", If{Condition: "condition"})
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		t.FailNow()
+	}
+
+	definitions, err := v.LoadLogiContent(circuitLg)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return v, definitions
+}
+
+func TestVmTracer(t *testing.T) {
+	var errFailed = errors.New("failed")
+
+	tests := map[string]struct {
+		failOn         string
+		expectedEvents []string
+	}{
+		"statements and expressions": {
+			expectedEvents: []string{
+				"statement components [0] 1",
+				"statement actions [1] 1",
+				"statement on [1 0 0] 2",
+				"statement on [1 0 1] 2",
+				"statement on_click [1 0 2] 2",
+				"statement if [1 0 2 0 0] 3",
+				"expression variable [1 0 2 0 0] button2",
+				"expression function_call [1 0 2 0 0] on",
+				"expression literal [1 0 2 0 0] on",
+				"expression binary_expression [1 0 2 0 0] true",
+				"statement on [1 0 2 0 0 0 0] 4",
+				"statement on [1 0 2 0 0 0 1] 4",
+				"statement on [1 0 2 0 0 0 2] 4",
+				"statement on_click [1 0 3] 2",
+				"statement off [1 0 3 0 0] 3",
+				"statement on [1 0 3 0 1] 3",
+				"statement on [1 0 3 0 2] 3",
+			},
+		},
+		"error is traced once, with path of the failed statement": {
+			failOn: "off",
+			expectedEvents: []string{
+				"statement components [0] 1",
+				"statement actions [1] 1",
+				"statement on [1 0 0] 2",
+				"statement on [1 0 1] 2",
+				"statement on_click [1 0 2] 2",
+				"statement if [1 0 2 0 0] 3",
+				"expression variable [1 0 2 0 0] button2",
+				"expression function_call [1 0 2 0 0] on",
+				"expression literal [1 0 2 0 0] on",
+				"expression binary_expression [1 0 2 0 0] true",
+				"statement on [1 0 2 0 0 0 0] 4",
+				"statement on [1 0 2 0 0 0 1] 4",
+				"statement on [1 0 2 0 0 0 2] 4",
+				"statement on_click [1 0 3] 2",
+				"statement off [1 0 3 0 0] 3",
+				"error off [1 0 3 0 0] failed",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, definitions := newTracedCircuit(t)
+
+			var events []string
+
+			v.SetTracer(func(event TraceEvent) {
+				switch event.Kind {
+				case TraceStatement:
+					events = append(events, fmt.Sprint(event.Kind, " ", event.Statement.Command, " ", event.Path, " ", event.Depth))
+				case TraceExpression:
+					events = append(events, fmt.Sprint(event.Kind, " ", event.Expression.Kind, " ", event.Path, " ", event.Value.AsInterface()))
+				case TraceError:
+					events = append(events, fmt.Sprint(event.Kind, " ", event.Statement.Command, " ", event.Path, " ", event.Err))
+				}
+			})
+
+			var router = NewRouter().
+				Handle("*", "components", IgnoreUnknown).
+				Handle("*", tt.failOn, func(vm VirtualMachine, statement logiAst.Statement) error {
+					return errFailed
+				}).
+				Unknown(DescendUnknown)
+
+			err := v.Execute(&definitions[0], router)
+
+			if tt.failOn != "" {
+				assert.ErrorIs(t, err, errFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedEvents, events)
+		})
+	}
+}
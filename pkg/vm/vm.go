@@ -5,34 +5,63 @@ import (
 	"github.com/tislib/logi/pkg/ast/common"
 	logiAst "github.com/tislib/logi/pkg/ast/logi"
 	macroAst "github.com/tislib/logi/pkg/ast/macro"
+	"sync"
 )
 
 type vm struct {
 	Macros          []macroAst.Macro
 	MacroContents   map[string]string
 	Definitions     []logiAst.Definition
-	locals          map[string]interface{}
-	vars            map[string]interface{}
+	globals         *Environment
+	annotations     map[annotationKey]Construct
+	functions       map[string]func(args ...common.Value) (common.Value, error)
+	tracer          Tracer
 	types           map[string]common.TypeDefinition
 	enableSourceMap bool
+
+	// sources of macros and definitions, by their index
+	macroSources      []string
+	definitionSources []string
+	duplicatePolicy   DuplicatePolicy
+
+	// mu guards the fields above, Macros and Definitions are replaced on change instead of being modified in place
+	mu sync.RWMutex
 }
 
 func (v *vm) GetMacros() []macroAst.Macro {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
 	return v.Macros
 }
 
 func (v *vm) GetMacroContent(name string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
 	return v.MacroContents[name]
 }
 
-func (v *vm) SetLocals(locals map[string]interface{}) {
-	for key, value := range locals {
-		v.locals[key] = value
-	}
+// Environment returns the global environment, its variables are visible to all executions and evaluations
+func (v *vm) Environment() *Environment {
+	return v.globals
+}
+
+// SetFunction registers a function which can be called from all expressions, functions given to Evaluate hide it
+func (v *vm) SetFunction(name string, fn func(args ...common.Value) (common.Value, error)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.functions[name] = fn
 }
 
-func (v *vm) GetLocals() map[string]interface{} {
-	return v.locals
+func (v *vm) function(name string) (func(args ...common.Value) (common.Value, error), bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	fn, found := v.functions[name]
+
+	return fn, found
 }
 
 func (v *vm) MapToStruct(definition logiAst.Definition) (string, error) {
@@ -40,8 +69,15 @@ func (v *vm) MapToStruct(definition logiAst.Definition) (string, error) {
 	panic("implement me")
 }
 
+func (v *vm) GetDefinitions() []logiAst.Definition {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.Definitions
+}
+
 func (v *vm) GetDefinitionByName(name string) (*logiAst.Definition, error) {
-	for _, definition := range v.Definitions {
+	for _, definition := range v.GetDefinitions() {
 		if definition.Name == name {
 			return &definition, nil
 		}
@@ -50,10 +86,35 @@ func (v *vm) GetDefinitionByName(name string) (*logiAst.Definition, error) {
 	return nil, fmt.Errorf("logiAst.Definition %s not found", name)
 }
 
+func (v *vm) EnableSourceMap(enable bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.enableSourceMap = enable
+}
+
+func (v *vm) sourceMapEnabled() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.enableSourceMap
+}
+
+func (v *vm) Query(query string) ([]QueryMatch, error) {
+	q, err := ParseQuery(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Match(v.GetDefinitions()), nil
+}
+
 func New() VirtualMachine {
 	return &vm{
-		locals:        make(map[string]interface{}),
-		vars:          make(map[string]interface{}),
+		globals:       NewEnvironment(nil),
+		annotations:   make(map[annotationKey]Construct),
+		functions:     make(map[string]func(args ...common.Value) (common.Value, error)),
 		MacroContents: make(map[string]string),
 	}
 }
@@ -0,0 +1,153 @@
+package vm
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func circuitContent(name string) string {
+	return strings.Replace(circuitLg, "circuit simple1", "circuit "+name, 1)
+}
+
+func TestVmConcurrentLoadAndRead(t *testing.T) {
+	v := New()
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	const writers = 8
+	const loads = 10
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < loads; i++ {
+				_, err := v.LoadLogiContent(circuitContent(fmt.Sprintf("c_%d_%d", w, i)))
+				assert.NoError(t, err)
+			}
+		}(w)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < loads; i++ {
+				// snapshots are consistent, all definitions are complete
+				for _, definition := range v.GetDefinitions() {
+					assert.Len(t, definition.Statements, 2)
+				}
+
+				_, _ = v.GetDefinitionByName(fmt.Sprintf("c_%d_%d", w, i))
+				assert.NotEmpty(t, v.GetMacros())
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	assert.Len(t, v.GetDefinitions(), writers*loads)
+}
+
+func TestVmSnapshotIsNotModified(t *testing.T) {
+	v := New()
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	_, err := v.LoadLogiContent(circuitContent("first"))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var snapshot = v.GetDefinitions()
+	var macros = v.GetMacros()
+
+	_, err = v.LoadLogiContent(circuitContent("second"))
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, v.LoadMacroContent(strings.Replace(circuitLgm, "macro circuit", "macro circuit2", 1)))
+
+	assert.Len(t, snapshot, 1)
+	assert.Len(t, snapshot[:cap(snapshot)], 1)
+	assert.Len(t, macros, 1)
+	assert.Len(t, v.GetDefinitions(), 2)
+	assert.Len(t, v.GetMacros(), 2)
+}
+
+func TestVmConcurrentExecute(t *testing.T) {
+	v := New()
+
+	if !assert.NoError(t, v.LoadMacroContent(circuitLgm)) {
+		return
+	}
+
+	definitions, err := v.LoadLogiContent(circuitLg)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var calls int64
+	var wg sync.WaitGroup
+
+	var implementer = NewImplementerFunc(func(vm VirtualMachine, statement logiAst.Statement, passNext func(statement logiAst.Statement) error) error {
+		atomic.AddInt64(&calls, 1)
+
+		// implementers can use the virtual machine while it is loading
+		_ = vm.GetDefinitions()
+
+		for _, subStatements := range statement.SubStatements {
+			for _, subStatement := range subStatements {
+				if err := passNext(subStatement); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	// number of calls of a single execution
+	if !assert.NoError(t, v.Execute(&definitions[0], implementer)) {
+		return
+	}
+
+	var expected = calls * 8
+	calls = 0
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, v.Execute(&definitions[0], implementer))
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := v.LoadLogiContent(circuitContent(fmt.Sprintf("c_%d", i)))
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, expected, calls)
+}
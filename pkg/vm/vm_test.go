@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"github.com/stretchr/testify/assert"
@@ -275,3 +276,29 @@ func TestVmDynamic(t *testing.T) {
 		})
 	}
 }
+
+func TestVmOptionalInterfaces(t *testing.T) {
+	program, err := NewBuilder().Build()
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := map[string]struct {
+		vm VirtualMachine
+	}{
+		"new":       {vm: New()},
+		"program":   {vm: NewFromProgram(program)},
+		"execution": {vm: New().(*vm).newExecution(context.Background(), Limits{})},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Implements(t, (*Registry)(nil), tt.vm)
+			assert.Implements(t, (*Loader)(nil), tt.vm)
+			assert.Implements(t, (*Executor)(nil), tt.vm)
+			assert.Implements(t, (*Extensible)(nil), tt.vm)
+			assert.Implements(t, (*Inspector)(nil), tt.vm)
+		})
+	}
+}
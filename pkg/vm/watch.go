@@ -0,0 +1,272 @@
+//go:build !js
+
+package vm
+
+import (
+	"fmt"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"github.com/tislib/logi/pkg/watcher"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchDebounceInterval is the time to wait for more changes before reloading
+const watchDebounceInterval = 100 * time.Millisecond
+
+// watchPollInterval is used if file system notifications are not available
+const watchPollInterval = time.Second
+
+// Watcher reloads macro (*.lgm) and logi (*.lg) files of the watched paths into the virtual machine.
+// On change, all watched files are parsed again, if all of them are valid, their macros and definitions are swapped
+// atomically, otherwise the last good version is kept.
+type Watcher struct {
+	vm    *vm
+	paths []string
+
+	fileWatcher watcher.FileWatcher
+
+	// files and definitions currently loaded from watched paths, files are used as sources of their macros and definitions
+	files       map[string]bool
+	definitions []logiAst.Definition
+
+	events chan ChangeEvent
+	done   chan struct{}
+	once   sync.Once
+	mu     sync.Mutex
+}
+
+func (v *vm) Watch(paths ...string) (*Watcher, error) {
+	w := &Watcher{
+		vm:     v,
+		paths:  paths,
+		events: make(chan ChangeEvent, 16),
+		done:   make(chan struct{}),
+	}
+
+	var dirs []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("error watching %s: %v", path, err)
+		}
+
+		if info.IsDir() {
+			dirs = append(dirs, path)
+		} else {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+
+	if event := w.Reload(); event.Err != nil {
+		return nil, event.Err
+	}
+
+	fileWatcher, err := watcher.NewFileWatcher(dirs, watchPollInterval)
+
+	if err != nil {
+		return nil, fmt.Errorf("error watching files: %v", err)
+	}
+
+	w.fileWatcher = fileWatcher
+
+	go w.run()
+
+	return w, nil
+}
+
+// Events returns channel of change events, it must be drained, otherwise reloads are blocked
+func (w *Watcher) Events() <-chan ChangeEvent {
+	return w.events
+}
+
+// Close stops watching, loaded macros and definitions are kept
+func (w *Watcher) Close() error {
+	var err error
+
+	w.once.Do(func() {
+		close(w.done)
+
+		if w.fileWatcher != nil {
+			err = w.fileWatcher.Close()
+		}
+	})
+
+	return err
+}
+
+func (w *Watcher) run() {
+	var changed = make(map[string]bool)
+	var timer = time.NewTimer(watchDebounceInterval)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case err := <-w.fileWatcher.Errors():
+			w.publish(ChangeEvent{Err: err})
+		case path, ok := <-w.fileWatcher.Events():
+			if !ok {
+				// file watcher stopped after an error, it is already published
+				return
+			}
+
+			if w.isWatched(path) {
+				changed[filepath.Clean(path)] = true
+				timer.Reset(watchDebounceInterval)
+			}
+		case <-timer.C:
+			var paths []string
+
+			for path := range changed {
+				paths = append(paths, path)
+			}
+
+			sort.Strings(paths)
+			changed = make(map[string]bool)
+
+			var event = w.Reload()
+			event.Paths = paths
+
+			w.publish(event)
+		}
+	}
+}
+
+func (w *Watcher) publish(event ChangeEvent) {
+	select {
+	case w.events <- event:
+	case <-w.done:
+	}
+}
+
+// isWatched checks if the file is a macro or logi file in the watched paths
+func (w *Watcher) isWatched(path string) bool {
+	if !strings.HasSuffix(path, ".lg") && !strings.HasSuffix(path, ".lgm") {
+		return false
+	}
+
+	for _, watched := range w.paths {
+		watched = filepath.Clean(watched)
+
+		if filepath.Clean(path) == watched || strings.HasPrefix(filepath.Clean(path), watched+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reload parses all watched files and swaps their macros and definitions if they are valid
+func (w *Watcher) Reload() ChangeEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	macroFiles, logiFiles, err := listWatchedFiles(w.paths)
+
+	if err != nil {
+		return ChangeEvent{Err: err}
+	}
+
+	// sources of previously and currently watched files are replaced
+	var sources = make(map[string]bool)
+	var set loadSet
+
+	for _, path := range append(macroFiles, logiFiles...) {
+		sources[path] = true
+	}
+
+	for path := range w.files {
+		sources[path] = true
+	}
+
+	for _, path := range macroFiles {
+		data, err := readFile(path)
+
+		if err != nil {
+			return ChangeEvent{Err: fmt.Errorf("error reading file: %v", err)}
+		}
+
+		ast, err := macro.ParseMacroContent(string(data), w.vm.sourceMapEnabled())
+
+		if err != nil {
+			return ChangeEvent{Err: fmt.Errorf("error parsing macro file %s: %w", path, err)}
+		}
+
+		set.addMacros(path, ast.Macros...)
+	}
+
+	// logi files are validated against macros loaded from other sources and new macros of watched files
+	var allMacros = append(w.vm.macrosExcept(sources), set.macros...)
+
+	for _, path := range logiFiles {
+		data, err := readFile(path)
+
+		if err != nil {
+			return ChangeEvent{Err: fmt.Errorf("error reading file: %v", err)}
+		}
+
+		ast, err := logi.Parse(string(data), allMacros, w.vm.sourceMapEnabled())
+
+		if err != nil {
+			return ChangeEvent{Err: fmt.Errorf("error parsing logi file %s: %w", path, err)}
+		}
+
+		set.addDefinitions(path, ast.Definitions...)
+	}
+
+	if err := w.vm.apply(sources, set); err != nil {
+		return ChangeEvent{Err: err}
+	}
+
+	var event = diffDefinitions(w.definitions, set.definitions)
+
+	w.files = make(map[string]bool)
+
+	for _, path := range append(macroFiles, logiFiles...) {
+		w.files[path] = true
+	}
+
+	w.definitions = set.definitions
+
+	return event
+}
+
+func listWatchedFiles(paths []string) ([]string, []string, error) {
+	var macroFiles, logiFiles []string
+
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			if strings.HasSuffix(file, ".lgm") {
+				macroFiles = append(macroFiles, file)
+			} else if strings.HasSuffix(file, ".lg") {
+				logiFiles = append(logiFiles, file)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading watched path: %v", err)
+		}
+	}
+
+	return macroFiles, logiFiles, nil
+}
@@ -0,0 +1,56 @@
+package vm
+
+import (
+	"encoding/json"
+	logiAst "github.com/tislib/logi/pkg/ast/logi"
+)
+
+// ChangeEvent reports result of a reload of watched files
+type ChangeEvent struct {
+	// Paths are the changed files which triggered the reload
+	Paths []string
+
+	Added    []string
+	Removed  []string
+	Modified []string
+
+	// Err is set if reload failed, in that case the last good version is kept and nothing is changed
+	Err error
+}
+
+func diffDefinitions(previous []logiAst.Definition, current []logiAst.Definition) ChangeEvent {
+	var event ChangeEvent
+	var previousJson = make(map[string]string)
+
+	for _, definition := range previous {
+		data, _ := json.Marshal(definition)
+		previousJson[definition.Name] = string(data)
+	}
+
+	var seen = make(map[string]bool)
+
+	for _, definition := range current {
+		seen[definition.Name] = true
+
+		existing, found := previousJson[definition.Name]
+
+		if !found {
+			event.Added = append(event.Added, definition.Name)
+			continue
+		}
+
+		data, _ := json.Marshal(definition)
+
+		if string(data) != existing {
+			event.Modified = append(event.Modified, definition.Name)
+		}
+	}
+
+	for _, definition := range previous {
+		if !seen[definition.Name] {
+			event.Removed = append(event.Removed, definition.Name)
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,27 @@
+//go:build js
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Watcher is not supported on js, there are no files to watch
+type Watcher struct{}
+
+func (v *vm) Watch(paths ...string) (*Watcher, error) {
+	return nil, fmt.Errorf("error watching files: %w", errors.ErrUnsupported)
+}
+
+func (w *Watcher) Events() <-chan ChangeEvent {
+	return nil
+}
+
+func (w *Watcher) Close() error {
+	return nil
+}
+
+func (w *Watcher) Reload() ChangeEvent {
+	return ChangeEvent{Err: errors.ErrUnsupported}
+}
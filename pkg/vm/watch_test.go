@@ -0,0 +1,140 @@
+package vm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	if !assert.NoError(t, os.WriteFile(path, []byte(content), 0644)) {
+		t.FailNow()
+	}
+}
+
+func definitionNames(v VirtualMachine) []string {
+	var names []string
+
+	for _, definition := range v.GetDefinitions() {
+		names = append(names, definition.Name)
+	}
+
+	return names
+}
+
+func TestVmWatchReload(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "circuit.lgm"), circuitLgm)
+	writeFile(t, filepath.Join(dir, "first.lg"), circuitContent("first"))
+
+	v := New().(*vm)
+
+	// macro of the watched directory replaces the same macro loaded from content
+	v.SetDuplicatePolicy(DuplicateOverride)
+
+	// definitions loaded from other sources are kept
+	assert.NoError(t, v.LoadMacroContent(circuitLgm))
+	_, err := v.LoadLogiContent(circuitContent("other"))
+	assert.NoError(t, err)
+
+	w, err := v.Watch(dir)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer w.Close()
+
+	assert.Equal(t, []string{"other", "first"}, definitionNames(v))
+
+	tests := []struct {
+		name          string
+		change        func()
+		expected      ChangeEvent
+		expectedError bool
+		expectedNames []string
+	}{
+		{
+			name: "add and modify",
+			change: func() {
+				writeFile(t, filepath.Join(dir, "first.lg"), circuitContent("first")+"\n"+circuitContent("second"))
+				writeFile(t, filepath.Join(dir, "third.lg"), circuitContent("third"))
+			},
+			expected:      ChangeEvent{Added: []string{"second", "third"}},
+			expectedNames: []string{"other", "first", "second", "third"},
+		},
+		{
+			name: "modify",
+			change: func() {
+				writeFile(t, filepath.Join(dir, "third.lg"), circuitContent("third")[:len(circuitContent("third"))-1]+"    components {\n        Led redLed 6\n    }\n}\n")
+			},
+			expected:      ChangeEvent{Modified: []string{"third"}},
+			expectedNames: []string{"other", "first", "second", "third"},
+		},
+		{
+			name: "invalid file keeps last good version",
+			change: func() {
+				writeFile(t, filepath.Join(dir, "third.lg"), "circuit third {\n    unknown\n}\n")
+			},
+			expectedError: true,
+			expectedNames: []string{"other", "first", "second", "third"},
+		},
+		{
+			name: "remove",
+			change: func() {
+				assert.NoError(t, os.Remove(filepath.Join(dir, "third.lg")))
+			},
+			expected:      ChangeEvent{Removed: []string{"third"}},
+			expectedNames: []string{"other", "first", "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+
+			event := w.Reload()
+
+			if tt.expectedError {
+				assert.Error(t, event.Err)
+			} else {
+				assert.Equal(t, tt.expected, event)
+			}
+
+			assert.Equal(t, tt.expectedNames, definitionNames(v))
+		})
+	}
+}
+
+func TestVmWatchEvents(t *testing.T) {
+	var dir = t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "circuit.lgm"), circuitLgm)
+	writeFile(t, filepath.Join(dir, "first.lg"), circuitContent("first"))
+
+	v := New().(*vm)
+
+	w, err := v.Watch(dir)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer w.Close()
+
+	writeFile(t, filepath.Join(dir, "second.lg"), circuitContent("second"))
+
+	select {
+	case event := <-w.Events():
+		assert.NoError(t, event.Err)
+		assert.Equal(t, []string{"second"}, event.Added)
+		assert.Equal(t, []string{filepath.Join(dir, "second.lg")}, event.Paths)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change is not detected")
+	}
+
+	assert.Equal(t, []string{"first", "second"}, definitionNames(v))
+}
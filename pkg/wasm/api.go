@@ -0,0 +1,58 @@
+package wasm
+
+import (
+	"fmt"
+	"github.com/tislib/logi/pkg/binding"
+	"github.com/tislib/logi/pkg/compiler"
+	"github.com/tislib/logi/pkg/parser/logi"
+	"github.com/tislib/logi/pkg/parser/macro"
+	"github.com/tislib/logi/pkg/vm"
+)
+
+// ParseMacro parses macro content, and returns the macro ast as json
+func ParseMacro(content string) (string, error) {
+	ast, err := macro.ParseMacroContent(content, true)
+
+	if err != nil {
+		return "", err
+	}
+
+	return binding.Encode(ast)
+}
+
+// ParseLogi parses logi content without macros, and returns the plain ast as json
+func ParseLogi(content string) (string, error) {
+	ast, err := logi.ParsePlainContent(content, true)
+
+	if err != nil {
+		return "", err
+	}
+
+	return binding.Encode(ast)
+}
+
+// Compile compiles logi content with the macros, and returns the same json as `logi compile` with the kind
+func Compile(content string, macroContent string, kind string) (string, error) {
+	if kind == "" {
+		kind = string(compiler.KindNormal)
+	}
+
+	macroAst, err := macro.ParseMacroContent(macroContent, true)
+
+	if err != nil {
+		return "", fmt.Errorf("error parsing macros: %v", err)
+	}
+
+	value, err := compiler.CompileContent(content, compiler.Kind(kind), macroAst.Macros)
+
+	if err != nil {
+		return "", err
+	}
+
+	return binding.Encode(value)
+}
+
+// Evaluate evaluates the expression with the variables given as a json object, and returns its value as json
+func Evaluate(expression string, varsJson string) (string, error) {
+	return binding.Evaluate(vm.New(), expression, varsJson)
+}
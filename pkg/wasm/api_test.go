@@ -0,0 +1,153 @@
+package wasm
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const lampLgm = `macro lamp {
+    kind Syntax
+
+    syntax {
+        power <watts int>
+        actions { command }
+    }
+
+    scopes {
+        command {
+            on(<component Name>)
+        }
+    }
+}`
+
+const lampLg = `lamp l1 {
+    power 60
+    actions {
+        on(led1)
+    }
+}`
+
+func TestApi(t *testing.T) {
+	tests := map[string]struct {
+		fn            func() (string, error)
+		expected      string
+		expectedError string
+	}{
+		"parse macro": {
+			fn: func() (string, error) {
+				return ParseMacro(lampLgm)
+			},
+			expected: `{"macros": [{"name": "lamp"}]}`,
+		},
+		"parse invalid macro": {
+			fn: func() (string, error) {
+				return ParseMacro("macro lamp {")
+			},
+			expectedError: "syntax error",
+		},
+		"parse logi": {
+			fn: func() (string, error) {
+				return ParseLogi(lampLg)
+			},
+			expected: `{"definitions": [{"macroName": "lamp", "name": "l1"}]}`,
+		},
+		"compile": {
+			fn: func() (string, error) {
+				return Compile(lampLg, lampLgm, "")
+			},
+			expected: `[{"macroName": "lamp", "name": "l1"}]`,
+		},
+		"compile values": {
+			fn: func() (string, error) {
+				return Compile(lampLg, lampLgm, "values")
+			},
+			expected: `{"l1": {"power": {"watts": 60}, "actions": {"statements": {"on": {"component": "led1"}}}}}`,
+		},
+		"compile without definitions": {
+			fn: func() (string, error) {
+				return Compile("", lampLgm, "")
+			},
+			expected: `[]`,
+		},
+		"compile with unknown macro": {
+			fn: func() (string, error) {
+				return Compile(lampLg, "", "")
+			},
+			expectedError: "lamp",
+		},
+		"evaluate": {
+			fn: func() (string, error) {
+				return Evaluate("x * 2", `{"x": 20}`)
+			},
+			expected: `40`,
+		},
+		"evaluate unknown variable": {
+			fn: func() (string, error) {
+				return Evaluate("x + 1", "")
+			},
+			expectedError: "variable x not found",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := tt.fn()
+
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+			} else if assert.NoError(t, err) {
+				assertJsonContains(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+// assertJsonContains checks that actual json has all fields of expected json
+func assertJsonContains(t *testing.T, expected string, actual string) {
+	var expectedValue, actualValue interface{}
+
+	if !assert.NoError(t, json.Unmarshal([]byte(expected), &expectedValue)) || !assert.NoError(t, json.Unmarshal([]byte(actual), &actualValue)) {
+		return
+	}
+
+	assert.Equal(t, expectedValue, pick(expectedValue, actualValue), actual)
+}
+
+// pick returns the fields of actual which are in expected
+func pick(expected interface{}, actual interface{}) interface{} {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+
+		if !ok {
+			return actual
+		}
+
+		var result = make(map[string]interface{})
+
+		for key, value := range e {
+			if item, found := a[key]; found {
+				result[key] = pick(value, item)
+			}
+		}
+
+		return result
+	case []interface{}:
+		a, ok := actual.([]interface{})
+
+		if !ok || len(a) != len(e) {
+			return actual
+		}
+
+		var result = make([]interface{}, 0, len(e))
+
+		for i := range e {
+			result = append(result, pick(e[i], a[i]))
+		}
+
+		return result
+	default:
+		return actual
+	}
+}
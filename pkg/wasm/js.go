@@ -0,0 +1,51 @@
+//go:build js && wasm
+
+package wasm
+
+import "syscall/js"
+
+// Register exposes parseMacro, parseLogi, compile and evaluate to javascript, as functions of the global `logi` object.
+// Functions return {result: json} on success, and {error: message} on failure.
+func Register() {
+	js.Global().Set("logi", js.ValueOf(map[string]interface{}{
+		"parseMacro": function(func(args []js.Value) (string, error) {
+			return ParseMacro(arg(args, 0))
+		}),
+		"parseLogi": function(func(args []js.Value) (string, error) {
+			return ParseLogi(arg(args, 0))
+		}),
+		"compile": function(func(args []js.Value) (string, error) {
+			return Compile(arg(args, 0), arg(args, 1), arg(args, 2))
+		}),
+		"evaluate": function(func(args []js.Value) (string, error) {
+			var vars string
+
+			if len(args) > 1 && args[1].Type() == js.TypeObject {
+				vars = js.Global().Get("JSON").Call("stringify", args[1]).String()
+			}
+
+			return Evaluate(arg(args, 0), vars)
+		}),
+	}))
+}
+
+func function(fn func(args []js.Value) (string, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		result, err := fn(args)
+
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+
+		return map[string]interface{}{"result": result}
+	})
+}
+
+// arg returns the string argument, missing and non string arguments are empty
+func arg(args []js.Value, index int) string {
+	if index >= len(args) || args[index].Type() != js.TypeString {
+		return ""
+	}
+
+	return args[index].String()
+}
@@ -0,0 +1,133 @@
+//go:build !js
+
+package wasm
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestJsImports checks that parser and virtual machine do not use os or logrus on js
+func TestJsImports(t *testing.T) {
+	if testing.Short() {
+		t.Skip("go list is skipped in short mode")
+	}
+
+	var list = exec.Command("go", "list", "-f", "{{.ImportPath}}: {{.Imports}}", "github.com/tislib/logi/pkg/vm", "github.com/tislib/logi/pkg/parser/...")
+	list.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+
+	output, err := list.CombinedOutput()
+
+	if !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		var imports = strings.Fields(strings.Trim(strings.SplitN(line, ": ", 2)[1], "[]"))
+
+		assert.NotContains(t, imports, "os", line)
+	}
+
+	var deps = exec.Command("go", "list", "-deps", "github.com/tislib/logi/pkg/vm", "github.com/tislib/logi/pkg/parser/...")
+	deps.Env = list.Env
+
+	output, err = deps.CombinedOutput()
+
+	if assert.NoError(t, err, string(output)) {
+		assert.NotContains(t, string(output), "github.com/sirupsen/logrus")
+	}
+}
+
+// TestNode builds cmd/logi-wasm, calls its functions in node, and checks they return the same results as Go functions
+func TestNode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("wasm build is skipped in short mode")
+	}
+
+	node, err := exec.LookPath("node")
+
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+
+	var wasmExec = filepath.Join(runtime.GOROOT(), "lib", "wasm", "wasm_exec.js")
+
+	if _, err := os.Stat(wasmExec); err != nil {
+		// before go 1.24
+		wasmExec = filepath.Join(runtime.GOROOT(), "misc", "wasm", "wasm_exec.js")
+	}
+
+	var dir = t.TempDir()
+	var wasmFile = filepath.Join(dir, "logi.wasm")
+
+	var build = exec.Command("go", "build", "-o", wasmFile, "github.com/tislib/logi/cmd/logi-wasm")
+	build.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+
+	if output, err := build.CombinedOutput(); !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	type testCase struct {
+		Function string        `json:"function"`
+		Args     []interface{} `json:"args"`
+	}
+
+	var cases = []testCase{
+		{Function: "parseMacro", Args: []interface{}{lampLgm}},
+		{Function: "parseMacro", Args: []interface{}{"macro lamp {"}},
+		{Function: "parseLogi", Args: []interface{}{lampLg}},
+		{Function: "compile", Args: []interface{}{lampLg, lampLgm}},
+		{Function: "compile", Args: []interface{}{lampLg, lampLgm, "values"}},
+		{Function: "compile", Args: []interface{}{lampLg, lampLgm, "plain"}},
+		{Function: "evaluate", Args: []interface{}{"x * 2", map[string]interface{}{"x": 20}}},
+		{Function: "evaluate", Args: []interface{}{"x + 1"}},
+	}
+
+	var expected = []map[string]string{
+		result(ParseMacro(lampLgm)),
+		result(ParseMacro("macro lamp {")),
+		result(ParseLogi(lampLg)),
+		result(Compile(lampLg, lampLgm, "")),
+		result(Compile(lampLg, lampLgm, "values")),
+		result(Compile(lampLg, lampLgm, "plain")),
+		result(Evaluate("x * 2", `{"x": 20}`)),
+		result(Evaluate("x + 1", "")),
+	}
+
+	data, err := json.Marshal(cases)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var casesFile = filepath.Join(dir, "cases.json")
+
+	assert.NoError(t, os.WriteFile(casesFile, data, 0644))
+
+	output, err := exec.Command(node, filepath.Join("test_data", "run.js"), wasmExec, wasmFile, casesFile).Output()
+
+	if !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	var actual []map[string]string
+
+	if assert.NoError(t, json.Unmarshal(output, &actual)) {
+		assert.Equal(t, expected, actual)
+	}
+}
+
+// result returns the result of a function as it is returned to javascript
+func result(value string, err error) map[string]string {
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+
+	return map[string]string{"result": value}
+}
@@ -0,0 +1,31 @@
+"use strict";
+
+// run.js loads logi.wasm, calls its functions with the cases, and prints their results as json.
+// usage: node run.js <wasm_exec.js> <logi.wasm> <cases.json>
+const [wasmExec, wasmFile, casesFile] = process.argv.slice(2);
+
+globalThis.require = require;
+globalThis.fs = require("fs");
+globalThis.path = require("path");
+globalThis.TextEncoder = require("util").TextEncoder;
+globalThis.TextDecoder = require("util").TextDecoder;
+globalThis.performance ??= require("perf_hooks").performance;
+globalThis.crypto ??= require("crypto");
+
+require(wasmExec);
+
+const go = new Go();
+
+WebAssembly.instantiate(fs.readFileSync(wasmFile), go.importObject).then((result) => {
+	// main registers the functions, then blocks
+	go.run(result.instance);
+
+	const cases = JSON.parse(fs.readFileSync(casesFile, "utf8"));
+	const results = cases.map((c) => logi[c.function](...c.args));
+
+	process.stdout.write(JSON.stringify(results));
+	process.exit(0);
+}).catch((err) => {
+	console.error(err);
+	process.exit(1);
+});
@@ -0,0 +1,164 @@
+package watcher
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileWatcher reports paths of changed, created or removed files in the watched directories and their subdirectories.
+// Events is closed when the watcher stops, if it stops on an error, the error is sent to Errors before.
+type FileWatcher interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// NewFileWatcher watches directories using inotify where it is available, falling back to polling with the given interval
+func NewFileWatcher(dirs []string, interval time.Duration) (FileWatcher, error) {
+	watcher, err := newNativeWatcher(dirs)
+
+	if err == nil {
+		return watcher, nil
+	}
+
+	return NewPollWatcher(dirs, interval)
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+type pollWatcher struct {
+	dirs     []string
+	interval time.Duration
+	states   map[string]fileState
+	events   chan string
+	errors   chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewPollWatcher watches directories by scanning them with the given interval
+func NewPollWatcher(dirs []string, interval time.Duration) (FileWatcher, error) {
+	w := &pollWatcher{
+		dirs:     dirs,
+		interval: interval,
+		events:   make(chan string),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+
+	states, err := w.scan()
+
+	if err != nil {
+		return nil, err
+	}
+
+	w.states = states
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *pollWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *pollWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *pollWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+
+	return nil
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.events)
+
+	var ticker = time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		states, err := w.scan()
+
+		if err != nil {
+			select {
+			case w.errors <- err:
+			case <-w.done:
+				return
+			}
+			continue
+		}
+
+		var changed []string
+
+		for path, state := range states {
+			if previous, found := w.states[path]; !found || previous != state {
+				changed = append(changed, path)
+			}
+		}
+
+		for path := range w.states {
+			if _, found := states[path]; !found {
+				changed = append(changed, path)
+			}
+		}
+
+		w.states = states
+
+		for _, path := range changed {
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+func (w *pollWatcher) scan() (map[string]fileState, error) {
+	var states = make(map[string]fileState)
+
+	for _, dir := range w.dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			info, err := d.Info()
+
+			if err != nil {
+				// file is removed during scan
+				return nil
+			}
+
+			states[filepath.Clean(path)] = fileState{modTime: info.ModTime(), size: info.Size()}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return states, nil
+}
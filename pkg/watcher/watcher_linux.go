@@ -0,0 +1,159 @@
+//go:build linux
+
+package watcher
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_MODIFY
+
+type inotifyWatcher struct {
+	file    *os.File
+	fd      int
+	watches map[int]string
+	events  chan string
+	errors  chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newNativeWatcher(dirs []string) (FileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+
+	if err != nil {
+		return nil, fmt.Errorf("inotify is not available: %v", err)
+	}
+
+	w := &inotifyWatcher{
+		// non-blocking descriptor is registered to runtime poller, so Close unblocks pending reads
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		watches: make(map[int]string),
+		events:  make(chan string),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+
+	for _, dir := range dirs {
+		if err := w.addRecursive(dir); err != nil {
+			_ = w.file.Close()
+			return nil, err
+		}
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *inotifyWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+
+	w.once.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+
+	return err
+}
+
+func (w *inotifyWatcher) addRecursive(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+
+		if err != nil {
+			return fmt.Errorf("error watching %s: %v", path, err)
+		}
+
+		w.watches[wd] = path
+
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) run() {
+	defer close(w.events)
+
+	var buf [syscall.SizeofInotifyEvent * 256]byte
+
+	for {
+		n, err := w.file.Read(buf[:])
+
+		if err != nil {
+			// reading can not be recovered, the error is reported and events are closed
+			select {
+			case <-w.done:
+			case w.errors <- err:
+			}
+			return
+		}
+
+		var offset = 0
+
+		for offset+syscall.SizeofInotifyEvent <= n {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			dir, found := w.watches[int(event.Wd)]
+
+			if !found {
+				continue
+			}
+
+			var path = filepath.Join(dir, string(trimNull(nameBytes)))
+
+			if event.Mask&syscall.IN_ISDIR != 0 {
+				if event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+					if err := w.addRecursive(path); err != nil {
+						select {
+						case w.errors <- err:
+						case <-w.done:
+							return
+						}
+					}
+				}
+				continue
+			}
+
+			select {
+			case w.events <- path:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+func trimNull(data []byte) []byte {
+	for i, b := range data {
+		if b == 0 {
+			return data[:i]
+		}
+	}
+
+	return data
+}
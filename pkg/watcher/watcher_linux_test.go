@@ -0,0 +1,36 @@
+//go:build linux
+
+package watcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNativeWatcherStops(t *testing.T) {
+	watcher, err := newNativeWatcher([]string{t.TempDir()})
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer watcher.Close()
+
+	// reading the closed descriptor fails, the watcher can not continue
+	assert.NoError(t, watcher.(*inotifyWatcher).file.Close())
+
+	select {
+	case err := <-watcher.Errors():
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("error is not reported")
+	}
+
+	select {
+	case _, ok := <-watcher.Events():
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("events are not closed")
+	}
+}
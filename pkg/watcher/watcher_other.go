@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watcher
+
+import "errors"
+
+func newNativeWatcher(dirs []string) (FileWatcher, error) {
+	return nil, errors.New("native file watcher is not supported on this platform")
+}
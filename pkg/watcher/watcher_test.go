@@ -0,0 +1,57 @@
+package watcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	tests := map[string]struct {
+		create func(dirs []string) (FileWatcher, error)
+	}{
+		"default": {
+			create: func(dirs []string) (FileWatcher, error) {
+				return NewFileWatcher(dirs, 10*time.Millisecond)
+			},
+		},
+		"poll": {
+			create: func(dirs []string) (FileWatcher, error) {
+				return NewPollWatcher(dirs, 10*time.Millisecond)
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var dir = t.TempDir()
+			var path = filepath.Join(dir, "rule.lg")
+
+			watcher, err := tt.create([]string{dir})
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			// poll watcher detects changes by modification time
+			time.Sleep(20 * time.Millisecond)
+
+			assert.NoError(t, os.WriteFile(path, []byte("rule"), 0644))
+
+			select {
+			case event := <-watcher.Events():
+				assert.Equal(t, path, event)
+			case <-time.After(5 * time.Second):
+				t.Fatal("change is not detected")
+			}
+
+			assert.NoError(t, watcher.Close())
+
+			// events are closed when the watcher stops
+			for range watcher.Events() {
+			}
+		})
+	}
+}